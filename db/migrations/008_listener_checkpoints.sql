@@ -0,0 +1,19 @@
+-- ============================================================
+-- Listener Checkpoints
+-- Migration 008: Persistent block cursor for chain listeners
+-- ============================================================
+-- Stores the last fully-confirmed block processed per listener,
+-- chain and contract. On startup the listeners backfill from
+-- last_block + 1 to the chain head before going live.
+-- ============================================================
+
+CREATE TABLE IF NOT EXISTS listener_checkpoints (
+    listener TEXT NOT NULL,
+    chain_id BIGINT NOT NULL,
+    contract_address TEXT NOT NULL,
+    last_block BIGINT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (listener, chain_id, contract_address)
+);
+
+COMMENT ON TABLE listener_checkpoints IS 'Last fully-confirmed block per listener, chain and contract';
@@ -0,0 +1,30 @@
+-- ============================================================
+-- Listener Outbox
+-- Migration 009: Durable queue between the listeners and Kafka
+-- ============================================================
+-- Confirmed chain events are written here before they are
+-- published. A relay delivers pending rows to Kafka in id order
+-- per topic and sets delivered_at only after the broker acks.
+-- ============================================================
+
+CREATE TABLE IF NOT EXISTS listener_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    topic TEXT NOT NULL,
+    msg_key BYTEA,
+    payload BYTEA NOT NULL,
+    headers JSONB NOT NULL DEFAULT '[]',
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_listener_outbox_pending
+    ON listener_outbox (topic, id)
+    WHERE delivered_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_listener_outbox_delivered
+    ON listener_outbox (delivered_at)
+    WHERE delivered_at IS NOT NULL;
+
+COMMENT ON TABLE listener_outbox IS 'Confirmed listener events awaiting delivery to Kafka';
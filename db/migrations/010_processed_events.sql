@@ -0,0 +1,32 @@
+-- ============================================================
+-- Processed Events Ledger
+-- Migration 010: Idempotent event application in the consumer
+-- ============================================================
+-- Every chain event applied by the consumer is recorded here,
+-- keyed by chain, transaction hash and log index, in the same
+-- transaction as its balance mutation. A redelivered event
+-- finds its row and is skipped. event_type is part of the key
+-- because one log can yield several events (a Transfer is
+-- applied as transfer_out and transfer_in) and a reorg
+-- retraction shares the log of the event it compensates.
+-- ============================================================
+
+CREATE TABLE IF NOT EXISTS processed_events (
+    chain TEXT NOT NULL,
+    tx_hash TEXT NOT NULL,
+    log_index INT NOT NULL,
+    event_type TEXT NOT NULL,
+    processed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (chain, tx_hash, log_index, event_type)
+);
+
+COMMENT ON TABLE processed_events IS 'Chain events already applied by the consumer';
+
+-- balance_events: distinguish logs of the same transaction.
+-- Rows written before this migration keep a NULL log_index and
+-- never conflict with each other.
+ALTER TABLE balance_events
+ADD COLUMN IF NOT EXISTS log_index INT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_balance_events_tx_log_type
+    ON balance_events (tx_hash, log_index, event_type);
@@ -0,0 +1,33 @@
+-- ============================================================
+-- Bridge Message Tracking
+-- Migration 011: On-chain state of Arbitrum bridge messages
+-- ============================================================
+-- The bridge monitor resolves every pending message against
+-- the chains. L1→L2 messages are retryable tickets: created on
+-- L2, then redeemed or left to expire. L2→L1 messages wait out
+-- the challenge period, become executable in the outbox once
+-- their assertion is confirmed, and are finally executed on L1.
+-- status keeps the coarse lifecycle (pending, confirmed,
+-- failed); arbitrum_status records the protocol step.
+-- ============================================================
+
+-- Written by the Go code since the bridge was introduced
+ALTER TABLE bridge_messages ADD COLUMN IF NOT EXISTS error_msg TEXT;
+ALTER TABLE bridge_messages ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT NOW();
+
+ALTER TABLE bridge_messages ADD COLUMN IF NOT EXISTS arbitrum_status TEXT
+    CHECK (arbitrum_status IN (
+        'retryable_created', 'retryable_redeemed', 'retryable_expired', 'retryable_creation_failed',
+        'challenge_period', 'executable', 'executed'
+    ));
+ALTER TABLE bridge_messages ADD COLUMN IF NOT EXISTS retryable_ticket_id TEXT;
+ALTER TABLE bridge_messages ADD COLUMN IF NOT EXISTS outbox_position NUMERIC(78, 0);
+ALTER TABLE bridge_messages ADD COLUMN IF NOT EXISTS checked_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_bridge_arbitrum_status
+    ON bridge_messages (arbitrum_status)
+    WHERE status IN ('initiated', 'pending');
+
+COMMENT ON COLUMN bridge_messages.arbitrum_status IS 'Protocol step resolved on-chain by the bridge monitor';
+COMMENT ON COLUMN bridge_messages.retryable_ticket_id IS 'L1→L2: L2 hash of the retryable submission';
+COMMENT ON COLUMN bridge_messages.outbox_position IS 'L2→L1: position of the L2ToL1Tx in the outbox';
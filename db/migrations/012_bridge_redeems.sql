@@ -0,0 +1,34 @@
+-- ============================================================
+-- Bridge Redeems
+-- Migration 012: Operator redeems of retryable tickets
+-- ============================================================
+-- A retryable ticket whose auto-redeem failed on L2 holds the
+-- user's deposit until someone calls ArbRetryableTx.redeem.
+-- The redeemer submits those calls from the operator key and
+-- records each attempt here. cost_wei is the gas reserved at
+-- submission and the gas actually paid once mined; the hourly
+-- spend cap is enforced over this table.
+-- ============================================================
+
+CREATE TABLE IF NOT EXISTS bridge_redeems (
+    id BIGSERIAL PRIMARY KEY,
+    message_hash TEXT NOT NULL,
+    ticket_id TEXT NOT NULL,
+    tx_hash TEXT,
+    gas_limit BIGINT NOT NULL DEFAULT 0,
+    gas_price NUMERIC(78, 0) NOT NULL DEFAULT 0,
+    cost_wei NUMERIC(78, 0) NOT NULL DEFAULT 0,
+    status TEXT NOT NULL CHECK (status IN ('submitted', 'mined', 'reverted', 'failed')),
+    error_msg TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_bridge_redeems_message
+    ON bridge_redeems (message_hash, created_at);
+
+CREATE INDEX IF NOT EXISTS idx_bridge_redeems_created
+    ON bridge_redeems (created_at);
+
+COMMENT ON TABLE bridge_redeems IS 'Retryable ticket redeems submitted by the operator';
+COMMENT ON COLUMN bridge_redeems.cost_wei IS 'Reserved (gas limit × price) while submitted; gas used × effective price once mined';
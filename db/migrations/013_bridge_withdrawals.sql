@@ -0,0 +1,40 @@
+-- ============================================================
+-- Bridge Withdrawals
+-- Migration 013: L2→L1 withdrawals finalized by the executor
+-- ============================================================
+-- The L2 gateway sends each withdrawal to the L1 Gateway through
+-- ArbSys. Once the assertion holding it is confirmed, the
+-- executor proves the message against the outbox, which calls
+-- L1Gateway.finalizeWithdrawal. This table keeps what the outbox
+-- needs to execute the message and how far the executor got;
+-- bridge_messages receives the same withdrawal as BridgeEvents.
+-- ============================================================
+
+CREATE TABLE IF NOT EXISTS bridge_withdrawals (
+    withdrawal_id TEXT PRIMARY KEY,
+    user_address TEXT NOT NULL,
+    amount NUMERIC(78, 0) NOT NULL,
+    outbox_position NUMERIC(78, 0) NOT NULL UNIQUE,
+    l2_sender TEXT NOT NULL,
+    destination TEXT NOT NULL,
+    l2_block_number BIGINT NOT NULL,
+    l1_block_number BIGINT NOT NULL,
+    l2_timestamp BIGINT NOT NULL,
+    call_value NUMERIC(78, 0) NOT NULL DEFAULT 0,
+    calldata BYTEA NOT NULL,
+    l2_tx_hash TEXT NOT NULL,
+    l1_tx_hash TEXT,
+    status TEXT NOT NULL DEFAULT 'waiting'
+        CHECK (status IN ('waiting', 'submitted', 'executed', 'failed')),
+    attempts INT NOT NULL DEFAULT 0,
+    error_msg TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    executed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_bridge_withdrawals_open
+    ON bridge_withdrawals (l2_block_number)
+    WHERE status IN ('waiting', 'submitted');
+
+COMMENT ON TABLE bridge_withdrawals IS 'L2→L1 withdrawals and their outbox execution';
+COMMENT ON COLUMN bridge_withdrawals.l1_block_number IS 'L1 block number ArbSys reported when the withdrawal was sent (executeTransaction l1Block)';
@@ -0,0 +1,29 @@
+-- ============================================================
+-- Bridge Message Queue
+-- Migration 014: Persistent, leased work queue for bridge messages
+-- ============================================================
+-- Each named queue (the monitor, the redeemer) keeps its items
+-- and their priority here, so priorities survive restarts.
+-- Workers lease the highest priority items with
+-- SELECT ... FOR UPDATE SKIP LOCKED; a lease hides the item from
+-- other workers until leased_until, after which an item whose
+-- worker died becomes visible again.
+-- ============================================================
+
+CREATE TABLE IF NOT EXISTS bridge_queue (
+    queue TEXT NOT NULL,
+    message_hash TEXT NOT NULL REFERENCES bridge_messages (message_hash) ON DELETE CASCADE,
+    priority INT NOT NULL DEFAULT 1,
+    enqueued_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    leased_until TIMESTAMPTZ,
+    lease_owner TEXT,
+    leases INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (queue, message_hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_bridge_queue_order
+    ON bridge_queue (queue, priority DESC, enqueued_at ASC);
+
+COMMENT ON TABLE bridge_queue IS 'Leased work queues over bridge_messages';
+COMMENT ON COLUMN bridge_queue.leased_until IS 'Visibility timeout: the item is hidden from other workers until then';
+COMMENT ON COLUMN bridge_queue.leases IS 'Times the item was leased; more than one means a worker released or lost it';
@@ -0,0 +1,26 @@
+-- ============================================================
+-- Bridge SLA
+-- Migration 015: Time spent in each bridge message status
+-- ============================================================
+-- initiated_at and confirmed_at bound the total latency;
+-- pending_at splits it into time initiated (waiting to be
+-- picked up on-chain) and time pending (in flight). Messages
+-- confirmed straight from initiated never set pending_at.
+-- ============================================================
+
+ALTER TABLE bridge_messages ADD COLUMN IF NOT EXISTS pending_at TIMESTAMP;
+
+-- Rows already in flight count as pending from their last check
+UPDATE bridge_messages
+SET pending_at = COALESCE(checked_at, updated_at)
+WHERE status = 'pending' AND pending_at IS NULL;
+
+-- Latency percentiles per direction and day
+CREATE INDEX IF NOT EXISTS idx_bridge_confirmed
+    ON bridge_messages (direction, confirmed_at)
+    WHERE status = 'confirmed';
+
+CREATE INDEX IF NOT EXISTS idx_bridge_direction_initiated
+    ON bridge_messages (direction, initiated_at);
+
+COMMENT ON COLUMN bridge_messages.pending_at IS 'When the message first moved to pending';
@@ -0,0 +1,22 @@
+-- ============================================================
+-- Airdrop Merkle Trees
+-- Migration 016: Merkle root per campaign, proof per allocation
+-- ============================================================
+-- Activating a campaign hashes every allocation into a leaf
+-- keccak256(abi.encodePacked(address, uint256 amount)) and
+-- builds a sorted-pair tree over them in import order, the
+-- same tree YielderaAirdrop.claim verifies. Token and native
+-- campaigns are settled by the distributor contract against
+-- merkle_root; points campaigns are credited off-chain after
+-- the same proof check.
+-- ============================================================
+
+ALTER TABLE airdrop_campaigns ADD COLUMN IF NOT EXISTS merkle_root TEXT;
+
+ALTER TABLE airdrop_allocations ADD COLUMN IF NOT EXISTS leaf_index INT;
+ALTER TABLE airdrop_allocations ADD COLUMN IF NOT EXISTS leaf_hash TEXT;
+ALTER TABLE airdrop_allocations ADD COLUMN IF NOT EXISTS merkle_proof JSONB;
+
+COMMENT ON COLUMN airdrop_campaigns.merkle_root IS 'Root of the allocation tree, set when the campaign is activated';
+COMMENT ON COLUMN airdrop_allocations.leaf_hash IS 'keccak256(abi.encodePacked(user_address, amount))';
+COMMENT ON COLUMN airdrop_allocations.merkle_proof IS 'Sibling hashes from the leaf up to merkle_root, as a JSON array of hex strings';
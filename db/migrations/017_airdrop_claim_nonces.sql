@@ -0,0 +1,31 @@
+-- ============================================================
+-- Airdrop Claim Nonces
+-- Migration 017: Server-issued single-use nonces for claims
+-- ============================================================
+-- A claim is an EIP-712 signature over
+-- Claim(campaignId, claimant, amount, nonce, deadline) in the
+-- domain of the configured chain and distributor. The nonce is
+-- issued here for one campaign and claimant, expires at the
+-- signed deadline and is marked used by the claim it authorizes,
+-- so a signature cannot be replayed.
+-- ============================================================
+
+CREATE TABLE IF NOT EXISTS airdrop_claim_nonces (
+    nonce TEXT PRIMARY KEY,
+    campaign_id INT NOT NULL REFERENCES airdrop_campaigns(id) ON DELETE CASCADE,
+    user_address TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_airdrop_claim_nonces_expires
+    ON airdrop_claim_nonces (expires_at);
+
+-- Outstanding nonce of a claimant, handed out again instead of a new one
+CREATE INDEX IF NOT EXISTS idx_airdrop_claim_nonces_open
+    ON airdrop_claim_nonces (campaign_id, user_address, expires_at)
+    WHERE used_at IS NULL;
+
+COMMENT ON TABLE airdrop_claim_nonces IS 'Single-use nonces airdrop claim signatures are bound to';
+COMMENT ON COLUMN airdrop_claim_nonces.expires_at IS 'Signed as the claim deadline (unix seconds)';
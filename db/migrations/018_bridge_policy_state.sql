@@ -0,0 +1,36 @@
+-- ============================================================
+-- Bridge Policy State
+-- Migration 018: Volume reservations and the circuit breaker
+-- ============================================================
+-- Every API replica checks bridge requests against the same
+-- state: accepted requests reserve their amount in the volume
+-- windows before the response is sent, and the circuit breaker
+-- is a single shared row.
+-- ============================================================
+
+CREATE TABLE IF NOT EXISTS bridge_volume_reservations (
+    id BIGSERIAL PRIMARY KEY,
+    direction VARCHAR(20) NOT NULL,
+    user_address VARCHAR(42) NOT NULL,
+    amount NUMERIC(78, 0) NOT NULL,
+    reserved_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_bridge_reservations_direction
+    ON bridge_volume_reservations (direction, reserved_at);
+
+CREATE INDEX IF NOT EXISTS idx_bridge_reservations_user
+    ON bridge_volume_reservations (lower(user_address), direction, reserved_at);
+
+CREATE TABLE IF NOT EXISTS bridge_circuit_breaker (
+    id SMALLINT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    open_until TIMESTAMP,
+    failures_since TIMESTAMP NOT NULL DEFAULT 'epoch',
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO bridge_circuit_breaker (id) VALUES (1) ON CONFLICT (id) DO NOTHING;
+
+COMMENT ON TABLE bridge_volume_reservations IS 'Amounts of bridge requests accepted by the API, counted in the volume windows';
+COMMENT ON COLUMN bridge_circuit_breaker.open_until IS 'Requests are refused until then; NULL while closed';
+COMMENT ON COLUMN bridge_circuit_breaker.failures_since IS 'Failures before this were already acted on and do not reopen the breaker';
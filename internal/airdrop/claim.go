@@ -0,0 +1,196 @@
+package airdrop
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"loyalty-points-system/internal/blockchain"
+)
+
+// EIP-712 domain of airdrop claims
+const (
+	claimDomainName    = "YielderaAirdrop"
+	claimDomainVersion = "1"
+)
+
+// claimType is the EIP-712 struct a claimant signs
+const claimType = "Claim(uint256 campaignId,address claimant,uint256 amount,bytes32 nonce,uint256 deadline)"
+
+var claimTypeHash = crypto.Keccak256([]byte(claimType))
+
+// Reasons a claim nonce is refused
+var (
+	ErrNonceUnknown  = errors.New("unknown nonce")
+	ErrNonceUsed     = errors.New("nonce already used")
+	ErrNonceExpired  = errors.New("nonce expired")
+	ErrNonceMismatch = errors.New("nonce was issued for another campaign, claimant or deadline")
+)
+
+// ClaimConfig is the EIP-712 domain claims are signed in and how long an
+// issued nonce stays valid
+type ClaimConfig struct {
+	ChainID     *big.Int
+	Distributor common.Address // verifyingContract; zero when no distributor is deployed
+	NonceTTL    time.Duration
+}
+
+// NewClaimConfig creates a claim config. distributor may be empty.
+func NewClaimConfig(chainID int64, distributor string, nonceTTL time.Duration) ClaimConfig {
+	if nonceTTL <= 0 {
+		nonceTTL = 10 * time.Minute
+	}
+	return ClaimConfig{
+		ChainID:     big.NewInt(chainID),
+		Distributor: common.HexToAddress(distributor),
+		NonceTTL:    nonceTTL,
+	}
+}
+
+// ClaimMessage is the Claim struct a claimant signs
+type ClaimMessage struct {
+	CampaignID int64
+	Claimant   common.Address
+	Amount     *big.Int
+	Nonce      common.Hash
+	Deadline   int64
+}
+
+// Hash returns the EIP-712 digest of a claim message in this domain
+func (c ClaimConfig) Hash(msg ClaimMessage) []byte {
+	domain := blockchain.HashDomain(blockchain.EIP712Domain{
+		Name:              claimDomainName,
+		Version:           claimDomainVersion,
+		ChainID:           c.ChainID,
+		VerifyingContract: c.Distributor,
+	})
+
+	data := make([]byte, 0, 5*32)
+	data = append(data, blockchain.PadBytes32(big.NewInt(msg.CampaignID).Bytes())...)
+	data = append(data, blockchain.PadBytes32(msg.Claimant.Bytes())...)
+	data = append(data, blockchain.PadBytes32(msg.Amount.Bytes())...)
+	data = append(data, msg.Nonce.Bytes()...)
+	data = append(data, blockchain.PadBytes32(big.NewInt(msg.Deadline).Bytes())...)
+
+	return blockchain.HashTypedData(domain, blockchain.HashStruct(claimTypeHash, data))
+}
+
+// TypedData returns a claim message in the eth_signTypedData_v4 format
+func (c ClaimConfig) TypedData(msg ClaimMessage) TypedData {
+	return TypedData{
+		Types: map[string][]TypedDataField{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"Claim": {
+				{Name: "campaignId", Type: "uint256"},
+				{Name: "claimant", Type: "address"},
+				{Name: "amount", Type: "uint256"},
+				{Name: "nonce", Type: "bytes32"},
+				{Name: "deadline", Type: "uint256"},
+			},
+		},
+		PrimaryType: "Claim",
+		Domain: map[string]interface{}{
+			"name":              claimDomainName,
+			"version":           claimDomainVersion,
+			"chainId":           c.ChainID.String(),
+			"verifyingContract": c.Distributor.Hex(),
+		},
+		Message: map[string]interface{}{
+			"campaignId": fmt.Sprint(msg.CampaignID),
+			"claimant":   msg.Claimant.Hex(),
+			"amount":     msg.Amount.String(),
+			"nonce":      msg.Nonce.Hex(),
+			"deadline":   fmt.Sprint(msg.Deadline),
+		},
+	}
+}
+
+// issueNonce returns the claimant's outstanding nonce for a campaign while it
+// leaves at least half its lifetime to sign, and otherwise stores a fresh
+// one, so repeated requests do not pile up rows. Returns it with its deadline.
+func issueNonce(db *sql.DB, campaignID int64, address string, ttl time.Duration) (common.Hash, int64, error) {
+	var existing string
+	var existingExpiry time.Time
+	err := db.QueryRow(`
+		SELECT nonce, expires_at
+		FROM airdrop_claim_nonces
+		WHERE campaign_id = $1 AND user_address = $2 AND used_at IS NULL AND expires_at > $3
+		ORDER BY expires_at DESC
+		LIMIT 1
+	`, campaignID, address, time.Now().Add(ttl/2)).Scan(&existing, &existingExpiry)
+	if err == nil {
+		return common.HexToHash(existing), existingExpiry.Unix(), nil
+	}
+	if err != sql.ErrNoRows {
+		return common.Hash{}, 0, err
+	}
+
+	var nonce common.Hash
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return common.Hash{}, 0, err
+	}
+	expiresAt := time.Now().Add(ttl).Truncate(time.Second)
+
+	_, err = db.Exec(`
+		INSERT INTO airdrop_claim_nonces (nonce, campaign_id, user_address, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`, nonce.Hex(), campaignID, address, expiresAt)
+	if err != nil {
+		return common.Hash{}, 0, err
+	}
+	return nonce, expiresAt.Unix(), nil
+}
+
+// useNonce marks the nonce of a claim used, after checking it was issued for
+// this campaign, claimant and deadline and has not expired
+func useNonce(tx *sql.Tx, msg ClaimMessage) error {
+	var campaignID int64
+	var address string
+	var expiresAt time.Time
+	var usedAt sql.NullTime
+	err := tx.QueryRow(`
+		SELECT campaign_id, user_address, expires_at, used_at
+		FROM airdrop_claim_nonces WHERE nonce = $1
+		FOR UPDATE
+	`, msg.Nonce.Hex()).Scan(&campaignID, &address, &expiresAt, &usedAt)
+	if err == sql.ErrNoRows {
+		return ErrNonceUnknown
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := checkNonce(msg, campaignID, address, expiresAt, usedAt, time.Now()); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE airdrop_claim_nonces SET used_at = NOW() WHERE nonce = $1`, msg.Nonce.Hex())
+	return err
+}
+
+// checkNonce refuses a stored nonce that was already used, was issued for
+// another campaign, claimant or deadline, or has expired by now
+func checkNonce(msg ClaimMessage, campaignID int64, address string, expiresAt time.Time, usedAt sql.NullTime, now time.Time) error {
+	if usedAt.Valid {
+		return ErrNonceUsed
+	}
+	if campaignID != msg.CampaignID || !common.IsHexAddress(address) ||
+		common.HexToAddress(address) != msg.Claimant || expiresAt.Unix() != msg.Deadline {
+		return ErrNonceMismatch
+	}
+	if !now.Before(expiresAt) {
+		return ErrNonceExpired
+	}
+	return nil
+}
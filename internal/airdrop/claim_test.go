@@ -0,0 +1,111 @@
+package airdrop
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+func testClaim() (ClaimConfig, ClaimMessage) {
+	config := NewClaimConfig(42161, "0x5FbDB2315678afecb367f032d93F642f64180aa3", 0)
+	msg := ClaimMessage{
+		CampaignID: 7,
+		Claimant:   common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8"),
+		Amount:     new(big.Int).Mul(big.NewInt(1250), big.NewInt(1e18)),
+		Nonce:      common.HexToHash("0x4f1b2c3d4e5f60718293a4b5c6d7e8f9000102030405060708090a0b0c0d0e0f"),
+		Deadline:   1767225600,
+	}
+	return config, msg
+}
+
+// TestClaimHashKnownVector pins the digest a wallet signs for a fixed claim,
+// as computed by eth_signTypedData_v4
+func TestClaimHashKnownVector(t *testing.T) {
+	config, msg := testClaim()
+	const want = "0x9b29605137a1c1dd95b3e5db1bea08500d2cb77ce3c951baacfa81db0a2ac2f6"
+	if got := common.BytesToHash(config.Hash(msg)).Hex(); got != want {
+		t.Fatalf("digest = %s, want %s", got, want)
+	}
+}
+
+// TestClaimHashMatchesTypedData checks the digest against go-ethereum's
+// eth_signTypedData_v4 hashing of the typed data handed to wallets
+func TestClaimHashMatchesTypedData(t *testing.T) {
+	config, msg := testClaim()
+	tests := []struct {
+		name   string
+		config ClaimConfig
+		msg    ClaimMessage
+	}{
+		{"claim", config, msg},
+		{"no distributor", NewClaimConfig(1, "", 0), msg},
+		{"points at leaf scale", config, ClaimMessage{
+			CampaignID: 1,
+			Claimant:   msg.Claimant,
+			Amount:     big.NewInt(500000000000000000),
+			Nonce:      common.HexToHash("0x01"),
+			Deadline:   1700000000,
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := json.Marshal(tc.config.TypedData(tc.msg))
+			if err != nil {
+				t.Fatalf("marshal typed data: %v", err)
+			}
+			var typed apitypes.TypedData
+			if err := json.Unmarshal(data, &typed); err != nil {
+				t.Fatalf("unmarshal typed data: %v", err)
+			}
+			want, _, err := apitypes.TypedDataAndHash(typed)
+			if err != nil {
+				t.Fatalf("hash typed data: %v", err)
+			}
+			if got := tc.config.Hash(tc.msg); common.BytesToHash(got) != common.BytesToHash(want) {
+				t.Fatalf("digest = %x, want %x", got, want)
+			}
+		})
+	}
+}
+
+func TestCheckNonce(t *testing.T) {
+	_, msg := testClaim()
+	expiresAt := time.Unix(msg.Deadline, 0)
+	address := msg.Claimant.Hex()
+	before := expiresAt.Add(-time.Minute)
+	used := sql.NullTime{Time: before, Valid: true}
+
+	tests := []struct {
+		name       string
+		campaignID int64
+		address    string
+		expiresAt  time.Time
+		usedAt     sql.NullTime
+		now        time.Time
+		err        error
+	}{
+		{"valid", msg.CampaignID, address, expiresAt, sql.NullTime{}, before, nil},
+		{"lowercase address", msg.CampaignID, "0x70997970c51812dc3a010c7d01b50e0d17dc79c8", expiresAt, sql.NullTime{}, before, nil},
+		{"replayed", msg.CampaignID, address, expiresAt, used, before, ErrNonceUsed},
+		{"replayed after expiry", msg.CampaignID, address, expiresAt, used, expiresAt.Add(time.Hour), ErrNonceUsed},
+		{"expired", msg.CampaignID, address, expiresAt, sql.NullTime{}, expiresAt.Add(time.Second), ErrNonceExpired},
+		{"at deadline", msg.CampaignID, address, expiresAt, sql.NullTime{}, expiresAt, ErrNonceExpired},
+		{"other campaign", msg.CampaignID + 1, address, expiresAt, sql.NullTime{}, before, ErrNonceMismatch},
+		{"other claimant", msg.CampaignID, "0x1111111111111111111111111111111111111111", expiresAt, sql.NullTime{}, before, ErrNonceMismatch},
+		{"other deadline", msg.CampaignID, address, expiresAt.Add(time.Minute), sql.NullTime{}, before, ErrNonceMismatch},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkNonce(msg, tc.campaignID, tc.address, tc.expiresAt, tc.usedAt, tc.now)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("err = %v, want %v", err, tc.err)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package airdrop
 import (
 	"database/sql"
 	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -11,8 +13,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/gin-gonic/gin"
-	"loyalty-points-system/services/api/middleware"
+	"loyalty-points-system/internal/blockchain"
 )
 
 // AdminAuthMiddleware validates that the requester is an admin
@@ -301,15 +305,24 @@ func ImportAllocationsHandler(db *sql.DB) gin.HandlerFunc {
 	}
 }
 
-// ActivateCampaignHandler activates a campaign
+// ActivateCampaignHandler activates a campaign. The allocations are frozen
+// into a Merkle tree whose root a distributor contract can be funded with.
 func ActivateCampaignHandler(db *sql.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		campaignID := c.Param("id")
 
+		// Begin transaction
+		tx, err := db.Begin()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
 		// Check current status
-		var status string
+		var status, assetType string
 		var startTime time.Time
-		err := db.QueryRow(`SELECT status, start_time FROM airdrop_campaigns WHERE id = $1`, campaignID).Scan(&status, &startTime)
+		err = tx.QueryRow(`SELECT status, asset_type, start_time FROM airdrop_campaigns WHERE id = $1 FOR UPDATE`, campaignID).Scan(&status, &assetType, &startTime)
 		if err == sql.ErrNoRows {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
 			return
@@ -324,6 +337,18 @@ func ActivateCampaignHandler(db *sql.DB) gin.HandlerFunc {
 			return
 		}
 
+		// Build the Merkle tree over the allocations
+		root, leafCount, err := buildCampaignTree(tx, campaignID, assetType)
+		if errors.Is(err, ErrNoAllocations) || errors.Is(err, ErrInvalidAmount) || errors.Is(err, ErrInvalidAddress) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			log.Printf("Build merkle tree error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build merkle tree"})
+			return
+		}
+
 		// Determine new status based on start time
 		newStatus := StatusActive
 		if time.Now().Before(startTime) {
@@ -331,16 +356,24 @@ func ActivateCampaignHandler(db *sql.DB) gin.HandlerFunc {
 		}
 
 		// Update status
-		_, err = db.Exec(`UPDATE airdrop_campaigns SET status = $1, updated_at = $2 WHERE id = $3`, newStatus, time.Now(), campaignID)
+		_, err = tx.Exec(`UPDATE airdrop_campaigns SET status = $1, updated_at = $2 WHERE id = $3`, newStatus, time.Now(), campaignID)
 		if err != nil {
 			log.Printf("Activate campaign error: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to activate campaign"})
 			return
 		}
 
+		// Commit transaction
+		if err := tx.Commit(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+			return
+		}
+
 		c.JSON(http.StatusOK, gin.H{
-			"message": "Campaign activated successfully",
-			"status":  newStatus,
+			"message":     "Campaign activated successfully",
+			"status":      newStatus,
+			"merkle_root": root.Hex(),
+			"leaf_count":  leafCount,
 		})
 	}
 }
@@ -370,7 +403,8 @@ func GetCampaignsHandler(db *sql.DB) gin.HandlerFunc {
 		offset := c.DefaultQuery("offset", "0")
 
 		query := `SELECT id, name, description, asset_type, status, start_time, end_time,
-		          total_budget, claimed_amount, participant_count, is_demo, created_by, created_at, updated_at
+		          total_budget, claimed_amount, participant_count, is_demo, created_by, created_at, updated_at,
+		          COALESCE(merkle_root, '')
 		          FROM airdrop_campaigns WHERE 1=1`
 		args := []interface{}{}
 		argCount := 1
@@ -399,7 +433,7 @@ func GetCampaignsHandler(db *sql.DB) gin.HandlerFunc {
 				&campaign.ID, &campaign.Name, &campaign.Description, &campaign.AssetType,
 				&campaign.Status, &campaign.StartTime, &campaign.EndTime, &campaign.TotalBudget,
 				&campaign.ClaimedAmount, &campaign.ParticipantCount, &campaign.IsDemo,
-				&campaign.CreatedBy, &campaign.CreatedAt, &campaign.UpdatedAt,
+				&campaign.CreatedBy, &campaign.CreatedAt, &campaign.UpdatedAt, &campaign.MerkleRoot,
 			)
 			if err != nil {
 				log.Printf("Scan campaign error: %v", err)
@@ -420,13 +454,14 @@ func GetCampaignHandler(db *sql.DB) gin.HandlerFunc {
 		var campaign Campaign
 		err := db.QueryRow(`
 			SELECT id, name, description, asset_type, status, start_time, end_time,
-			       total_budget, claimed_amount, participant_count, is_demo, created_by, created_at, updated_at
+			       total_budget, claimed_amount, participant_count, is_demo, created_by, created_at, updated_at,
+			       COALESCE(merkle_root, '')
 			FROM airdrop_campaigns WHERE id = $1
 		`, campaignID).Scan(
 			&campaign.ID, &campaign.Name, &campaign.Description, &campaign.AssetType,
 			&campaign.Status, &campaign.StartTime, &campaign.EndTime, &campaign.TotalBudget,
 			&campaign.ClaimedAmount, &campaign.ParticipantCount, &campaign.IsDemo,
-			&campaign.CreatedBy, &campaign.CreatedAt, &campaign.UpdatedAt,
+			&campaign.CreatedBy, &campaign.CreatedAt, &campaign.UpdatedAt, &campaign.MerkleRoot,
 		)
 
 		if err == sql.ErrNoRows {
@@ -516,23 +551,170 @@ func CheckEligibilityHandler(db *sql.DB) gin.HandlerFunc {
 	}
 }
 
-// ClaimAirdropHandler processes an airdrop claim
-func ClaimAirdropHandler(db *sql.DB) gin.HandlerFunc {
+// GetProofHandler returns the Merkle leaf and proof of a user's allocation,
+// as submitted to the distributor contract's claim
+func GetProofHandler(db *sql.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		campaignID := c.Param("id")
-		var req ClaimRequest
+		address := strings.ToLower(c.Query("address"))
+
+		if address == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "address is required"})
+			return
+		}
+
+		var proof ProofResponse
+		var assetType string
+		err := db.QueryRow(`SELECT id, asset_type, COALESCE(merkle_root, '') FROM airdrop_campaigns WHERE id = $1`, campaignID).Scan(&proof.CampaignID, &assetType, &proof.MerkleRoot)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		if proof.MerkleRoot == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Campaign has not been activated"})
+			return
+		}
+
+		var amount string
+		var proofJSON []byte
+		err = db.QueryRow(`
+			SELECT amount::text, COALESCE(leaf_index, 0), COALESCE(leaf_hash, ''), merkle_proof
+			FROM airdrop_allocations WHERE campaign_id = $1 AND user_address = $2
+		`, campaignID, address).Scan(&amount, &proof.LeafIndex, &proof.Leaf, &proofJSON)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Not in whitelist"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+
+		value, err := leafAmount(assetType, amount)
+		if err != nil || proofJSON == nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Allocation has no merkle proof"})
+			return
+		}
+		if err := json.Unmarshal(proofJSON, &proof.Proof); err != nil {
+			log.Printf("Decode merkle proof error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Allocation has no merkle proof"})
+			return
+		}
+
+		proof.Address = address
+		proof.Amount = value.String()
+		c.JSON(http.StatusOK, proof)
+	}
+}
+
+// IssueNonceHandler issues a single-use claim nonce to an eligible address
+// and returns the EIP-712 typed data the claim has to sign
+func IssueNonceHandler(db *sql.DB, claims ClaimConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		campaignID := c.Param("id")
+		var req NonceRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
 		address := strings.ToLower(req.Address)
+		if !common.IsHexAddress(address) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address"})
+			return
+		}
 
-		// Verify signature
-		message := fmt.Sprintf("Claim airdrop from campaign %s with nonce %s", campaignID, req.Nonce)
-		if err := middleware.VerifySignature(address, message, req.Signature); err != nil {
-			log.Printf("Signature verification failed: %v", err)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+		// Check campaign status
+		var id int64
+		var status, assetType string
+		err := db.QueryRow(`SELECT id, status, asset_type FROM airdrop_campaigns WHERE id = $1`, campaignID).Scan(&id, &status, &assetType)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+
+		if status != StatusActive && status != StatusClaimable {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Campaign is not active"})
+			return
+		}
+
+		// Get allocation amount, unless already claimed
+		var amount string
+		var claimed bool
+		err = db.QueryRow(`
+			SELECT a.amount::text,
+			       EXISTS(SELECT 1 FROM airdrop_claims WHERE campaign_id = a.campaign_id AND user_address = a.user_address)
+			FROM airdrop_allocations a WHERE a.campaign_id = $1 AND a.user_address = $2
+		`, campaignID, address).Scan(&amount, &claimed)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not eligible for this airdrop"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+
+		if claimed {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Already claimed"})
+			return
+		}
+
+		value, err := leafAmount(assetType, amount)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		nonce, deadline, err := issueNonce(db, id, address, claims.NonceTTL)
+		if err != nil {
+			log.Printf("Issue nonce error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue nonce"})
+			return
+		}
+
+		c.JSON(http.StatusOK, NonceResponse{
+			Nonce:    nonce.Hex(),
+			Deadline: deadline,
+			TypedData: claims.TypedData(ClaimMessage{
+				CampaignID: id,
+				Claimant:   common.HexToAddress(address),
+				Amount:     value,
+				Nonce:      nonce,
+				Deadline:   deadline,
+			}),
+		})
+	}
+}
+
+// ClaimAirdropHandler processes an airdrop claim. The claimant signs an
+// EIP-712 Claim over their allocation with a nonce from IssueNonceHandler;
+// the nonce is used up by the claim and refused once expired.
+func ClaimAirdropHandler(db *sql.DB, claims ClaimConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		campaignID := c.Param("id")
+		var req ClaimRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		address := strings.ToLower(req.Address)
+		if !common.IsHexAddress(address) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address"})
+			return
+		}
+		nonce, err := hexutil.Decode(req.Nonce)
+		if err != nil || len(nonce) != common.HashLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "nonce must be 32 bytes of hex"})
 			return
 		}
 
@@ -545,8 +727,9 @@ func ClaimAirdropHandler(db *sql.DB) gin.HandlerFunc {
 		defer tx.Rollback()
 
 		// Check campaign status
-		var status, assetType string
-		err = tx.QueryRow(`SELECT status, asset_type FROM airdrop_campaigns WHERE id = $1`, campaignID).Scan(&status, &assetType)
+		var id int64
+		var status, assetType, merkleRoot string
+		err = tx.QueryRow(`SELECT id, status, asset_type, COALESCE(merkle_root, '') FROM airdrop_campaigns WHERE id = $1`, campaignID).Scan(&id, &status, &assetType, &merkleRoot)
 		if err == sql.ErrNoRows {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
 			return
@@ -576,7 +759,8 @@ func ClaimAirdropHandler(db *sql.DB) gin.HandlerFunc {
 
 		// Get allocation amount
 		var amount string
-		err = tx.QueryRow(`SELECT amount FROM airdrop_allocations WHERE campaign_id = $1 AND user_address = $2`, campaignID, address).Scan(&amount)
+		var proofJSON []byte
+		err = tx.QueryRow(`SELECT amount, merkle_proof FROM airdrop_allocations WHERE campaign_id = $1 AND user_address = $2`, campaignID, address).Scan(&amount, &proofJSON)
 		if err == sql.ErrNoRows {
 			c.JSON(http.StatusForbidden, gin.H{"error": "Not eligible for this airdrop"})
 			return
@@ -586,11 +770,52 @@ func ClaimAirdropHandler(db *sql.DB) gin.HandlerFunc {
 			return
 		}
 
+		// Verify the allocation against the campaign Merkle root. Points
+		// campaigns activated before roots existed keep claiming off-chain.
+		if merkleRoot != "" || assetType != AssetTypePoints {
+			if err := verifyAllocation(merkleRoot, assetType, address, amount, proofJSON); err != nil {
+				log.Printf("Merkle verification failed for %s in campaign %s: %v", address, campaignID, err)
+				c.JSON(http.StatusForbidden, gin.H{"error": "Allocation does not match the campaign merkle root"})
+				return
+			}
+		}
+
+		value, err := leafAmount(assetType, amount)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		msg := ClaimMessage{
+			CampaignID: id,
+			Claimant:   common.HexToAddress(address),
+			Amount:     value,
+			Nonce:      common.BytesToHash(nonce),
+			Deadline:   req.Deadline,
+		}
+
+		// Use up the nonce, then verify the typed signature over it
+		if err := useNonce(tx, msg); err != nil {
+			if errors.Is(err, ErrNonceUnknown) || errors.Is(err, ErrNonceUsed) ||
+				errors.Is(err, ErrNonceExpired) || errors.Is(err, ErrNonceMismatch) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			log.Printf("Use nonce error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		valid, err := blockchain.VerifyEIP712Signature(req.Signature, claims.Hash(msg), address)
+		if err != nil || !valid {
+			log.Printf("Signature verification failed for %s in campaign %s: %v", address, campaignID, err)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+			return
+		}
+
 		// Insert claim record
 		_, err = tx.Exec(`
 			INSERT INTO airdrop_claims (campaign_id, user_address, amount, nonce, signature)
 			VALUES ($1, $2, $3, $4, $5)
-		`, campaignID, address, amount, req.Nonce, req.Signature)
+		`, campaignID, address, amount, msg.Nonce.Hex(), req.Signature)
 		if err != nil {
 			if strings.Contains(err.Error(), "duplicate") {
 				c.JSON(http.StatusBadRequest, gin.H{"error": "Already claimed"})
@@ -646,6 +871,22 @@ func ClaimAirdropHandler(db *sql.DB) gin.HandlerFunc {
 			return
 		}
 
+		// Tokens and native assets are paid by the distributor contract
+		if assetType != AssetTypePoints {
+			var proof []common.Hash
+			if err := json.Unmarshal(proofJSON, &proof); err != nil {
+				log.Printf("Decode merkle proof error: %v", err)
+			}
+			c.JSON(http.StatusOK, gin.H{
+				"message":     "Airdrop claim verified, submit the proof to the distributor contract",
+				"amount":      value.String(),
+				"settlement":  "onchain",
+				"merkle_root": merkleRoot,
+				"proof":       proof,
+			})
+			return
+		}
+
 		c.JSON(http.StatusOK, gin.H{
 			"message": "Airdrop claimed successfully",
 			"amount":  amount,
@@ -0,0 +1,221 @@
+package airdrop
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Reasons a campaign tree cannot be built or a claim does not verify
+var (
+	ErrNoAllocations  = errors.New("campaign has no allocations")
+	ErrInvalidAmount  = errors.New("invalid allocation amount")
+	ErrInvalidAddress = errors.New("invalid allocation address")
+	ErrNoMerkleRoot   = errors.New("campaign has no merkle root")
+	ErrInvalidProof   = errors.New("allocation does not match the campaign merkle root")
+)
+
+// MerkleTree is a sorted-pair Merkle tree as verified by OpenZeppelin's
+// MerkleProof and built by merkletreejs with sortPairs: a lone node at the
+// end of a level is carried up unchanged.
+type MerkleTree struct {
+	layers [][]common.Hash // layers[0] are the leaves, the last layer holds the root
+}
+
+// NewMerkleTree builds a tree over leaves in the given order
+func NewMerkleTree(leaves []common.Hash) *MerkleTree {
+	layer := append([]common.Hash(nil), leaves...)
+	tree := &MerkleTree{layers: [][]common.Hash{layer}}
+	for len(layer) > 1 {
+		next := make([]common.Hash, 0, (len(layer)+1)/2)
+		for i := 0; i < len(layer); i += 2 {
+			if i+1 == len(layer) {
+				next = append(next, layer[i])
+				continue
+			}
+			next = append(next, hashPair(layer[i], layer[i+1]))
+		}
+		tree.layers = append(tree.layers, next)
+		layer = next
+	}
+	return tree
+}
+
+// Root returns the tree root, the zero hash for an empty tree
+func (t *MerkleTree) Root() common.Hash {
+	top := t.layers[len(t.layers)-1]
+	if len(top) == 0 {
+		return common.Hash{}
+	}
+	return top[0]
+}
+
+// Proof returns the sibling hashes from the leaf at index up to the root
+func (t *MerkleTree) Proof(index int) []common.Hash {
+	proof := []common.Hash{}
+	for _, layer := range t.layers[:len(t.layers)-1] {
+		sibling := index ^ 1
+		if sibling < len(layer) {
+			proof = append(proof, layer[sibling])
+		}
+		index /= 2
+	}
+	return proof
+}
+
+// VerifyProof reports whether leaf is in the tree with the given root
+func VerifyProof(proof []common.Hash, root, leaf common.Hash) bool {
+	computed := leaf
+	for _, sibling := range proof {
+		computed = hashPair(computed, sibling)
+	}
+	return computed == root
+}
+
+// LeafHash is keccak256(abi.encodePacked(account, amount)), the leaf
+// YielderaAirdrop.claim checks for msg.sender
+func LeafHash(account common.Address, amount *big.Int) common.Hash {
+	return crypto.Keccak256Hash(account.Bytes(), common.LeftPadBytes(amount.Bytes(), 32))
+}
+
+func hashPair(a, b common.Hash) common.Hash {
+	if bytes.Compare(a.Bytes(), b.Bytes()) > 0 {
+		a, b = b, a
+	}
+	return crypto.Keccak256Hash(a.Bytes(), b.Bytes())
+}
+
+// pointsDecimals is the fixed precision points amounts are hashed at, the
+// scale of the NUMERIC(78, 18) amount column
+const pointsDecimals = 18
+
+var pointsScale = new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(pointsDecimals), nil))
+
+// parseAmount parses an allocation amount (NUMERIC text such as
+// "1000.000000000000000000") into base units; it has to be a whole number
+func parseAmount(amount string) (*big.Int, error) {
+	r, ok := new(big.Rat).SetString(amount)
+	if !ok || !r.IsInt() || r.Sign() <= 0 || r.Num().BitLen() > 256 {
+		return nil, fmt.Errorf("%w: %s is not a positive whole number of base units", ErrInvalidAmount, amount)
+	}
+	return new(big.Int).Set(r.Num()), nil
+}
+
+// leafAmount is the uint256 amount of an allocation in its leaf. Tokens and
+// native assets are paid on-chain and need whole base units; points stay
+// off-chain and may be fractional, so they are scaled by 10^pointsDecimals.
+func leafAmount(assetType, amount string) (*big.Int, error) {
+	if assetType != AssetTypePoints {
+		return parseAmount(amount)
+	}
+	r, ok := new(big.Rat).SetString(amount)
+	if ok {
+		r.Mul(r, pointsScale)
+	}
+	if !ok || !r.IsInt() || r.Sign() <= 0 || r.Num().BitLen() > 256 {
+		return nil, fmt.Errorf("%w: %s is not a positive amount of points with at most %d decimals", ErrInvalidAmount, amount, pointsDecimals)
+	}
+	return new(big.Int).Set(r.Num()), nil
+}
+
+// allocationLeaf hashes one allocation row
+func allocationLeaf(assetType, address, amount string) (common.Hash, error) {
+	if !common.IsHexAddress(address) {
+		return common.Hash{}, fmt.Errorf("%w: %s", ErrInvalidAddress, address)
+	}
+	value, err := leafAmount(assetType, amount)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return LeafHash(common.HexToAddress(address), value), nil
+}
+
+// buildCampaignTree hashes the allocations of a campaign in import order,
+// stores each leaf with its index and proof, and stores the root on the
+// campaign. Returns the root and the number of leaves.
+func buildCampaignTree(tx *sql.Tx, campaignID, assetType string) (common.Hash, int, error) {
+	rows, err := tx.Query(`
+		SELECT id, user_address, amount::text
+		FROM airdrop_allocations
+		WHERE campaign_id = $1
+		ORDER BY id
+	`, campaignID)
+	if err != nil {
+		return common.Hash{}, 0, err
+	}
+
+	var ids []int
+	var leaves []common.Hash
+	for rows.Next() {
+		var id int
+		var address, amount string
+		if err := rows.Scan(&id, &address, &amount); err != nil {
+			rows.Close()
+			return common.Hash{}, 0, err
+		}
+		leaf, err := allocationLeaf(assetType, address, amount)
+		if err != nil {
+			rows.Close()
+			return common.Hash{}, 0, err
+		}
+		ids = append(ids, id)
+		leaves = append(leaves, leaf)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return common.Hash{}, 0, err
+	}
+	if len(leaves) == 0 {
+		return common.Hash{}, 0, ErrNoAllocations
+	}
+
+	tree := NewMerkleTree(leaves)
+	for i, id := range ids {
+		proof, err := json.Marshal(tree.Proof(i))
+		if err != nil {
+			return common.Hash{}, 0, err
+		}
+		_, err = tx.Exec(`
+			UPDATE airdrop_allocations
+			SET leaf_index = $1, leaf_hash = $2, merkle_proof = $3
+			WHERE id = $4
+		`, i, leaves[i].Hex(), string(proof), id)
+		if err != nil {
+			return common.Hash{}, 0, err
+		}
+	}
+
+	root := tree.Root()
+	if _, err := tx.Exec(`UPDATE airdrop_campaigns SET merkle_root = $1 WHERE id = $2`, root.Hex(), campaignID); err != nil {
+		return common.Hash{}, 0, err
+	}
+	return root, len(leaves), nil
+}
+
+// verifyAllocation checks an allocation and its stored proof against the
+// campaign root
+func verifyAllocation(root, assetType, address, amount string, proofJSON []byte) error {
+	if root == "" {
+		return ErrNoMerkleRoot
+	}
+	leaf, err := allocationLeaf(assetType, address, amount)
+	if err != nil {
+		return err
+	}
+	var proof []common.Hash
+	if len(proofJSON) > 0 {
+		if err := json.Unmarshal(proofJSON, &proof); err != nil {
+			return fmt.Errorf("decode merkle proof: %w", err)
+		}
+	}
+	if !VerifyProof(proof, common.HexToHash(root), leaf) {
+		return ErrInvalidProof
+	}
+	return nil
+}
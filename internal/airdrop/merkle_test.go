@@ -0,0 +1,214 @@
+package airdrop
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"golang.org/x/crypto/sha3"
+)
+
+func testLeaves(n int) []common.Hash {
+	leaves := make([]common.Hash, n)
+	for i := range leaves {
+		leaves[i] = LeafHash(common.BigToAddress(big.NewInt(int64(i+1))), big.NewInt(int64(1000*(i+1))))
+	}
+	return leaves
+}
+
+func TestMerkleTreeCarriesOddLeafUp(t *testing.T) {
+	l := testLeaves(7)
+	tests := []struct {
+		name string
+		n    int
+		want common.Hash
+	}{
+		{"one leaf", 1, l[0]},
+		{"two leaves", 2, hashPair(l[0], l[1])},
+		{"three leaves", 3, hashPair(hashPair(l[0], l[1]), l[2])},
+		{"five leaves", 5, hashPair(hashPair(hashPair(l[0], l[1]), hashPair(l[2], l[3])), l[4])},
+		{"six leaves", 6, hashPair(hashPair(hashPair(l[0], l[1]), hashPair(l[2], l[3])), hashPair(l[4], l[5]))},
+		{"seven leaves", 7, hashPair(hashPair(hashPair(l[0], l[1]), hashPair(l[2], l[3])), hashPair(hashPair(l[4], l[5]), l[6]))},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := NewMerkleTree(l[:tc.n]).Root(); got != tc.want {
+				t.Fatalf("root = %s, want %s", got.Hex(), tc.want.Hex())
+			}
+		})
+	}
+
+	if root := NewMerkleTree(nil).Root(); root != (common.Hash{}) {
+		t.Fatalf("empty tree root = %s, want zero hash", root.Hex())
+	}
+}
+
+func TestMerkleProofRoundTrip(t *testing.T) {
+	for n := 1; n <= 9; n++ {
+		leaves := testLeaves(n)
+		tree := NewMerkleTree(leaves)
+		for i, leaf := range leaves {
+			t.Run(fmt.Sprintf("%d leaves/index %d", n, i), func(t *testing.T) {
+				if !VerifyProof(tree.Proof(i), tree.Root(), leaf) {
+					t.Fatalf("proof %v does not verify", tree.Proof(i))
+				}
+			})
+		}
+	}
+}
+
+func TestVerifyProofRejectsTampering(t *testing.T) {
+	leaves := testLeaves(5)
+	tree := NewMerkleTree(leaves)
+	root, leaf, proof := tree.Root(), leaves[2], tree.Proof(2)
+
+	flipped := append([]common.Hash(nil), proof...)
+	flipped[0][31] ^= 1
+
+	tests := []struct {
+		name  string
+		proof []common.Hash
+		root  common.Hash
+		leaf  common.Hash
+	}{
+		{"flipped sibling", flipped, root, leaf},
+		{"dropped sibling", proof[:len(proof)-1], root, leaf},
+		{"extra sibling", append(append([]common.Hash(nil), proof...), leaves[0]), root, leaf},
+		{"other leaf", proof, root, leaves[3]},
+		{"other root", proof, NewMerkleTree(leaves[:4]).Root(), leaf},
+		{"other amount", proof, root, LeafHash(common.BigToAddress(big.NewInt(3)), big.NewInt(3001))},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if VerifyProof(tc.proof, tc.root, tc.leaf) {
+				t.Fatal("tampered proof verified")
+			}
+		})
+	}
+}
+
+// encodePacked hashes abi.encodePacked(address, uint256) spelled out byte by
+// byte: the 20 address bytes followed by the 32-byte big-endian amount
+func encodePacked(t *testing.T, address string, amount *big.Int) common.Hash {
+	t.Helper()
+	packed, err := hex.DecodeString(strings.TrimPrefix(address, "0x") + fmt.Sprintf("%064x", amount))
+	if err != nil {
+		t.Fatalf("decode %s: %v", address, err)
+	}
+	h := sha3.NewLegacyKeccak256()
+	h.Write(packed)
+	return common.BytesToHash(h.Sum(nil))
+}
+
+func TestLeafHashMatchesEncodePacked(t *testing.T) {
+	maxUint256 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	tests := []struct {
+		name    string
+		address string
+		amount  *big.Int
+	}{
+		{"one wei", "0x1111111111111111111111111111111111111111", big.NewInt(1)},
+		{"1000 tokens", "0x742d35Cc6634C0532925a3b844Bc454e4438f44e", new(big.Int).Mul(big.NewInt(1000), big.NewInt(1e18))},
+		{"leading zero address", "0x00000000000000000000000000000000000000aa", big.NewInt(255)},
+		{"max uint256", "0xffffffffffffffffffffffffffffffffffffffff", maxUint256},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := LeafHash(common.HexToAddress(tc.address), tc.amount)
+			if want := encodePacked(t, strings.ToLower(tc.address), tc.amount); got != want {
+				t.Fatalf("leaf = %s, want %s", got.Hex(), want.Hex())
+			}
+		})
+	}
+}
+
+func TestLeafAmount(t *testing.T) {
+	tests := []struct {
+		assetType string
+		amount    string
+		want      string
+		err       error
+	}{
+		{AssetTypeTokens, "1000000000000000000", "1000000000000000000", nil},
+		{AssetTypeTokens, "1000.000000000000000000", "1000", nil},
+		{AssetTypeNative, "42", "42", nil},
+		{AssetTypeTokens, "1.5", "", ErrInvalidAmount},
+		{AssetTypeTokens, "0", "", ErrInvalidAmount},
+		{AssetTypeTokens, "-1", "", ErrInvalidAmount},
+		{AssetTypeTokens, "abc", "", ErrInvalidAmount},
+		{AssetTypeTokens, "1" + strings.Repeat("0", 78), "", ErrInvalidAmount},
+		{AssetTypePoints, "250", "250000000000000000000", nil},
+		{AssetTypePoints, "0.5", "500000000000000000", nil},
+		{AssetTypePoints, "1000.000000000000000001", "1000000000000000000001", nil},
+		{AssetTypePoints, "0.0000000000000000001", "", ErrInvalidAmount},
+		{AssetTypePoints, "0", "", ErrInvalidAmount},
+	}
+	for _, tc := range tests {
+		t.Run(tc.assetType+"/"+tc.amount, func(t *testing.T) {
+			got, err := leafAmount(tc.assetType, tc.amount)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("err = %v, want %v", err, tc.err)
+			}
+			if tc.err == nil && got.String() != tc.want {
+				t.Fatalf("amount = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestVerifyAllocation(t *testing.T) {
+	allocations := []struct{ address, amount string }{
+		{"0x1111111111111111111111111111111111111111", "100"},
+		{"0x2222222222222222222222222222222222222222", "250.5"},
+		{"0x3333333333333333333333333333333333333333", "0.000000000000000001"},
+	}
+	leaves := make([]common.Hash, len(allocations))
+	for i, a := range allocations {
+		leaf, err := allocationLeaf(AssetTypePoints, a.address, a.amount)
+		if err != nil {
+			t.Fatalf("leaf %d: %v", i, err)
+		}
+		leaves[i] = leaf
+	}
+	tree := NewMerkleTree(leaves)
+	root := tree.Root().Hex()
+	proof := func(i int) []byte {
+		data, err := json.Marshal(tree.Proof(i))
+		if err != nil {
+			t.Fatalf("marshal proof: %v", err)
+		}
+		return data
+	}
+
+	tests := []struct {
+		name    string
+		root    string
+		address string
+		amount  string
+		proof   []byte
+		err     error
+	}{
+		{"first", root, allocations[0].address, allocations[0].amount, proof(0), nil},
+		{"carried leaf", root, allocations[2].address, allocations[2].amount, proof(2), nil},
+		{"same amount other scale", root, allocations[1].address, "250.500", proof(1), nil},
+		{"other amount", root, allocations[1].address, "251", proof(1), ErrInvalidProof},
+		{"other address", root, allocations[0].address, allocations[1].amount, proof(1), ErrInvalidProof},
+		{"other proof", root, allocations[0].address, allocations[0].amount, proof(1), ErrInvalidProof},
+		{"no proof", root, allocations[0].address, allocations[0].amount, nil, ErrInvalidProof},
+		{"no root", "", allocations[0].address, allocations[0].amount, proof(0), ErrNoMerkleRoot},
+		{"bad address", root, "0x1234", allocations[0].amount, proof(0), ErrInvalidAddress},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := verifyAllocation(tc.root, AssetTypePoints, tc.address, tc.amount, tc.proof)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("err = %v, want %v", err, tc.err)
+			}
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package airdrop
 
-import "time"
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
 
 // Campaign status constants
 const (
@@ -35,6 +39,7 @@ type Campaign struct {
 	CreatedBy        string    `json:"created_by"`
 	CreatedAt        time.Time `json:"created_at"`
 	UpdatedAt        time.Time `json:"updated_at"`
+	MerkleRoot       string    `json:"merkle_root,omitempty"` // set at activation
 }
 
 // Allocation represents a user's allocation in a campaign
@@ -85,10 +90,52 @@ type UpdateCampaignRequest struct {
 	TotalBudget string    `json:"total_budget"`
 }
 
-// ClaimRequest represents the request to claim airdrop
+// ProofResponse is a user's allocation as a leaf of the campaign Merkle tree;
+// Amount is the leaf amount: base units for tokens and native assets, points
+// scaled by 10^18
+type ProofResponse struct {
+	CampaignID int           `json:"campaign_id"`
+	Address    string        `json:"address"`
+	Amount     string        `json:"amount"`
+	LeafIndex  int           `json:"leaf_index"`
+	Leaf       string        `json:"leaf"`
+	Proof      []common.Hash `json:"proof"`
+	MerkleRoot string        `json:"merkle_root"`
+}
+
+// NonceRequest represents the request for a claim nonce
+type NonceRequest struct {
+	Address string `json:"address" binding:"required"`
+}
+
+// NonceResponse is a freshly issued claim nonce and the typed data to sign
+// with it
+type NonceResponse struct {
+	Nonce     string    `json:"nonce"`
+	Deadline  int64     `json:"deadline"`
+	TypedData TypedData `json:"typed_data"`
+}
+
+// TypedData is an EIP-712 message as passed to eth_signTypedData_v4
+type TypedData struct {
+	Types       map[string][]TypedDataField `json:"types"`
+	PrimaryType string                      `json:"primaryType"`
+	Domain      map[string]interface{}      `json:"domain"`
+	Message     map[string]interface{}      `json:"message"`
+}
+
+// TypedDataField is one field of an EIP-712 type
+type TypedDataField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// ClaimRequest represents the request to claim airdrop: an EIP-712 Claim
+// signature over a nonce issued by the nonce endpoint and its deadline
 type ClaimRequest struct {
 	Address   string `json:"address" binding:"required"`
 	Nonce     string `json:"nonce" binding:"required"`
+	Deadline  int64  `json:"deadline" binding:"required"`
 	Signature string `json:"signature" binding:"required"`
 }
 
@@ -90,3 +90,23 @@ func CleanupExpiredAllocations(ctx context.Context, db *sql.DB) error {
 
 	return nil
 }
+
+// CleanupExpiredNonces deletes claim nonces that expired more than a day ago.
+// Claims keep the nonce they used, so nothing can be replayed with them.
+func CleanupExpiredNonces(ctx context.Context, db *sql.DB) error {
+	result, err := db.ExecContext(ctx, `
+		DELETE FROM airdrop_claim_nonces
+		WHERE expires_at < $1
+	`, time.Now().Add(-24*time.Hour))
+
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected > 0 {
+		log.Printf("🧹 Cleaned up %d expired claim nonces", rowsAffected)
+	}
+
+	return nil
+}
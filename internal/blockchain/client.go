@@ -45,7 +45,18 @@ func NewClient() (*Client, error) {
 		return nil, fmt.Errorf("failed to connect to L2: %w", err)
 	}
 
-	// Load private key if provided
+	client, err := NewClientWithKey(l1Client, l2Client, privateKeyHex)
+	if err != nil {
+		l1Client.Close()
+		l2Client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// NewClientWithKey wraps already connected L1 and L2 clients; privateKeyHex
+// may be empty for a read-only client
+func NewClientWithKey(l1Client, l2Client *ethclient.Client, privateKeyHex string) (*Client, error) {
 	var privateKey *ecdsa.PrivateKey
 	var fromAddress common.Address
 
@@ -55,6 +66,7 @@ func NewClient() (*Client, error) {
 			privateKeyHex = privateKeyHex[2:]
 		}
 
+		var err error
 		privateKey, err = crypto.HexToECDSA(privateKeyHex)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse private key: %w", err)
@@ -133,6 +145,23 @@ func (c *Client) GetL2TransactOpts(ctx context.Context) (*bind.TransactOpts, err
 	return auth, nil
 }
 
+// IsContract reports whether code is deployed at address on L1 or L2
+func (c *Client) IsContract(ctx context.Context, address common.Address) (bool, error) {
+	for _, client := range []*ethclient.Client{c.L1Client, c.L2Client} {
+		if client == nil {
+			continue
+		}
+		code, err := client.CodeAt(ctx, address, nil)
+		if err != nil {
+			return false, fmt.Errorf("failed to get code: %w", err)
+		}
+		if len(code) > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // WaitForTransaction waits for transaction to be mined on L1
 func (c *Client) WaitForL1Transaction(ctx context.Context, tx *types.Transaction) (*types.Receipt, error) {
 	receipt, err := bind.WaitMined(ctx, c.L1Client, tx)
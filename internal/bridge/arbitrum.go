@@ -0,0 +1,510 @@
+package bridge
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Arbitrum protocol steps, stored in bridge_messages.arbitrum_status
+const (
+	RetryableCreated        = "retryable_created"         // ticket exists on L2, not redeemed yet
+	RetryableRedeemed       = "retryable_redeemed"        // a redeem succeeded
+	RetryableExpired        = "retryable_expired"         // ticket timed out without a successful redeem
+	RetryableCreationFailed = "retryable_creation_failed" // the submission reverted on L2
+	OutboxChallengePeriod   = "challenge_period"          // sent on L2, assertion not confirmed yet
+	OutboxExecutable        = "executable"                // assertion confirmed; can be executed on L1
+	OutboxExecuted          = "executed"                  // executed on L1
+)
+
+// ErrNoArbitrumMessage is returned for transactions that did not go through
+// the Arbitrum Inbox or ArbSys, e.g. messages relayed by other means
+var ErrNoArbitrumMessage = errors.New("no arbitrum message in transaction")
+
+// l1MessageTypeSubmitRetryable is the delayed inbox message kind of createRetryableTicket
+const l1MessageTypeSubmitRetryable = 9
+
+// sendRootLookback is how far back the outbox is scanned for confirmed send
+// roots on the first check; assertions are confirmed several times a day
+const sendRootLookback = 50_000
+
+// logChunk is the max block range per eth_getLogs call
+const logChunk = 10_000
+
+// redeemScanChunks caps the L2 chunks one ResolveRetryable call scans for
+// manual redeems; a longer backlog is picked up on the next check
+const redeemScanChunks = 20
+
+var (
+	// Arbitrum precompiles
+	arbSys         = common.HexToAddress("0x0000000000000000000000000000000000000064")
+	arbRetryableTx = common.HexToAddress("0x000000000000000000000000000000000000006E")
+
+	// Bridge.MessageDelivered and Inbox.InboxMessageDelivered
+	messageDeliveredTopic      = crypto.Keccak256Hash([]byte("MessageDelivered(uint256,bytes32,address,uint8,address,bytes32,uint256,uint64)"))
+	inboxMessageDeliveredTopic = crypto.Keccak256Hash([]byte("InboxMessageDelivered(uint256,bytes)"))
+
+	// ArbRetryableTx.RedeemScheduled, ArbSys.L2ToL1Tx and Outbox.SendRootUpdated
+	redeemScheduledTopic = crypto.Keccak256Hash([]byte("RedeemScheduled(bytes32,bytes32,uint64,uint64,address,uint256,uint256)"))
+	l2ToL1TxTopic        = crypto.Keccak256Hash([]byte("L2ToL1Tx(address,address,uint256,uint256,uint256,uint256,uint256,uint256,bytes)"))
+	sendRootUpdatedTopic = crypto.Keccak256Hash([]byte("SendRootUpdated(bytes32,bytes32)"))
+
+	// NodeInterface.constructOutboxProof, served by Arbitrum nodes at a virtual address
+	nodeInterface    = common.HexToAddress("0x00000000000000000000000000000000000000C8")
+	nodeInterfaceABI = mustABI(`[{"type":"function","name":"constructOutboxProof","stateMutability":"view",
+		"inputs":[{"name":"size","type":"uint64"},{"name":"leaf","type":"uint64"}],
+		"outputs":[{"name":"send","type":"bytes32"},{"name":"root","type":"bytes32"},{"name":"proof","type":"bytes32[]"}]}]`)
+
+	getTimeoutSelector = crypto.Keccak256([]byte("getTimeout(bytes32)"))[:4]
+	isSpentSelector    = crypto.Keccak256([]byte("isSpent(uint256)"))[:4]
+)
+
+// ChainReader is the subset of ethclient.Client (and of the simulated
+// backend's client) used to resolve messages
+type ChainReader interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+	HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error)
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+}
+
+// Resolution is the on-chain state of one bridge message
+type Resolution struct {
+	ArbitrumStatus string
+	TicketID       string // L1→L2 retryable ticket
+	OutboxPosition string // L2→L1 position in the outbox (decimal)
+	L2TxHash       string // L1→L2: the successful redeem
+}
+
+// ArbitrumTracker resolves bridge messages against an Arbitrum rollup and its
+// parent chain
+type ArbitrumTracker struct {
+	l1, l2    ChainReader
+	l2ChainID *big.Int
+	inbox     common.Address
+	outbox    common.Address
+
+	// Highest L2 block covered by a confirmed send root, the number of L2→L1
+	// messages that root commits to, and the L1 block scanned up to
+	mu                sync.Mutex
+	confirmedL2       uint64
+	confirmedSends    uint64
+	scannedSendRootTo uint64
+
+	// Last L2 block scanned for manual redeems, per unresolved ticket
+	redeemScannedTo map[common.Hash]uint64
+}
+
+// NewArbitrumTracker creates a tracker for the rollup whose Inbox and Outbox
+// live at the given L1 addresses
+func NewArbitrumTracker(l1, l2 ChainReader, l2ChainID int64, inbox, outbox string) *ArbitrumTracker {
+	return &ArbitrumTracker{
+		l1:        l1,
+		l2:        l2,
+		l2ChainID: big.NewInt(l2ChainID),
+		inbox:     common.HexToAddress(inbox),
+		outbox:    common.HexToAddress(outbox),
+
+		redeemScannedTo: make(map[common.Hash]uint64),
+	}
+}
+
+// ResolveRetryable finds the retryable ticket created by an L1 transaction and
+// reports whether it was redeemed, is still redeemable or expired
+func (t *ArbitrumTracker) ResolveRetryable(ctx context.Context, l1TxHash string) (*Resolution, error) {
+	receipt, err := t.l1.TransactionReceipt(ctx, common.HexToHash(l1TxHash))
+	if err != nil {
+		return nil, fmt.Errorf("L1 receipt: %w", err)
+	}
+	ticket, err := t.retryableTicketID(receipt)
+	if err != nil {
+		return nil, err
+	}
+	res := &Resolution{TicketID: ticket.Hex()}
+
+	creation, err := t.l2.TransactionReceipt(ctx, ticket)
+	if errors.Is(err, ethereum.NotFound) {
+		return res, nil // not yet included on L2
+	}
+	if err != nil {
+		return nil, fmt.Errorf("L2 ticket receipt: %w", err)
+	}
+	if creation.Status != types.ReceiptStatusSuccessful {
+		res.ArbitrumStatus = RetryableCreationFailed
+		return res, nil
+	}
+
+	// The auto-redeem is scheduled in the creation receipt; manual redeems come later
+	redeem, err := t.successfulRedeem(ctx, ticket, creation.Logs)
+	if err != nil {
+		return nil, err
+	}
+	if redeem == nil {
+		// Read the ticket and the logs at the same head so a redeem in
+		// between is not mistaken for an expiry
+		head, err := t.l2.BlockNumber(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("L2 head: %w", err)
+		}
+		alive, err := t.retryableAlive(ctx, ticket, head)
+		if err != nil {
+			return nil, err
+		}
+		var scanned bool
+		redeem, scanned, err = t.scanRedeems(ctx, ticket, creation.BlockNumber.Uint64()+1, head)
+		if err != nil {
+			return nil, err
+		}
+		if redeem == nil {
+			switch {
+			case alive:
+				res.ArbitrumStatus = RetryableCreated
+			case scanned:
+				t.forgetRedeemScan(ticket)
+				res.ArbitrumStatus = RetryableExpired
+			}
+			return res, nil // gone but not scanned up to head yet: undecided
+		}
+	}
+
+	t.forgetRedeemScan(ticket)
+	res.ArbitrumStatus = RetryableRedeemed
+	res.L2TxHash = redeem.TxHash.Hex()
+	return res, nil
+}
+
+// retryableAlive reports whether the ticket still exists at an L2 block;
+// ArbRetryableTx.getTimeout reverts once it was redeemed or expired
+func (t *ArbitrumTracker) retryableAlive(ctx context.Context, ticket common.Hash, block uint64) (bool, error) {
+	data := append(append([]byte{}, getTimeoutSelector...), ticket.Bytes()...)
+	_, err := t.l2.CallContract(ctx, ethereum.CallMsg{To: &arbRetryableTx, Data: data}, new(big.Int).SetUint64(block))
+	if err != nil {
+		if !isRevert(err) {
+			return false, fmt.Errorf("getTimeout: %w", err)
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+// scanRedeems looks for a successful manual redeem of ticket between from
+// and head, continuing where the previous check of the ticket stopped and
+// scanning at most redeemScanChunks chunks. Reports whether it got to head.
+func (t *ArbitrumTracker) scanRedeems(ctx context.Context, ticket common.Hash, from, head uint64) (*types.Receipt, bool, error) {
+	t.mu.Lock()
+	if to, ok := t.redeemScannedTo[ticket]; ok && to >= from {
+		from = to + 1
+	}
+	t.mu.Unlock()
+
+	for chunks := 0; from <= head; chunks++ {
+		if chunks == redeemScanChunks {
+			return nil, false, nil
+		}
+		to := min(from+logChunk-1, head)
+		later, err := t.l2.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(from),
+			ToBlock:   new(big.Int).SetUint64(to),
+			Addresses: []common.Address{arbRetryableTx},
+			Topics:    [][]common.Hash{{redeemScheduledTopic}, {ticket}},
+		})
+		if err != nil {
+			return nil, false, fmt.Errorf("redeem logs: %w", err)
+		}
+		logs := make([]*types.Log, len(later))
+		for i := range later {
+			logs[i] = &later[i]
+		}
+		redeem, err := t.successfulRedeem(ctx, ticket, logs)
+		if err != nil || redeem != nil {
+			return redeem, err == nil, err
+		}
+
+		t.mu.Lock()
+		t.redeemScannedTo[ticket] = to
+		t.mu.Unlock()
+		from = to + 1
+	}
+	return nil, true, nil
+}
+
+// forgetRedeemScan drops the scan position of a resolved ticket
+func (t *ArbitrumTracker) forgetRedeemScan(ticket common.Hash) {
+	t.mu.Lock()
+	delete(t.redeemScannedTo, ticket)
+	t.mu.Unlock()
+}
+
+// successfulRedeem returns the receipt of the first successful redeem scheduled
+// for ticket in logs, or nil when every attempt failed
+func (t *ArbitrumTracker) successfulRedeem(ctx context.Context, ticket common.Hash, logs []*types.Log) (*types.Receipt, error) {
+	for _, lg := range logs {
+		if lg.Address != arbRetryableTx || len(lg.Topics) < 3 || lg.Topics[0] != redeemScheduledTopic || lg.Topics[1] != ticket {
+			continue
+		}
+		retry, err := t.l2.TransactionReceipt(ctx, lg.Topics[2])
+		if errors.Is(err, ethereum.NotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("redeem receipt: %w", err)
+		}
+		if retry.Status == types.ReceiptStatusSuccessful {
+			return retry, nil
+		}
+	}
+	return nil, nil
+}
+
+// retryableTicketID computes the L2 hash of the retryable submitted in an L1
+// receipt, from the Bridge's MessageDelivered and the Inbox's
+// InboxMessageDelivered logs. The sender in MessageDelivered is already aliased.
+func (t *ArbitrumTracker) retryableTicketID(receipt *types.Receipt) (common.Hash, error) {
+	for _, delivered := range receipt.Logs {
+		if len(delivered.Topics) < 2 || delivered.Topics[0] != messageDeliveredTopic || len(delivered.Data) < 192 {
+			continue
+		}
+		// Non-indexed: inbox, kind, sender, messageDataHash, baseFeeL1, timestamp
+		inbox := common.BytesToAddress(delivered.Data[0:32])
+		kind := new(big.Int).SetBytes(delivered.Data[32:64])
+		sender := common.BytesToAddress(delivered.Data[64:96])
+		baseFee := new(big.Int).SetBytes(delivered.Data[128:160])
+		if inbox != t.inbox || kind.Int64() != l1MessageTypeSubmitRetryable {
+			continue
+		}
+
+		messageNum := delivered.Topics[1]
+		for _, lg := range receipt.Logs {
+			if lg.Address != t.inbox || len(lg.Topics) < 2 || lg.Topics[0] != inboxMessageDeliveredTopic || lg.Topics[1] != messageNum {
+				continue
+			}
+			payload, err := unpackBytes(lg.Data)
+			if err != nil {
+				return common.Hash{}, fmt.Errorf("inbox message %s: %w", messageNum.Big(), err)
+			}
+			return submitRetryableID(t.l2ChainID, messageNum, sender, baseFee, payload)
+		}
+	}
+	return common.Hash{}, ErrNoArbitrumMessage
+}
+
+// submitRetryableID hashes an ArbitrumSubmitRetryableTx (type 0x69) the way the
+// L2 node does. payload is abi.encodePacked(to, l2CallValue, deposit,
+// maxSubmissionCost, excessFeeRefundAddress, callValueRefundAddress, gasLimit,
+// maxFeePerGas, data.length, data), as written by the Inbox.
+func submitRetryableID(chainID *big.Int, messageNum common.Hash, from common.Address, l1BaseFee *big.Int, payload []byte) (common.Hash, error) {
+	if len(payload) < 9*32 {
+		return common.Hash{}, fmt.Errorf("retryable payload too short (%d bytes)", len(payload))
+	}
+	word := func(i int) []byte { return payload[i*32 : (i+1)*32] }
+	num := func(i int) *big.Int { return new(big.Int).SetBytes(word(i)) }
+
+	dataLen := num(8).Uint64()
+	if uint64(len(payload)-9*32) < dataLen {
+		return common.Hash{}, fmt.Errorf("retryable data truncated")
+	}
+
+	var to []byte // contract creation when zero
+	if dest := common.BytesToAddress(word(0)); dest != (common.Address{}) {
+		to = dest.Bytes()
+	}
+
+	fields := []interface{}{
+		chainID,
+		messageNum.Bytes(),
+		from,
+		l1BaseFee,
+		num(2), // deposit
+		num(7), // maxFeePerGas
+		num(6), // gasLimit
+		to,
+		num(1),                         // l2CallValue
+		common.BytesToAddress(word(5)), // callValueRefundAddress
+		num(3),                         // maxSubmissionCost
+		common.BytesToAddress(word(4)), // excessFeeRefundAddress
+		payload[9*32 : 9*32+dataLen],
+	}
+	enc, err := rlp.EncodeToBytes(fields)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash([]byte{0x69}, enc), nil
+}
+
+// ResolveOutbox finds the L2→L1 message sent by an L2 transaction and reports
+// whether it is still in the challenge period, executable or executed
+func (t *ArbitrumTracker) ResolveOutbox(ctx context.Context, l2TxHash string) (*Resolution, error) {
+	receipt, err := t.l2.TransactionReceipt(ctx, common.HexToHash(l2TxHash))
+	if err != nil {
+		return nil, fmt.Errorf("L2 receipt: %w", err)
+	}
+
+	var position *big.Int
+	for _, lg := range receipt.Logs {
+		if lg.Address == arbSys && len(lg.Topics) == 4 && lg.Topics[0] == l2ToL1TxTopic {
+			position = lg.Topics[3].Big()
+			break
+		}
+	}
+	if position == nil {
+		return nil, ErrNoArbitrumMessage
+	}
+	res := &Resolution{OutboxPosition: position.String()}
+
+	spent, err := t.isSpent(ctx, position)
+	if err != nil {
+		return nil, err
+	}
+	if spent {
+		res.ArbitrumStatus = OutboxExecuted
+		return res, nil
+	}
+
+	confirmed, err := t.confirmedL2Block(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if receipt.BlockNumber.Uint64() <= confirmed {
+		res.ArbitrumStatus = OutboxExecutable
+	} else {
+		res.ArbitrumStatus = OutboxChallengePeriod
+	}
+	return res, nil
+}
+
+// isSpent reports whether the outbox already executed the message at position
+func (t *ArbitrumTracker) isSpent(ctx context.Context, position *big.Int) (bool, error) {
+	data := append(append([]byte{}, isSpentSelector...), common.BigToHash(position).Bytes()...)
+	out, err := t.l1.CallContract(ctx, ethereum.CallMsg{To: &t.outbox, Data: data}, nil)
+	if err != nil {
+		return false, fmt.Errorf("isSpent: %w", err)
+	}
+	return new(big.Int).SetBytes(out).Sign() != 0, nil
+}
+
+// confirmedL2Block returns the highest L2 block covered by a send root the
+// outbox accepted, i.e. by a confirmed assertion. Outbox logs are scanned
+// incrementally from where the previous call stopped.
+func (t *ArbitrumTracker) confirmedL2Block(ctx context.Context) (uint64, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	head, err := t.l1.BlockNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+	from := t.scannedSendRootTo + 1
+	if t.scannedSendRootTo == 0 {
+		from = head - min(head, sendRootLookback)
+	}
+
+	for from <= head {
+		to := min(from+logChunk-1, head)
+		logs, err := t.l1.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(from),
+			ToBlock:   new(big.Int).SetUint64(to),
+			Addresses: []common.Address{t.outbox},
+			Topics:    [][]common.Hash{{sendRootUpdatedTopic}},
+		})
+		if err != nil {
+			return t.confirmedL2, fmt.Errorf("send root logs: %w", err)
+		}
+		// Only the newest root of a range matters
+		for i := len(logs) - 1; i >= 0; i-- {
+			if len(logs[i].Topics) < 3 {
+				continue
+			}
+			h, err := t.l2.HeaderByHash(ctx, logs[i].Topics[2])
+			if err != nil {
+				return t.confirmedL2, fmt.Errorf("send root block: %w", err)
+			}
+			if n := h.Number.Uint64(); n > t.confirmedL2 {
+				// Arbitrum headers carry the send count in the first 8 bytes of mixHash
+				t.confirmedL2, t.confirmedSends = n, binary.BigEndian.Uint64(h.MixDigest[:8])
+			}
+			break
+		}
+		t.scannedSendRootTo = to
+		from = to + 1
+	}
+	return t.confirmedL2, nil
+}
+
+// OutboxProof builds the Merkle proof of the L2→L1 message at position
+// against the newest confirmed send root. It fails while the message is not
+// covered by a confirmed root yet.
+func (t *ArbitrumTracker) OutboxProof(ctx context.Context, position uint64) ([]common.Hash, error) {
+	if _, err := t.confirmedL2Block(ctx); err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	size := t.confirmedSends
+	t.mu.Unlock()
+	if position >= size {
+		return nil, fmt.Errorf("outbox position %d not covered by the confirmed send root (%d sends)", position, size)
+	}
+
+	data, err := nodeInterfaceABI.Pack("constructOutboxProof", size, position)
+	if err != nil {
+		return nil, err
+	}
+	out, err := t.l2.CallContract(ctx, ethereum.CallMsg{To: &nodeInterface, Data: data}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("constructOutboxProof: %w", err)
+	}
+	values, err := nodeInterfaceABI.Unpack("constructOutboxProof", out)
+	if err != nil {
+		return nil, fmt.Errorf("constructOutboxProof: %w", err)
+	}
+	proof, ok := values[2].([][32]byte)
+	if !ok {
+		return nil, fmt.Errorf("constructOutboxProof: unexpected proof type %T", values[2])
+	}
+	hashes := make([]common.Hash, len(proof))
+	for i, p := range proof {
+		hashes[i] = p
+	}
+	return hashes, nil
+}
+
+// unpackBytes decodes an ABI-encoded dynamic bytes value (offset, length, data)
+func unpackBytes(data []byte) ([]byte, error) {
+	if len(data) < 64 {
+		return nil, fmt.Errorf("short bytes encoding")
+	}
+	offset := new(big.Int).SetBytes(data[:32]).Uint64()
+	if offset+32 > uint64(len(data)) {
+		return nil, fmt.Errorf("bad bytes offset %d", offset)
+	}
+	length := new(big.Int).SetBytes(data[offset : offset+32]).Uint64()
+	if offset+32+length > uint64(len(data)) {
+		return nil, fmt.Errorf("bytes length %d out of range", length)
+	}
+	return data[offset+32 : offset+32+length], nil
+}
+
+// mustABI parses a JSON ABI fragment declared in this package
+func mustABI(definition string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(definition))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// isRevert reports whether a call failed in the EVM rather than in transport
+func isRevert(err error) bool {
+	return strings.Contains(err.Error(), "revert")
+}
@@ -0,0 +1,484 @@
+package bridge
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	k "github.com/segmentio/kafka-go"
+
+	"loyalty-points-system/internal/blockchain"
+	"loyalty-points-system/internal/blockchain/l1"
+	"loyalty-points-system/internal/db"
+	"loyalty-points-system/internal/events"
+	"loyalty-points-system/internal/listener"
+	"loyalty-points-system/internal/metrics"
+	"loyalty-points-system/internal/models"
+)
+
+// executorName names the executor's L2 scan checkpoint
+const executorName = "bridge-executor"
+
+// outboxABI is Outbox.executeTransaction
+var outboxABI = mustABI(`[{"type":"function","name":"executeTransaction","stateMutability":"nonpayable",
+	"inputs":[{"name":"proof","type":"bytes32[]"},{"name":"index","type":"uint256"},{"name":"l2Sender","type":"address"},
+	{"name":"to","type":"address"},{"name":"l2Block","type":"uint256"},{"name":"l1Block","type":"uint256"},
+	{"name":"l2Timestamp","type":"uint256"},{"name":"value","type":"uint256"},{"name":"data","type":"bytes"}],
+	"outputs":[]}]`)
+
+// arbSysABI is ArbSys.L2ToL1Tx, whose unindexed fields carry what the outbox
+// needs to execute the message
+var arbSysABI = mustABI(`[{"type":"event","name":"L2ToL1Tx","anonymous":false,"inputs":[
+	{"name":"caller","type":"address","indexed":false},{"name":"destination","type":"address","indexed":true},
+	{"name":"hash","type":"uint256","indexed":true},{"name":"position","type":"uint256","indexed":true},
+	{"name":"arbBlockNum","type":"uint256","indexed":false},{"name":"ethBlockNum","type":"uint256","indexed":false},
+	{"name":"timestamp","type":"uint256","indexed":false},{"name":"callvalue","type":"uint256","indexed":false},
+	{"name":"data","type":"bytes","indexed":false}]}]`)
+
+// ExecutorConfig configures the withdrawal executor
+type ExecutorConfig struct {
+	L1ChainID      int64
+	L2ChainID      int64
+	L1Gateway      string // destination of the withdrawal messages
+	L2Gateway      string // their sender on L2; empty accepts any sender
+	StartBlock     uint64 // first L2 block to scan without a checkpoint (0 = head)
+	Interval       time.Duration
+	ScanBatch      uint64 // max L2 blocks per eth_getLogs call
+	MaxAttempts    int
+	ReceiptTimeout time.Duration
+	Codec          events.Codec // BridgeEvent encoding (nil = JSON)
+}
+
+// Executor finalizes L2→L1 withdrawals. It picks up the messages the L2
+// gateway sends to L1Gateway.finalizeWithdrawal through ArbSys, waits for the
+// assertion holding them to be confirmed, and executes them in the outbox with
+// a proof, which makes the outbox call finalizeWithdrawal; the gateway only
+// accepts the call from the outbox. Every step is published as a BridgeEvent.
+type Executor struct {
+	database    *sql.DB
+	client      *blockchain.Client
+	tracker     *ArbitrumTracker
+	writer      listener.Writer
+	checkpoints *db.CheckpointStore
+	config      ExecutorConfig
+	l1Gateway   common.Address
+	l2Gateway   common.Address
+	outbox      *bind.BoundContract
+	mu          sync.RWMutex
+	running     bool
+}
+
+// NewExecutor creates an executor sending from the client's L1 operator key;
+// BridgeEvents are written to writer
+func NewExecutor(database *sql.DB, client *blockchain.Client, tracker *ArbitrumTracker, writer listener.Writer, config ExecutorConfig) *Executor {
+	if config.Interval <= 0 {
+		config.Interval = time.Minute
+	}
+	if config.ScanBatch == 0 {
+		config.ScanBatch = listener.DefaultBackfillBatch
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 5
+	}
+	if config.ReceiptTimeout <= 0 {
+		config.ReceiptTimeout = 5 * time.Minute
+	}
+	return &Executor{
+		database:    database,
+		client:      client,
+		tracker:     tracker,
+		writer:      writer,
+		checkpoints: db.NewCheckpointStore(database),
+		config:      config,
+		l1Gateway:   common.HexToAddress(config.L1Gateway),
+		l2Gateway:   common.HexToAddress(config.L2Gateway),
+		outbox:      bind.NewBoundContract(tracker.outbox, outboxABI, client.L1Client, client.L1Client, client.L1Client),
+	}
+}
+
+// Start scans for and finalizes withdrawals until ctx is cancelled
+func (e *Executor) Start(ctx context.Context) error {
+	e.mu.Lock()
+	if e.running {
+		e.mu.Unlock()
+		return nil
+	}
+	e.running = true
+	e.mu.Unlock()
+
+	log.Printf("🏦 [Bridge Executor] Started (L1 gateway %s, operator %s)", e.l1Gateway.Hex(), e.client.FromAddress.Hex())
+
+	ticker := time.NewTicker(e.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("🛑 [Bridge Executor] Stopped")
+			return nil
+		case <-ticker.C:
+			if err := e.scan(ctx); err != nil {
+				log.Printf("❌ [Bridge Executor] Error scanning withdrawals: %v", err)
+			}
+			if err := e.finalizeReady(ctx); err != nil {
+				log.Printf("❌ [Bridge Executor] Error finalizing withdrawals: %v", err)
+			}
+		}
+	}
+}
+
+// Stop halts the executor
+func (e *Executor) Stop() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.running = false
+}
+
+// scan records the withdrawals sent on L2 since the last checkpoint
+func (e *Executor) scan(ctx context.Context) error {
+	head, err := e.client.L2Client.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+
+	from := e.config.StartBlock
+	last, found, err := e.checkpoints.LoadCheckpoint(ctx, executorName, e.config.L2ChainID, arbSys.Hex())
+	if err != nil {
+		return fmt.Errorf("load checkpoint: %w", err)
+	}
+	if found {
+		from = last + 1
+	} else if from == 0 {
+		from = head
+	}
+
+	for from <= head {
+		to := min(from+e.config.ScanBatch-1, head)
+		logs, err := e.client.L2Client.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(from),
+			ToBlock:   new(big.Int).SetUint64(to),
+			Addresses: []common.Address{arbSys},
+			Topics:    [][]common.Hash{{l2ToL1TxTopic}, {common.BytesToHash(e.l1Gateway.Bytes())}},
+		})
+		if err != nil {
+			return fmt.Errorf("L2ToL1Tx logs: %w", err)
+		}
+		for _, lg := range logs {
+			if err := e.record(ctx, lg); err != nil {
+				return err
+			}
+		}
+		if err := e.checkpoints.SaveCheckpoint(ctx, executorName, e.config.L2ChainID, arbSys.Hex(), to); err != nil {
+			return fmt.Errorf("save checkpoint: %w", err)
+		}
+		from = to + 1
+	}
+	return nil
+}
+
+// record stores one L2ToL1Tx to the L1 gateway and publishes it as a pending
+// withdrawal. Messages that are not finalizeWithdrawal calls are skipped.
+func (e *Executor) record(ctx context.Context, lg types.Log) error {
+	w, err := e.parseWithdrawal(lg)
+	if err != nil {
+		log.Printf("⚠️  [Bridge Executor] Skipping L2→L1 message in %s: %v", lg.TxHash.Hex(), err)
+		return nil
+	}
+
+	inserted, err := db.InsertBridgeWithdrawal(e.database, w)
+	if err != nil {
+		return fmt.Errorf("record withdrawal %s: %w", w.WithdrawalID, err)
+	}
+	if !inserted {
+		return nil
+	}
+
+	log.Printf("🚀 [Bridge Executor] Withdrawal %s user=%s amt=%s at outbox position %d",
+		w.WithdrawalID, w.UserAddress, w.Amount, w.OutboxPosition)
+	e.publish(ctx, w, "pending", "", 0)
+	return nil
+}
+
+// parseWithdrawal decodes an ArbSys L2ToL1Tx log and the finalizeWithdrawal
+// call it carries
+func (e *Executor) parseWithdrawal(lg types.Log) (*db.BridgeWithdrawal, error) {
+	if len(lg.Topics) != 4 {
+		return nil, fmt.Errorf("malformed L2ToL1Tx log")
+	}
+	fields, err := arbSysABI.Unpack("L2ToL1Tx", lg.Data)
+	if err != nil {
+		return nil, fmt.Errorf("unpack L2ToL1Tx: %w", err)
+	}
+	caller := fields[0].(common.Address)
+	arbBlockNum, ethBlockNum, timestamp, callValue := fields[1].(*big.Int), fields[2].(*big.Int), fields[3].(*big.Int), fields[4].(*big.Int)
+	calldata := fields[5].([]byte)
+
+	if e.l2Gateway != (common.Address{}) && caller != e.l2Gateway {
+		return nil, fmt.Errorf("sent by %s, not the L2 gateway", caller.Hex())
+	}
+
+	gatewayABI, err := l1.L1GatewayMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	method, err := gatewayABI.MethodById(calldata)
+	if err != nil || method.Name != "finalizeWithdrawal" {
+		return nil, fmt.Errorf("not a finalizeWithdrawal call")
+	}
+	args, err := method.Inputs.Unpack(calldata[4:])
+	if err != nil {
+		return nil, fmt.Errorf("unpack finalizeWithdrawal: %w", err)
+	}
+	withdrawalID := args[0].([32]byte)
+	user := args[1].(common.Address)
+	amount := args[2].(*big.Int)
+
+	return &db.BridgeWithdrawal{
+		WithdrawalID:   common.Hash(withdrawalID).Hex(),
+		UserAddress:    user.Hex(),
+		Amount:         amount.String(),
+		OutboxPosition: lg.Topics[3].Big().Uint64(),
+		L2Sender:       caller.Hex(),
+		Destination:    e.l1Gateway.Hex(),
+		L2BlockNumber:  arbBlockNum.Int64(),
+		L1BlockNumber:  ethBlockNum.Int64(),
+		L2Timestamp:    timestamp.Int64(),
+		CallValue:      callValue.String(),
+		Calldata:       calldata,
+		L2TxHash:       lg.TxHash.Hex(),
+		CreatedAt:      time.Now().Unix(),
+	}, nil
+}
+
+// finalizeReady executes the open withdrawals covered by a confirmed assertion
+func (e *Executor) finalizeReady(ctx context.Context) error {
+	confirmed, err := e.tracker.confirmedL2Block(ctx)
+	if err != nil {
+		return err
+	}
+	withdrawals, err := db.GetOpenBridgeWithdrawals(e.database, confirmed, e.config.MaxAttempts)
+	if err != nil {
+		return err
+	}
+
+	for _, w := range withdrawals {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err := e.finalize(ctx, w); err != nil {
+			log.Printf("❌ [Bridge Executor] Withdrawal %s (attempt %d/%d): %v",
+				w.WithdrawalID, w.Attempts+1, e.config.MaxAttempts, err)
+			metrics.BridgeWithdrawalsTotal.WithLabelValues("error").Inc()
+			if dbErr := db.RecordBridgeWithdrawalFailure(e.database, w.WithdrawalID, err.Error(), e.config.MaxAttempts); dbErr != nil {
+				log.Printf("⚠️  [Bridge Executor] Failed to record failure of %s: %v", w.WithdrawalID, dbErr)
+			}
+		}
+	}
+
+	// A failed withdrawal may still have been executed, by a submission of
+	// ours that landed late or by anyone else
+	failed, err := db.GetFailedBridgeWithdrawals(e.database, 50)
+	if err != nil {
+		return err
+	}
+	for _, w := range failed {
+		if ctx.Err() != nil {
+			return nil
+		}
+		receipt, _, err := e.submission(ctx, w)
+		if err == nil && receipt != nil && receipt.Status == types.ReceiptStatusSuccessful {
+			err = e.finish(ctx, w, receipt.BlockNumber.Int64())
+		} else if err == nil {
+			var spent bool
+			if spent, err = e.tracker.isSpent(ctx, new(big.Int).SetUint64(w.OutboxPosition)); err == nil && spent {
+				err = e.executed(ctx, w, w.L1TxHash)
+			}
+		}
+		if err != nil {
+			log.Printf("⚠️  [Bridge Executor] Failed to recheck failed withdrawal %s: %v", w.WithdrawalID, err)
+		}
+	}
+	return nil
+}
+
+// finalize executes one withdrawal in the outbox and waits for it. A
+// withdrawal the outbox already executed, by us before a restart or by anyone
+// else, is only marked executed; a submission still in flight is waited for
+// instead of being sent again.
+func (e *Executor) finalize(ctx context.Context, w *db.BridgeWithdrawal) error {
+	// Settle an earlier submission before sending another one
+	receipt, inFlight, err := e.submission(ctx, w)
+	if err != nil {
+		return err
+	}
+	if receipt != nil && receipt.Status == types.ReceiptStatusSuccessful {
+		return e.finish(ctx, w, receipt.BlockNumber.Int64())
+	}
+	if inFlight {
+		log.Printf("⏳ [Bridge Executor] Withdrawal %s still waiting for %s", w.WithdrawalID, w.L1TxHash)
+		return nil
+	}
+
+	position := new(big.Int).SetUint64(w.OutboxPosition)
+	spent, err := e.tracker.isSpent(ctx, position)
+	if err != nil {
+		return err
+	}
+	if spent {
+		return e.executed(ctx, w, w.L1TxHash)
+	}
+
+	proof, err := e.tracker.OutboxProof(ctx, w.OutboxPosition)
+	if err != nil {
+		return err
+	}
+	opts, err := e.client.GetL1TransactOpts(ctx)
+	if err != nil {
+		return err
+	}
+	callValue, _ := new(big.Int).SetString(w.CallValue, 10)
+	proofBytes := make([][32]byte, len(proof))
+	for i, h := range proof {
+		proofBytes[i] = h
+	}
+
+	tx, err := e.outbox.Transact(opts, "executeTransaction",
+		proofBytes,
+		position,
+		common.HexToAddress(w.L2Sender),
+		common.HexToAddress(w.Destination),
+		big.NewInt(w.L2BlockNumber),
+		big.NewInt(w.L1BlockNumber),
+		big.NewInt(w.L2Timestamp),
+		callValue,
+		w.Calldata,
+	)
+	if err != nil {
+		return fmt.Errorf("executeTransaction: %w", err)
+	}
+	if err := db.MarkBridgeWithdrawalSubmitted(e.database, w.WithdrawalID, tx.Hash().Hex()); err != nil {
+		log.Printf("⚠️  [Bridge Executor] Failed to record submission %s of %s: %v", tx.Hash().Hex(), w.WithdrawalID, err)
+	}
+	log.Printf("🏦 [Bridge Executor] Finalizing withdrawal %s in %s", w.WithdrawalID, tx.Hash().Hex())
+
+	waitCtx, cancel := context.WithTimeout(ctx, e.config.ReceiptTimeout)
+	defer cancel()
+	receipt, err = e.client.WaitForL1Transaction(waitCtx, tx)
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		// Picked up again from its stored hash on the next round
+		log.Printf("⏳ [Bridge Executor] Withdrawal %s not mined within %s", w.WithdrawalID, e.config.ReceiptTimeout)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	w.L1TxHash = tx.Hash().Hex()
+	return e.finish(ctx, w, receipt.BlockNumber.Int64())
+}
+
+// submission looks up our stored executeTransaction of a withdrawal. It
+// returns the receipt once mined, and reports a transaction the node still
+// knows without a receipt as in flight. Neither means it was never sent or
+// was dropped.
+func (e *Executor) submission(ctx context.Context, w *db.BridgeWithdrawal) (*types.Receipt, bool, error) {
+	if w.L1TxHash == "" {
+		return nil, false, nil
+	}
+	hash := common.HexToHash(w.L1TxHash)
+	receipt, err := e.client.L1Client.TransactionReceipt(ctx, hash)
+	if err == nil {
+		return receipt, false, nil
+	}
+	if !errors.Is(err, ethereum.NotFound) {
+		return nil, false, fmt.Errorf("submission receipt: %w", err)
+	}
+	if _, _, err := e.client.L1Client.TransactionByHash(ctx, hash); err != nil {
+		if errors.Is(err, ethereum.NotFound) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("submission lookup: %w", err)
+	}
+	return nil, true, nil
+}
+
+// executed marks a withdrawal the outbox already executed. The block of our
+// own earlier submission is looked up when we know it.
+func (e *Executor) executed(ctx context.Context, w *db.BridgeWithdrawal, l1TxHash string) error {
+	var block int64
+	if l1TxHash != "" {
+		if receipt, err := e.client.L1Client.TransactionReceipt(ctx, common.HexToHash(l1TxHash)); err == nil && receipt.Status == types.ReceiptStatusSuccessful {
+			block = receipt.BlockNumber.Int64()
+		} else {
+			l1TxHash = "" // executed by someone else
+		}
+	}
+	w.L1TxHash = l1TxHash
+	return e.finish(ctx, w, block)
+}
+
+// finish records an executed withdrawal, confirming its bridge message with
+// the L1 transaction, and publishes it as confirmed
+func (e *Executor) finish(ctx context.Context, w *db.BridgeWithdrawal, l1Block int64) error {
+	evt := e.event(w, "confirmed", w.L1TxHash, l1Block)
+	if err := db.MarkBridgeWithdrawalExecuted(e.database, evt); err != nil {
+		return err
+	}
+	log.Printf("✅ [Bridge Executor] Withdrawal %s finalized on L1 (%s)", w.WithdrawalID, w.L1TxHash)
+	metrics.BridgeWithdrawalsTotal.WithLabelValues("executed").Inc()
+	e.send(ctx, evt)
+	return nil
+}
+
+// event builds the withdrawal's BridgeEvent, keyed by withdrawal ID
+func (e *Executor) event(w *db.BridgeWithdrawal, status, l1TxHash string, l1Block int64) *models.BridgeEvent {
+	evt := &models.BridgeEvent{
+		UserAddress:   w.UserAddress,
+		Amount:        w.Amount,
+		Direction:     "L2_TO_L1",
+		Status:        status,
+		L1TxHash:      l1TxHash,
+		L2TxHash:      w.L2TxHash,
+		MessageHash:   w.WithdrawalID,
+		L1BlockNumber: l1Block,
+		L2BlockNumber: w.L2BlockNumber,
+		InitiatedAt:   w.CreatedAt,
+	}
+	if status == "confirmed" {
+		evt.ConfirmedAt = time.Now().Unix()
+	}
+	return evt
+}
+
+// publish writes the withdrawal's BridgeEvent
+func (e *Executor) publish(ctx context.Context, w *db.BridgeWithdrawal, status, l1TxHash string, l1Block int64) {
+	e.send(ctx, e.event(w, status, l1TxHash, l1Block))
+}
+
+// send writes evt, on the L1 chain once confirmed
+func (e *Executor) send(ctx context.Context, evt *models.BridgeEvent) {
+	chainID := e.config.L2ChainID
+	if evt.Status == "confirmed" {
+		chainID = e.config.L1ChainID
+	}
+
+	msg, err := events.Message(e.config.Codec, events.NewBridgeEnvelope(chainID, evt), []byte(evt.MessageHash),
+		k.Header{Key: "direction", Value: []byte(evt.Direction)},
+		k.Header{Key: "status", Value: []byte(evt.Status)},
+	)
+	if err != nil {
+		log.Printf("❌ [Bridge Executor] Encode error for %s: %v", evt.MessageHash, err)
+		return
+	}
+	if err := e.writer.WriteMessages(ctx, msg); err != nil {
+		log.Printf("❌ [Bridge Executor] Failed to publish %s %s: %v", evt.MessageHash, evt.Status, err)
+	}
+}
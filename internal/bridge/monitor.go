@@ -3,11 +3,14 @@ package bridge
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"log"
 	"sync"
 	"time"
 
 	"loyalty-points-system/internal/db"
+	"loyalty-points-system/internal/metrics"
 	"loyalty-points-system/internal/models"
 )
 
@@ -19,6 +22,8 @@ type Monitor struct {
 	maxRetries    int
 	mu           sync.RWMutex
 	running      bool
+	arbitrum     *ArbitrumTracker // resolves messages on-chain; nil falls back to the timeout
+	queue        *MessageQueue
 }
 
 // NewMonitor creates a new bridge monitor
@@ -29,9 +34,17 @@ func NewMonitor(database *sql.DB) *Monitor {
 		messageTimeout: 15 * time.Minute,     // Timeout after 15 minutes
 		maxRetries:    10,                    // Max 10 retry attempts
 		running:       false,
+		queue:         NewMessageQueue(database, QueueMonitor),
 	}
 }
 
+// WithArbitrum resolves pending messages against the rollup instead of only
+// timing them out
+func (m *Monitor) WithArbitrum(t *ArbitrumTracker) *Monitor {
+	m.arbitrum = t
+	return m
+}
+
 // Start begins monitoring bridge messages
 func (m *Monitor) Start(ctx context.Context) error {
 	m.mu.Lock()
@@ -67,59 +80,141 @@ func (m *Monitor) Stop() {
 	m.running = false
 }
 
-// processPendingMessages checks and retries pending bridge messages
+// monitorLeaseBatch is how many messages a monitor leases at a time
+const monitorLeaseBatch = 100
+
+// processPendingMessages checks and retries pending bridge messages. Messages
+// are leased from the monitor queue, so several monitors split the work
+// instead of checking every message twice.
 func (m *Monitor) processPendingMessages(ctx context.Context) error {
-	messages, err := db.GetPendingBridgeMessages(m.database)
-	if err != nil {
+	if err := m.queue.LoadPendingMessages(m.maxRetries); err != nil {
 		return err
 	}
-
-	if len(messages) == 0 {
-		return nil
+	if count, err := m.GetPendingMessagesCount(); err == nil {
+		metrics.BridgePendingMessages.Set(float64(count))
 	}
 
-	log.Printf("🔍 [Bridge Monitor] Found %d pending messages", len(messages))
+	// Lease until every leased message was already checked this round; a
+	// message leased again after its release delay is put back untouched so
+	// a slow round does not spend its retries twice
+	checked := make(map[string]bool)
+	processed := 0
+	for ctx.Err() == nil {
+		items, err := m.queue.Lease(monitorLeaseBatch, 2*m.checkInterval)
+		if err != nil {
+			return err
+		}
 
-	for _, msg := range messages {
-		if err := m.processMessage(ctx, msg); err != nil {
-			log.Printf("❌ [Bridge Monitor] Error processing message %s: %v", msg.MessageHash, err)
+		fresh := 0
+		for _, item := range items {
+			msg := item.Message
+			if !checked[msg.MessageHash] {
+				checked[msg.MessageHash] = true
+				fresh++
+				if err := m.processMessage(ctx, msg); err != nil {
+					log.Printf("❌ [Bridge Monitor] Error processing message %s: %v", msg.MessageHash, err)
+				}
+				processed++
+			}
+			// Hidden until the next round; settled messages leave the queue then
+			if err := m.queue.Release(msg.MessageHash, m.checkInterval/2); err != nil {
+				log.Printf("⚠️  [Bridge Monitor] Failed to release message %s: %v", msg.MessageHash, err)
+			}
+		}
+		if fresh == 0 {
+			break
 		}
 	}
 
+	if processed > 0 {
+		log.Printf("🔍 [Bridge Monitor] Checked %d pending messages", processed)
+	}
 	return nil
 }
 
 // processMessage handles a single pending bridge message
 func (m *Monitor) processMessage(ctx context.Context, msg *models.BridgeEvent) error {
+	// Check if max retries exceeded
+	if msg.RetryCount >= m.maxRetries {
+		log.Printf("🔄 [Bridge Monitor] Message %s exceeded max retries (%d/%d)",
+			msg.MessageHash, msg.RetryCount, m.maxRetries)
+		return m.markFailed(msg, "Max retry attempts exceeded")
+	}
+
+	// Query the chains; messages that never went through the rollup's
+	// Inbox or ArbSys fall back to the timeout below
+	if m.arbitrum != nil {
+		res, err := m.resolve(ctx, msg)
+		if err == nil {
+			return m.record(msg, res)
+		}
+		if !errors.Is(err, ErrNoArbitrumMessage) {
+			log.Printf("🔄 [Bridge Monitor] Checking message %s failed (retry %d/%d, direction: %s): %v",
+				msg.MessageHash, msg.RetryCount+1, m.maxRetries, msg.Direction, err)
+			metrics.BridgeRetryCount.WithLabelValues(msg.Direction).Inc()
+			return db.RecordBridgeMessageCheckFailure(m.database, msg.MessageHash, err.Error())
+		}
+	}
+
 	// Check if message has timed out
 	if m.isTimedOut(msg) {
 		log.Printf("⏰ [Bridge Monitor] Message %s timed out (initiated %s ago)",
 			msg.MessageHash, time.Since(time.Unix(msg.InitiatedAt, 0)))
-		return db.MarkBridgeMessageFailed(m.database, msg.MessageHash, "Message timed out after 15 minutes")
+		return m.markFailed(msg, fmt.Sprintf("Message timed out after %s", m.messageTimeout))
 	}
+	return nil
+}
 
-	// Check if max retries exceeded
-	if msg.RetryCount >= m.maxRetries {
-		log.Printf("🔄 [Bridge Monitor] Message %s exceeded max retries (%d/%d)",
-			msg.MessageHash, msg.RetryCount, m.maxRetries)
-		return db.MarkBridgeMessageFailed(m.database, msg.MessageHash, "Max retry attempts exceeded")
+// resolve looks up the on-chain state of a message
+func (m *Monitor) resolve(ctx context.Context, msg *models.BridgeEvent) (*Resolution, error) {
+	switch {
+	case msg.Direction == "L1_TO_L2" && msg.L1TxHash != "":
+		return m.arbitrum.ResolveRetryable(ctx, msg.L1TxHash)
+	case msg.Direction == "L2_TO_L1" && msg.L2TxHash != "":
+		return m.arbitrum.ResolveOutbox(ctx, msg.L2TxHash)
 	}
+	return nil, ErrNoArbitrumMessage
+}
 
-	// Attempt to check message status
-	// In production, this would query L1/L2 chains for transaction receipts
-	log.Printf("🔄 [Bridge Monitor] Checking message %s (retry %d/%d, direction: %s)",
-		msg.MessageHash, msg.RetryCount, m.maxRetries, msg.Direction)
-
-	// TODO: Implement actual chain query logic
-	// For now, we just increment retry count
-	// In production, you would:
-	// 1. Query L1 for L1→L2 messages using Arbitrum Inbox
-	// 2. Query L2 for L2→L1 messages using Arbitrum Outbox
-	// 3. Update status based on transaction receipts
+// record stores a resolution. Redeemed retryables and executed outbox
+// messages are confirmed; expired or failed retryables are failed; anything
+// else stays pending.
+func (m *Monitor) record(msg *models.BridgeEvent, res *Resolution) error {
+	t := &db.BridgeMessageTracking{
+		Status:         "pending",
+		ArbitrumStatus: res.ArbitrumStatus,
+		TicketID:       res.TicketID,
+		OutboxPosition: res.OutboxPosition,
+		L2TxHash:       res.L2TxHash,
+	}
+	switch res.ArbitrumStatus {
+	case RetryableRedeemed, OutboxExecuted:
+		t.Status = "confirmed"
+	case RetryableExpired:
+		t.Status, t.ErrorMsg = "failed", "Retryable ticket expired without a successful redeem"
+	case RetryableCreationFailed:
+		t.Status, t.ErrorMsg = "failed", "Retryable ticket submission reverted on L2"
+	}
 
+	if err := db.UpdateBridgeMessageTracking(m.database, msg.MessageHash, t); err != nil {
+		return err
+	}
+	if t.Status != "pending" {
+		log.Printf("🌉 [Bridge Monitor] Message %s %s (%s)", msg.MessageHash, t.Status, res.ArbitrumStatus)
+		metrics.BridgeMessagesTotal.WithLabelValues(msg.Direction, t.Status).Inc()
+		if t.Status == "confirmed" {
+			metrics.BridgeConfirmationTime.WithLabelValues(msg.Direction).Observe(time.Since(time.Unix(msg.InitiatedAt, 0)).Seconds())
+		}
+	}
 	return nil
 }
 
+// markFailed fails a message and counts it
+func (m *Monitor) markFailed(msg *models.BridgeEvent, reason string) error {
+	metrics.BridgeMessagesTotal.WithLabelValues(msg.Direction, "failed").Inc()
+	return db.MarkBridgeMessageFailed(m.database, msg.MessageHash, reason)
+}
+
 // isTimedOut checks if a message has exceeded the timeout period
 func (m *Monitor) isTimedOut(msg *models.BridgeEvent) bool {
 	initiatedTime := time.Unix(msg.InitiatedAt, 0)
@@ -249,7 +344,7 @@ func (m *Monitor) RetryMessage(messageHash string) error {
 	}
 
 	// Check if retry is allowed
-	if status.Status == "confirmed" {
+	if !models.CanTransitionBridgeStatus(status.Status, models.BridgeStatusPending) {
 		return nil // Already confirmed, no retry needed
 	}
 
@@ -260,9 +355,10 @@ func (m *Monitor) RetryMessage(messageHash string) error {
 	// Reset status to pending for retry
 	_, err = m.database.Exec(`
 		UPDATE bridge_messages
-		SET status = 'pending', retry_count = retry_count + 1, updated_at = NOW()
-		WHERE message_hash = $1
-	`, messageHash)
+		SET status = 'pending', retry_count = retry_count + 1,
+		    pending_at = COALESCE(pending_at, NOW()), updated_at = NOW()
+		WHERE message_hash = $1 AND status = ANY($2)
+	`, messageHash, models.BridgeStatusesInto(models.BridgeStatusPending))
 
 	if err == nil {
 		log.Printf("🔄 [Bridge Monitor] Message %s queued for retry (attempt %d)",
@@ -0,0 +1,181 @@
+package bridge
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"loyalty-points-system/internal/db"
+	"loyalty-points-system/internal/metrics"
+	"loyalty-points-system/internal/models"
+)
+
+// Reasons a bridge request is refused; wrapped with the numbers involved
+var (
+	ErrBridgePaused      = errors.New("collateral vault is emergency paused")
+	ErrDailyCapacity     = errors.New("amount exceeds the vault's remaining daily lock capacity")
+	ErrUserVolume        = errors.New("amount exceeds the per-user bridge volume limit")
+	ErrGlobalVolume      = errors.New("amount exceeds the global bridge volume limit")
+	ErrCircuitOpen       = errors.New("bridge circuit breaker is open")
+	ErrPolicyUnavailable = errors.New("bridge limits could not be checked")
+)
+
+// VaultLimits is the part of CollateralVaultL1 a request has to fit in:
+// deposits lock collateral and withdrawals unlock it, both only while the
+// vault is not paused; implemented by l1.CollateralVaultService
+type VaultLimits interface {
+	GetRemainingDailyLockCapacity(ctx context.Context) (*big.Int, error)
+	IsEmergencyPaused(ctx context.Context) (bool, error)
+}
+
+// PolicyConfig sets the limits a bridge request is checked against. Zero
+// volume limits and a zero failure threshold disable that check.
+type PolicyConfig struct {
+	UserMaxVolume   *big.Int // wei per user within UserWindow
+	UserWindow      time.Duration
+	GlobalMaxVolume *big.Int // wei across all users within GlobalWindow
+	GlobalWindow    time.Duration
+
+	BreakerMaxFailures int // failed messages within BreakerWindow that open the breaker
+	BreakerWindow      time.Duration
+	BreakerCooldown    time.Duration // how long the breaker stays open
+}
+
+// Policy decides whether a bridge request is accepted before anything is sent
+// on-chain. No request may hit the vault's emergency pause, since withdrawals
+// finalize through CollateralVaultL1.unlockCollateral; deposits also have to
+// fit the vault's remaining daily lock capacity. Every request has to fit the
+// per-user and global volume windows and reserves its amount in them when
+// accepted. A burst of failed messages opens a circuit breaker that refuses
+// all requests until it cools down. Reservations and the breaker live in
+// Postgres, so every API replica enforces the same limits.
+type Policy struct {
+	database *sql.DB
+	vault    VaultLimits // nil skips the on-chain checks
+	config   PolicyConfig
+}
+
+// NewPolicy creates a bridge policy. vault may be nil when no L1 vault is configured.
+func NewPolicy(database *sql.DB, vault VaultLimits, config PolicyConfig) *Policy {
+	if config.UserMaxVolume == nil {
+		config.UserMaxVolume = new(big.Int)
+	}
+	if config.GlobalMaxVolume == nil {
+		config.GlobalMaxVolume = new(big.Int)
+	}
+	if config.UserWindow <= 0 {
+		config.UserWindow = 24 * time.Hour
+	}
+	if config.GlobalWindow <= 0 {
+		config.GlobalWindow = time.Hour
+	}
+	if config.BreakerWindow <= 0 {
+		config.BreakerWindow = 10 * time.Minute
+	}
+	if config.BreakerCooldown <= 0 {
+		config.BreakerCooldown = 15 * time.Minute
+	}
+	metrics.BridgeCircuitOpen.Set(0)
+	return &Policy{database: database, vault: vault, config: config}
+}
+
+// Reserve returns nil when a request to move amount wei in direction for
+// user may go ahead, and records its amount in the volume windows. Otherwise
+// it returns an error wrapping one of the Err* reasons and reserves nothing.
+func (p *Policy) Reserve(ctx context.Context, direction string, user common.Address, amount *big.Int) error {
+	err := p.reserve(ctx, direction, user, amount)
+	if err != nil {
+		metrics.BridgePolicyRejections.WithLabelValues(RejectionReason(err)).Inc()
+	}
+	return err
+}
+
+func (p *Policy) reserve(ctx context.Context, direction string, user common.Address, amount *big.Int) error {
+	if err := p.checkBreaker(ctx); err != nil {
+		return err
+	}
+
+	if p.vault != nil {
+		paused, err := p.vault.IsEmergencyPaused(ctx)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrPolicyUnavailable, err)
+		}
+		if paused {
+			return ErrBridgePaused
+		}
+	}
+	if direction == models.BridgeL1ToL2 && p.vault != nil {
+		remaining, err := p.vault.GetRemainingDailyLockCapacity(ctx)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrPolicyUnavailable, err)
+		}
+		if amount.Cmp(remaining) > 0 {
+			return fmt.Errorf("%w: requested %s, remaining %s", ErrDailyCapacity, amount, remaining)
+		}
+	}
+
+	now := time.Now()
+	res, err := db.ReserveBridgeVolume(ctx, p.database, direction, user.Hex(), amount,
+		db.BridgeVolumeLimit{Max: p.config.UserMaxVolume, Since: now.Add(-p.config.UserWindow)},
+		db.BridgeVolumeLimit{Max: p.config.GlobalMaxVolume, Since: now.Add(-p.config.GlobalWindow)})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPolicyUnavailable, err)
+	}
+	if res.UserOver {
+		return fmt.Errorf("%w: %s used of %s per %s", ErrUserVolume, res.UserUsed, p.config.UserMaxVolume, p.config.UserWindow)
+	}
+	if res.GlobalOver {
+		return fmt.Errorf("%w: %s used of %s per %s", ErrGlobalVolume, res.GlobalUsed, p.config.GlobalMaxVolume, p.config.GlobalWindow)
+	}
+	return nil
+}
+
+// checkBreaker refuses requests while the shared breaker is open, opening it
+// when too many messages failed in the window
+func (p *Policy) checkBreaker(ctx context.Context) error {
+	state, err := db.CheckBridgeBreaker(ctx, p.database, p.config.BreakerMaxFailures,
+		p.config.BreakerWindow, p.config.BreakerCooldown)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPolicyUnavailable, err)
+	}
+
+	if state.Closed {
+		log.Println("🔌 [Bridge Policy] Circuit breaker closed")
+	}
+	if state.OpenUntil.IsZero() {
+		metrics.BridgeCircuitOpen.Set(0)
+		return nil
+	}
+	metrics.BridgeCircuitOpen.Set(1)
+
+	until := state.OpenUntil.UTC().Format(time.RFC3339)
+	if !state.Opened {
+		return fmt.Errorf("%w until %s", ErrCircuitOpen, until)
+	}
+	log.Printf("🚨 [Bridge Policy] Circuit breaker opened: %d failed messages within %s, refusing requests for %s",
+		state.Failures, p.config.BreakerWindow, p.config.BreakerCooldown)
+	return fmt.Errorf("%w until %s: %d bridge messages failed recently", ErrCircuitOpen, until, state.Failures)
+}
+
+// RejectionReason labels an error from Check for metrics and API responses
+func RejectionReason(err error) string {
+	switch {
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, ErrBridgePaused):
+		return "emergency_paused"
+	case errors.Is(err, ErrDailyCapacity):
+		return "daily_capacity"
+	case errors.Is(err, ErrUserVolume):
+		return "user_volume"
+	case errors.Is(err, ErrGlobalVolume):
+		return "global_volume"
+	}
+	return "unavailable"
+}
@@ -1,10 +1,11 @@
 package bridge
 
 import (
-	"container/heap"
 	"database/sql"
+	"fmt"
 	"log"
-	"sync"
+	"os"
+	"sort"
 	"time"
 
 	"loyalty-points-system/internal/models"
@@ -18,136 +19,107 @@ const (
 	PriorityEmergency = 3
 )
 
-// MessageQueue manages pending bridge operations with priority support
+// Named queues
+const (
+	QueueMonitor = "monitor"
+	QueueRedeem  = "redeem"
+)
+
+// MessageQueue is a named work queue of bridge messages persisted in
+// bridge_queue. Workers lease items in priority order with
+// SELECT ... FOR UPDATE SKIP LOCKED, so several workers (and several
+// processes) drain the same queue without handing out an item twice. A lease
+// expires after its visibility timeout, returning the items of a crashed
+// worker to the queue.
 type MessageQueue struct {
 	database *sql.DB
-	pq       *PriorityQueue
-	mu       sync.RWMutex
-	items    map[string]*QueueItem // messageHash -> item
+	name     string
+	owner    string // identifies this process's leases
 }
 
 // QueueItem represents a bridge message in the queue
 type QueueItem struct {
-	Message  *models.BridgeEvent
-	Priority int
-	AddedAt  time.Time
-	index    int // Index in heap
-}
-
-// PriorityQueue implements heap.Interface
-type PriorityQueue []*QueueItem
-
-func (pq PriorityQueue) Len() int { return len(pq) }
-
-func (pq PriorityQueue) Less(i, j int) bool {
-	// Higher priority comes first
-	if pq[i].Priority != pq[j].Priority {
-		return pq[i].Priority > pq[j].Priority
-	}
-	// If same priority, earlier timestamp comes first (FIFO)
-	return pq[i].AddedAt.Before(pq[j].AddedAt)
-}
-
-func (pq PriorityQueue) Swap(i, j int) {
-	pq[i], pq[j] = pq[j], pq[i]
-	pq[i].index = i
-	pq[j].index = j
-}
-
-func (pq *PriorityQueue) Push(x interface{}) {
-	n := len(*pq)
-	item := x.(*QueueItem)
-	item.index = n
-	*pq = append(*pq, item)
+	Message     *models.BridgeEvent
+	Priority    int
+	AddedAt     time.Time
+	LeasedUntil time.Time // zero when not leased
 }
 
-func (pq *PriorityQueue) Pop() interface{} {
-	old := *pq
-	n := len(old)
-	item := old[n-1]
-	old[n-1] = nil  // avoid memory leak
-	item.index = -1 // for safety
-	*pq = old[0 : n-1]
-	return item
-}
-
-// NewMessageQueue creates a new message queue
-func NewMessageQueue(database *sql.DB) *MessageQueue {
-	pq := make(PriorityQueue, 0)
-	heap.Init(&pq)
-
+// queueMessageColumns are the bridge_messages columns scanned into a BridgeEvent
+const queueMessageColumns = `
+	m.message_hash, m.direction, m.user_address, m.amount, m.status,
+	COALESCE(m.l1_tx_hash, ''), COALESCE(m.l2_tx_hash, ''),
+	COALESCE(m.l1_block_number, 0), COALESCE(m.l2_block_number, 0),
+	EXTRACT(EPOCH FROM m.initiated_at)::bigint,
+	COALESCE(EXTRACT(EPOCH FROM m.confirmed_at)::bigint, 0),
+	m.retry_count, COALESCE(m.error_msg, '')`
+
+// NewMessageQueue opens the named queue
+func NewMessageQueue(database *sql.DB, name string) *MessageQueue {
+	host, _ := os.Hostname()
 	return &MessageQueue{
 		database: database,
-		pq:       &pq,
-		items:    make(map[string]*QueueItem),
+		name:     name,
+		owner:    fmt.Sprintf("%s-%d", host, os.Getpid()),
 	}
 }
 
-// LoadPendingMessages loads pending messages from database into queue
-func (mq *MessageQueue) LoadPendingMessages() error {
-	mq.mu.Lock()
-	defer mq.mu.Unlock()
+// LoadPendingMessages enqueues every pending message at its computed priority
+// and drops items whose message was confirmed or failed meanwhile. Items
+// already queued keep their priority unless the computed one is higher.
+// Messages that used up maxRetries are still loaded once more so the
+// monitor can mark them failed.
+func (mq *MessageQueue) LoadPendingMessages(maxRetries int) error {
+	_, err := mq.database.Exec(`
+		DELETE FROM bridge_queue q
+		USING bridge_messages m
+		WHERE q.queue = $1
+		  AND m.message_hash = q.message_hash
+		  AND m.status NOT IN ('initiated', 'pending')
+	`, mq.name)
+	if err != nil {
+		return err
+	}
 
-	query := `
-		SELECT message_hash, direction, user_address, amount, status,
-		       l1_tx_hash, l2_tx_hash, l1_block_number, l2_block_number,
-		       EXTRACT(EPOCH FROM initiated_at)::bigint,
-		       COALESCE(EXTRACT(EPOCH FROM confirmed_at)::bigint, 0),
-		       retry_count, COALESCE(error_msg, '')
-		FROM bridge_messages
-		WHERE status IN ('initiated', 'pending')
-		  AND retry_count < 10
-		ORDER BY initiated_at ASC
-	`
-
-	rows, err := mq.database.Query(query)
+	rows, err := mq.database.Query(`
+		SELECT ` + queueMessageColumns + `
+		FROM bridge_messages m
+		WHERE m.status IN ('initiated', 'pending')
+		  AND m.retry_count <= $1
+		ORDER BY m.initiated_at ASC
+	`, maxRetries)
 	if err != nil {
 		return err
 	}
 	defer rows.Close()
 
-	count := 0
+	var messages []*models.BridgeEvent
 	for rows.Next() {
-		evt := &models.BridgeEvent{}
-		err := rows.Scan(
-			&evt.MessageHash,
-			&evt.Direction,
-			&evt.UserAddress,
-			&evt.Amount,
-			&evt.Status,
-			&evt.L1TxHash,
-			&evt.L2TxHash,
-			&evt.L1BlockNumber,
-			&evt.L2BlockNumber,
-			&evt.InitiatedAt,
-			&evt.ConfirmedAt,
-			&evt.RetryCount,
-			&evt.ErrorMsg,
-		)
+		evt, err := scanQueueMessage(rows)
 		if err != nil {
 			return err
 		}
+		messages = append(messages, evt)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
 
-		// Determine priority based on direction and retry count
-		priority := mq.determinePriority(evt)
-
-		// Add to queue
-		item := &QueueItem{
-			Message:  evt,
-			Priority: priority,
-			AddedAt:  time.Now(),
+	added := 0
+	for _, evt := range messages {
+		inserted, err := mq.enqueue(evt.MessageHash, mq.determinePriority(evt))
+		if err != nil {
+			return err
+		}
+		if inserted {
+			added++
 		}
-
-		heap.Push(mq.pq, item)
-		mq.items[evt.MessageHash] = item
-		count++
 	}
 
-	if count > 0 {
-		log.Printf("📥 [Message Queue] Loaded %d pending messages", count)
+	if added > 0 {
+		log.Printf("📥 [Message Queue] Loaded %d pending messages into %s", added, mq.name)
 	}
-
-	return rows.Err()
+	return nil
 }
 
 // determinePriority assigns priority based on message characteristics
@@ -171,184 +143,323 @@ func (mq *MessageQueue) determinePriority(msg *models.BridgeEvent) int {
 	return PriorityNormal
 }
 
-// Enqueue adds a new message to the queue
-func (mq *MessageQueue) Enqueue(msg *models.BridgeEvent, priority int) {
-	mq.mu.Lock()
-	defer mq.mu.Unlock()
-
-	// Check if already in queue
-	if _, exists := mq.items[msg.MessageHash]; exists {
-		return
+// Enqueue adds a message to the queue. A message already queued keeps its
+// place and is only raised to priority if that is higher.
+func (mq *MessageQueue) Enqueue(msg *models.BridgeEvent, priority int) error {
+	inserted, err := mq.enqueue(msg.MessageHash, priority)
+	if err != nil {
+		return err
 	}
-
-	item := &QueueItem{
-		Message:  msg,
-		Priority: priority,
-		AddedAt:  time.Now(),
+	if inserted {
+		log.Printf("📩 [Message Queue] Enqueued message %s in %s (priority: %d)", msg.MessageHash, mq.name, priority)
 	}
-
-	heap.Push(mq.pq, item)
-	mq.items[msg.MessageHash] = item
-
-	log.Printf("📩 [Message Queue] Enqueued message %s (priority: %d)", msg.MessageHash, priority)
+	return nil
 }
 
-// Dequeue removes and returns the highest priority message
-func (mq *MessageQueue) Dequeue() *models.BridgeEvent {
-	mq.mu.Lock()
-	defer mq.mu.Unlock()
+// enqueue inserts or raises an item and reports whether it was new
+func (mq *MessageQueue) enqueue(messageHash string, priority int) (bool, error) {
+	var inserted bool
+	err := mq.database.QueryRow(`
+		INSERT INTO bridge_queue (queue, message_hash, priority)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (queue, message_hash)
+		DO UPDATE SET priority = GREATEST(bridge_queue.priority, EXCLUDED.priority)
+		RETURNING (xmax = 0)
+	`, mq.name, messageHash, priority).Scan(&inserted)
+	return inserted, err
+}
 
-	if mq.pq.Len() == 0 {
-		return nil
+// Lease hands out up to n visible items in priority order (FIFO within a
+// priority) and hides them from other workers for visibility. Each leased
+// item must be acknowledged with Remove or given back with Release; items
+// that are neither reappear once the lease expires.
+func (mq *MessageQueue) Lease(n int, visibility time.Duration) ([]*QueueItem, error) {
+	rows, err := mq.database.Query(`
+		WITH picked AS (
+			SELECT message_hash
+			FROM bridge_queue
+			WHERE queue = $1
+			  AND (leased_until IS NULL OR leased_until < NOW())
+			ORDER BY priority DESC, enqueued_at ASC
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		), leased AS (
+			UPDATE bridge_queue q
+			SET leased_until = NOW() + make_interval(secs => $3::float8),
+			    lease_owner = $4,
+			    leases = q.leases + 1
+			FROM picked
+			WHERE q.queue = $1 AND q.message_hash = picked.message_hash
+			RETURNING q.message_hash, q.priority, q.enqueued_at, q.leased_until
+		)
+		SELECT l.priority, l.enqueued_at, l.leased_until, `+queueMessageColumns+`
+		FROM leased l
+		JOIN bridge_messages m ON m.message_hash = l.message_hash
+	`, mq.name, n, visibility.Seconds(), mq.owner)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	item := heap.Pop(mq.pq).(*QueueItem)
-	delete(mq.items, item.Message.MessageHash)
-
-	return item.Message
+	items, err := scanQueueItems(rows, true)
+	if err != nil {
+		return nil, err
+	}
+	// RETURNING has no order of its own
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Priority != items[j].Priority {
+			return items[i].Priority > items[j].Priority
+		}
+		return items[i].AddedAt.Before(items[j].AddedAt)
+	})
+	return items, nil
 }
 
-// Peek returns the highest priority message without removing it
-func (mq *MessageQueue) Peek() *models.BridgeEvent {
-	mq.mu.RLock()
-	defer mq.mu.RUnlock()
+// Release gives a leased item back to the queue; it becomes visible again
+// after delay (0 = immediately)
+func (mq *MessageQueue) Release(messageHash string, delay time.Duration) error {
+	_, err := mq.database.Exec(`
+		UPDATE bridge_queue
+		SET leased_until = CASE WHEN $3::float8 > 0 THEN NOW() + make_interval(secs => $3::float8) ELSE NULL END,
+		    lease_owner = NULL
+		WHERE queue = $1 AND message_hash = $2 AND lease_owner = $4
+	`, mq.name, messageHash, delay.Seconds(), mq.owner)
+	return err
+}
 
-	if mq.pq.Len() == 0 {
-		return nil
+// Dequeue removes and returns the highest priority visible message
+func (mq *MessageQueue) Dequeue() (*models.BridgeEvent, error) {
+	rows, err := mq.database.Query(`
+		WITH picked AS (
+			SELECT message_hash
+			FROM bridge_queue
+			WHERE queue = $1
+			  AND (leased_until IS NULL OR leased_until < NOW())
+			ORDER BY priority DESC, enqueued_at ASC
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		), removed AS (
+			DELETE FROM bridge_queue q
+			USING picked
+			WHERE q.queue = $1 AND q.message_hash = picked.message_hash
+			RETURNING q.message_hash, q.priority, q.enqueued_at
+		)
+		SELECT r.priority, r.enqueued_at, `+queueMessageColumns+`
+		FROM removed r
+		JOIN bridge_messages m ON m.message_hash = r.message_hash
+	`, mq.name)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	return (*mq.pq)[0].Message
+	items, err := scanQueueItems(rows, false)
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+	return items[0].Message, nil
 }
 
-// Remove removes a specific message from the queue
-func (mq *MessageQueue) Remove(messageHash string) bool {
-	mq.mu.Lock()
-	defer mq.mu.Unlock()
-
-	item, exists := mq.items[messageHash]
-	if !exists {
-		return false
+// Peek returns the highest priority visible message without leasing it
+func (mq *MessageQueue) Peek() (*models.BridgeEvent, error) {
+	items, err := mq.snapshot(`AND (q.leased_until IS NULL OR q.leased_until < NOW())`, 1)
+	if err != nil || len(items) == 0 {
+		return nil, err
 	}
+	return items[0].Message, nil
+}
 
-	heap.Remove(mq.pq, item.index)
-	delete(mq.items, messageHash)
-
-	log.Printf("🗑️  [Message Queue] Removed message %s", messageHash)
-	return true
+// Remove acknowledges a lease held by this queue's owner, deleting the item.
+// It reports false when the lease was lost meanwhile: the item expired and may
+// have been leased by another worker, which then owns it.
+func (mq *MessageQueue) Remove(messageHash string) (bool, error) {
+	res, err := mq.database.Exec(`
+		DELETE FROM bridge_queue WHERE queue = $1 AND message_hash = $2 AND lease_owner = $3
+	`, mq.name, messageHash, mq.owner)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
 }
 
 // UpdatePriority changes the priority of a message in the queue
-func (mq *MessageQueue) UpdatePriority(messageHash string, newPriority int) bool {
-	mq.mu.Lock()
-	defer mq.mu.Unlock()
-
-	item, exists := mq.items[messageHash]
-	if !exists {
-		return false
+func (mq *MessageQueue) UpdatePriority(messageHash string, newPriority int) (bool, error) {
+	res, err := mq.database.Exec(`
+		UPDATE bridge_queue SET priority = $3 WHERE queue = $1 AND message_hash = $2
+	`, mq.name, messageHash, newPriority)
+	if err != nil {
+		return false, err
 	}
-
-	item.Priority = newPriority
-	heap.Fix(mq.pq, item.index)
-
-	log.Printf("🔄 [Message Queue] Updated priority for %s to %d", messageHash, newPriority)
-	return true
+	n, err := res.RowsAffected()
+	if n > 0 {
+		log.Printf("🔄 [Message Queue] Updated priority for %s to %d", messageHash, newPriority)
+	}
+	return n > 0, err
 }
 
-// Size returns the number of messages in the queue
-func (mq *MessageQueue) Size() int {
-	mq.mu.RLock()
-	defer mq.mu.RUnlock()
-	return mq.pq.Len()
+// Size returns the number of messages in the queue, leased or not
+func (mq *MessageQueue) Size() (int, error) {
+	var n int
+	err := mq.database.QueryRow(`SELECT COUNT(*) FROM bridge_queue WHERE queue = $1`, mq.name).Scan(&n)
+	return n, err
 }
 
 // IsEmpty returns true if queue is empty
-func (mq *MessageQueue) IsEmpty() bool {
-	mq.mu.RLock()
-	defer mq.mu.RUnlock()
-	return mq.pq.Len() == 0
+func (mq *MessageQueue) IsEmpty() (bool, error) {
+	n, err := mq.Size()
+	return n == 0, err
 }
 
 // Contains checks if a message is in the queue
-func (mq *MessageQueue) Contains(messageHash string) bool {
-	mq.mu.RLock()
-	defer mq.mu.RUnlock()
-	_, exists := mq.items[messageHash]
-	return exists
+func (mq *MessageQueue) Contains(messageHash string) (bool, error) {
+	var exists bool
+	err := mq.database.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM bridge_queue WHERE queue = $1 AND message_hash = $2)
+	`, mq.name, messageHash).Scan(&exists)
+	return exists, err
 }
 
-// GetQueueSnapshot returns a snapshot of all messages in the queue
-func (mq *MessageQueue) GetQueueSnapshot() []*QueueItem {
-	mq.mu.RLock()
-	defer mq.mu.RUnlock()
-
-	snapshot := make([]*QueueItem, len(*mq.pq))
-	for i, item := range *mq.pq {
-		snapshot[i] = &QueueItem{
-			Message:  item.Message,
-			Priority: item.Priority,
-			AddedAt:  item.AddedAt,
-		}
-	}
-
-	return snapshot
+// GetQueueSnapshot returns all messages in the queue in priority order
+func (mq *MessageQueue) GetQueueSnapshot() ([]*QueueItem, error) {
+	return mq.snapshot("", 0)
 }
 
 // GetByPriority returns all messages with a specific priority
-func (mq *MessageQueue) GetByPriority(priority int) []*models.BridgeEvent {
-	mq.mu.RLock()
-	defer mq.mu.RUnlock()
+func (mq *MessageQueue) GetByPriority(priority int) ([]*models.BridgeEvent, error) {
+	items, err := mq.snapshot("AND q.priority = $2", 0, priority)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]*models.BridgeEvent, len(items))
+	for i, item := range items {
+		messages[i] = item.Message
+	}
+	return messages, nil
+}
 
-	var messages []*models.BridgeEvent
-	for _, item := range *mq.pq {
-		if item.Priority == priority {
-			messages = append(messages, item.Message)
-		}
+// snapshot lists items matching an extra condition (with parameters from $2)
+// in priority order; limit 0 means all
+func (mq *MessageQueue) snapshot(condition string, limit int, args ...any) ([]*QueueItem, error) {
+	query := `
+		SELECT q.priority, q.enqueued_at, COALESCE(q.leased_until, 'epoch'), ` + queueMessageColumns + `
+		FROM bridge_queue q
+		JOIN bridge_messages m ON m.message_hash = q.message_hash
+		WHERE q.queue = $1 ` + condition + `
+		ORDER BY q.priority DESC, q.enqueued_at ASC`
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
 	}
 
-	return messages
+	rows, err := mq.database.Query(query, append([]any{mq.name}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanQueueItems(rows, true)
 }
 
 // Clear removes all messages from the queue
-func (mq *MessageQueue) Clear() {
-	mq.mu.Lock()
-	defer mq.mu.Unlock()
-
-	mq.pq = &PriorityQueue{}
-	heap.Init(mq.pq)
-	mq.items = make(map[string]*QueueItem)
-
-	log.Println("🧹 [Message Queue] Cleared all messages")
+func (mq *MessageQueue) Clear() error {
+	if _, err := mq.database.Exec(`DELETE FROM bridge_queue WHERE queue = $1`, mq.name); err != nil {
+		return err
+	}
+	log.Printf("🧹 [Message Queue] Cleared all messages from %s", mq.name)
+	return nil
 }
 
 // GetStats returns queue statistics
-func (mq *MessageQueue) GetStats() *QueueStats {
-	mq.mu.RLock()
-	defer mq.mu.RUnlock()
-
-	stats := &QueueStats{
-		TotalMessages: mq.pq.Len(),
-		ByPriority:    make(map[int]int),
+func (mq *MessageQueue) GetStats() (*QueueStats, error) {
+	stats := &QueueStats{ByPriority: make(map[int]int)}
+
+	rows, err := mq.database.Query(`
+		SELECT priority, COUNT(*), COUNT(*) FILTER (WHERE leased_until > NOW()), MIN(enqueued_at)
+		FROM bridge_queue
+		WHERE queue = $1
+		GROUP BY priority
+	`, mq.name)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	for _, item := range *mq.pq {
-		stats.ByPriority[item.Priority]++
+	for rows.Next() {
+		var priority, count, leased int
+		var oldest time.Time
+		if err := rows.Scan(&priority, &count, &leased, &oldest); err != nil {
+			return nil, err
+		}
+		stats.TotalMessages += count
+		stats.LeasedMessages += leased
+		stats.ByPriority[priority] = count
 
 		// Track oldest message
-		if stats.OldestMessage.IsZero() || item.AddedAt.Before(stats.OldestMessage) {
-			stats.OldestMessage = item.AddedAt
+		if stats.OldestMessage.IsZero() || oldest.Before(stats.OldestMessage) {
+			stats.OldestMessage = oldest
 		}
 	}
 
-	return stats
+	return stats, rows.Err()
 }
 
 // QueueStats contains queue statistics
 type QueueStats struct {
 	TotalMessages  int
+	LeasedMessages int
 	ByPriority     map[int]int
 	OldestMessage  time.Time
 }
 
+// scanQueueItems reads rows of priority, enqueued_at, [leased_until,] and the
+// message columns
+func scanQueueItems(rows *sql.Rows, withLease bool) ([]*QueueItem, error) {
+	var items []*QueueItem
+	for rows.Next() {
+		item := &QueueItem{Message: &models.BridgeEvent{}}
+		dest := []any{&item.Priority, &item.AddedAt}
+		if withLease {
+			dest = append(dest, &item.LeasedUntil)
+		}
+		dest = append(dest, queueMessageDest(item.Message)...)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		if item.LeasedUntil.Unix() == 0 {
+			item.LeasedUntil = time.Time{}
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// scanQueueMessage reads a row of the message columns
+func scanQueueMessage(rows *sql.Rows) (*models.BridgeEvent, error) {
+	evt := &models.BridgeEvent{}
+	if err := rows.Scan(queueMessageDest(evt)...); err != nil {
+		return nil, err
+	}
+	return evt, nil
+}
+
+// queueMessageDest are the scan targets of queueMessageColumns
+func queueMessageDest(evt *models.BridgeEvent) []any {
+	return []any{
+		&evt.MessageHash,
+		&evt.Direction,
+		&evt.UserAddress,
+		&evt.Amount,
+		&evt.Status,
+		&evt.L1TxHash,
+		&evt.L2TxHash,
+		&evt.L1BlockNumber,
+		&evt.L2BlockNumber,
+		&evt.InitiatedAt,
+		&evt.ConfirmedAt,
+		&evt.RetryCount,
+		&evt.ErrorMsg,
+	}
+}
+
 // PriorityName returns human-readable priority name
 func PriorityName(priority int) string {
 	switch priority {
@@ -0,0 +1,310 @@
+package bridge
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+
+	"loyalty-points-system/internal/blockchain/l1"
+	"loyalty-points-system/internal/chain"
+	"loyalty-points-system/internal/models"
+)
+
+// Gas assumed when a transaction cannot be estimated for the user, e.g.
+// before the collateral allowance is set
+const (
+	defaultDepositGas  = 250_000 // L1Gateway.depositToL2: vault lock and retryable ticket
+	defaultApproveGas  = 50_000  // collateral approve, when the allowance is short
+	defaultWithdrawGas = 150_000 // L2 withdrawal including ArbSys.sendTxToL1
+	defaultExecuteGas  = 200_000 // Outbox.executeTransaction into L1Gateway.finalizeWithdrawal
+)
+
+// finalizeDepositDataLength is the calldata of the retryable every deposit
+// creates: finalizeDeposit(uint256,address,uint256)
+const finalizeDepositDataLength = 4 + 3*32
+
+// inboxABI is Inbox.calculateRetryableSubmissionFee
+var inboxABI = mustABI(`[{"type":"function","name":"calculateRetryableSubmissionFee","stateMutability":"view",
+	"inputs":[{"name":"dataLength","type":"uint256"},{"name":"baseFee","type":"uint256"}],
+	"outputs":[{"name":"","type":"uint256"}]}]`)
+
+// ErrUnsupportedToken is returned for a token the gateway does not bridge
+var ErrUnsupportedToken = errors.New("token is not bridged by the gateway")
+
+// GasCost is the cost of one transaction, in wei
+type GasCost struct {
+	Chain     string `json:"chain"` // l1 or l2
+	GasLimit  uint64 `json:"gas_limit"`
+	GasPrice  string `json:"gas_price"`
+	CostWei   string `json:"cost_wei"`
+	Estimated bool   `json:"estimated"` // false when the default gas was assumed
+	PaidBy    string `json:"paid_by"`   // user or operator
+}
+
+// RetryableCost is what a deposit's retryable ticket costs with the gateway's
+// configured parameters (L1Gateway.setGasParameters)
+type RetryableCost struct {
+	MaxSubmissionCost      string `json:"max_submission_cost"`
+	RequiredSubmissionCost string `json:"required_submission_cost"` // by the inbox at the current L1 base fee
+	MaxGas                 uint64 `json:"max_gas"`
+	GasPriceBid            string `json:"gas_price_bid"`
+	L2GasPrice             string `json:"l2_gas_price"`
+	MaxExecutionWei        string `json:"max_execution_wei"`      // maxGas × gasPriceBid, paid up front
+	ExpectedExecutionWei   string `json:"expected_execution_wei"` // at the current L2 price; the rest is refunded
+}
+
+// Quote is the estimated cost and arrival time of a bridge transfer
+type Quote struct {
+	Direction string `json:"direction"`
+	Token     string `json:"token"`
+	Amount    string `json:"amount"`
+
+	SourceTx    GasCost        `json:"source_tx"`
+	Retryable   *RetryableCost `json:"retryable,omitempty"`    // L1→L2
+	L1Execution *GasCost       `json:"l1_execution,omitempty"` // L2→L1 outbox execution
+
+	ProtocolFeeWei   string `json:"protocol_fee_wei"`   // the gateway charges no fee of its own
+	RequiredValueWei string `json:"required_value_wei"` // ETH sent along with the source transaction
+	TotalCostWei     string `json:"total_cost_wei"`     // everything the user pays, at current prices
+
+	ExpectedArrivalSeconds   float64  `json:"expected_arrival_seconds"` // recent median, or the advertised time
+	AdvertisedArrivalSeconds float64  `json:"advertised_arrival_seconds"`
+	Warnings                 []string `json:"warnings,omitempty"`
+	QuotedAt                 int64    `json:"quoted_at"`
+}
+
+// Quoter prices bridge transfers from current gas prices, the L1 gateway's
+// retryable parameters and recent confirmation times
+type Quoter struct {
+	clients      *chain.DualClientManager
+	gateway      *l1.L1Gateway
+	gatewayAddr  common.Address
+	database     *sql.DB
+	targets      SLATargets
+	autoFinalize bool // the operator executes withdrawals in the outbox
+}
+
+// NewQuoter creates a quoter for the gateway at l1Gateway
+func NewQuoter(database *sql.DB, clients *chain.DualClientManager, l1Gateway string, targets SLATargets, autoFinalize bool) (*Quoter, error) {
+	addr := common.HexToAddress(l1Gateway)
+	gateway, err := l1.NewL1Gateway(addr, clients.L1Client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate L1Gateway contract: %w", err)
+	}
+	return &Quoter{
+		clients:      clients,
+		gateway:      gateway,
+		gatewayAddr:  addr,
+		database:     database,
+		targets:      targets,
+		autoFinalize: autoFinalize,
+	}, nil
+}
+
+// Quote estimates a transfer of amount of token in direction by user. user
+// may be the zero address, in which case gas is not estimated for an account.
+func (q *Quoter) Quote(ctx context.Context, direction string, token, user common.Address, amount *big.Int) (*Quote, error) {
+	opts := &bind.CallOpts{Context: ctx}
+	collateral, err := q.gateway.CollateralToken(opts)
+	if err != nil {
+		return nil, fmt.Errorf("collateral token: %w", err)
+	}
+	if token != collateral {
+		return nil, fmt.Errorf("%w: %s (bridged token is %s)", ErrUnsupportedToken, token.Hex(), collateral.Hex())
+	}
+
+	quote := &Quote{
+		Direction:      direction,
+		Token:          token.Hex(),
+		Amount:         amount.String(),
+		ProtocolFeeWei: "0",
+		QuotedAt:       time.Now().Unix(),
+	}
+
+	var total *big.Int
+	switch direction {
+	case models.BridgeL1ToL2:
+		total, err = q.quoteDeposit(ctx, quote, user, amount)
+	case models.BridgeL2ToL1:
+		total, err = q.quoteWithdrawal(ctx, quote)
+	default:
+		return nil, fmt.Errorf("unknown direction %q", direction)
+	}
+	if err != nil {
+		return nil, err
+	}
+	quote.TotalCostWei = total.String()
+
+	target := q.targets.target(direction)
+	quote.AdvertisedArrivalSeconds = target.Seconds()
+	quote.ExpectedArrivalSeconds = target.Seconds()
+	if sla, err := directionSLA(q.database, direction, time.Now().AddDate(0, 0, -7), target); err == nil && sla.Confirmed > 0 {
+		quote.ExpectedArrivalSeconds = sla.Latency.P50
+	}
+	return quote, nil
+}
+
+// quoteDeposit prices depositToL2 on L1 and the retryable it creates. The
+// user pays the L1 transaction and the ETH the gateway requires up front.
+func (q *Quoter) quoteDeposit(ctx context.Context, quote *Quote, user common.Address, amount *big.Int) (*big.Int, error) {
+	opts := &bind.CallOpts{Context: ctx}
+	submission, err := q.gateway.MaxSubmissionCost(opts)
+	if err != nil {
+		return nil, fmt.Errorf("max submission cost: %w", err)
+	}
+	maxGas, err := q.gateway.MaxGas(opts)
+	if err != nil {
+		return nil, fmt.Errorf("max gas: %w", err)
+	}
+	bid, err := q.gateway.GasPriceBid(opts)
+	if err != nil {
+		return nil, fmt.Errorf("gas price bid: %w", err)
+	}
+	required, err := q.gateway.CalculateRequiredEth(opts)
+	if err != nil {
+		return nil, fmt.Errorf("required eth: %w", err)
+	}
+
+	l1Price, err := q.clients.GetL1GasPrice(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("l1 gas price: %w", err)
+	}
+	l2Price, err := q.clients.GetL2GasPrice(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("l2 gas price: %w", err)
+	}
+
+	// depositToL2 reverts in estimation until the allowance is set; fall back
+	// to the default and add the approval
+	gas, estimated := uint64(defaultDepositGas+defaultApproveGas), false
+	if user != (common.Address{}) {
+		gatewayABI, err := l1.L1GatewayMetaData.GetAbi()
+		if err != nil {
+			return nil, err
+		}
+		data, err := gatewayABI.Pack("depositToL2", amount)
+		if err != nil {
+			return nil, err
+		}
+		estimate, err := q.clients.L1Client.EstimateGas(ctx, ethereum.CallMsg{
+			From: user, To: &q.gatewayAddr, Value: required, Data: data,
+		})
+		if err == nil {
+			gas, estimated = estimate, true
+		}
+	}
+	var l1Cost *big.Int
+	quote.SourceTx, l1Cost = gasCost("l1", gas, l1Price, estimated, "user")
+
+	rc := &RetryableCost{
+		MaxSubmissionCost:    submission.String(),
+		MaxGas:               maxGas.Uint64(),
+		GasPriceBid:          bid.String(),
+		L2GasPrice:           l2Price.String(),
+		MaxExecutionWei:      new(big.Int).Mul(maxGas, bid).String(),
+		ExpectedExecutionWei: new(big.Int).Mul(maxGas, minBig(bid, l2Price)).String(),
+	}
+	if bid.Cmp(l2Price) < 0 {
+		quote.Warnings = append(quote.Warnings, fmt.Sprintf(
+			"gateway gas price bid %s is below the L2 gas price %s; the auto-redeem will fail and the ticket needs a manual redeem", bid, l2Price))
+	}
+	if fee, err := q.requiredSubmissionCost(ctx); err == nil {
+		rc.RequiredSubmissionCost = fee.String()
+		if submission.Cmp(fee) < 0 {
+			quote.Warnings = append(quote.Warnings, fmt.Sprintf(
+				"gateway max submission cost %s is below the %s the inbox requires; deposits will revert", submission, fee))
+		}
+	}
+	quote.Retryable = rc
+	quote.RequiredValueWei = required.String()
+
+	// The excess of the execution bid over the actual L2 price is refunded
+	refund := new(big.Int).Mul(maxGas, new(big.Int).Sub(bid, minBig(bid, l2Price)))
+	total := new(big.Int).Sub(required, refund)
+	return total.Add(total, l1Cost), nil
+}
+
+// quoteWithdrawal prices the L2 withdrawal and the outbox execution on L1,
+// which the operator pays when auto-finalize is on
+func (q *Quoter) quoteWithdrawal(ctx context.Context, quote *Quote) (*big.Int, error) {
+	l1Price, err := q.clients.GetL1GasPrice(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("l1 gas price: %w", err)
+	}
+	l2Price, err := q.clients.GetL2GasPrice(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("l2 gas price: %w", err)
+	}
+
+	var total *big.Int
+	quote.SourceTx, total = gasCost("l2", defaultWithdrawGas, l2Price, false, "user")
+	payer := "user"
+	if q.autoFinalize {
+		payer = "operator"
+	}
+	execution, executionCost := gasCost("l1", defaultExecuteGas, l1Price, false, payer)
+	quote.L1Execution = &execution
+	quote.RequiredValueWei = "0"
+
+	if payer == "user" {
+		total.Add(total, executionCost)
+	}
+	return total, nil
+}
+
+// requiredSubmissionCost asks the gateway's inbox what a deposit's retryable
+// submission costs at the current L1 base fee
+func (q *Quoter) requiredSubmissionCost(ctx context.Context) (*big.Int, error) {
+	inbox, err := q.gateway.Inbox(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, err
+	}
+	head, err := q.clients.L1Client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if head.BaseFee == nil {
+		return nil, errors.New("l1 has no base fee")
+	}
+	data, err := inboxABI.Pack("calculateRetryableSubmissionFee", big.NewInt(finalizeDepositDataLength), head.BaseFee)
+	if err != nil {
+		return nil, err
+	}
+	out, err := q.clients.L1Client.CallContract(ctx, ethereum.CallMsg{To: &inbox, Data: data}, nil)
+	if err != nil {
+		return nil, err
+	}
+	values, err := inboxABI.Unpack("calculateRetryableSubmissionFee", out)
+	if err != nil {
+		return nil, err
+	}
+	return values[0].(*big.Int), nil
+}
+
+// gasCost prices gas at price, returning the cost also as a number
+func gasCost(chainName string, gas uint64, price *big.Int, estimated bool, payer string) (GasCost, *big.Int) {
+	cost := new(big.Int).Mul(new(big.Int).SetUint64(gas), price)
+	return GasCost{
+		Chain:     chainName,
+		GasLimit:  gas,
+		GasPrice:  price.String(),
+		CostWei:   cost.String(),
+		Estimated: estimated,
+		PaidBy:    payer,
+	}, cost
+}
+
+// minBig returns the smaller of a and b
+func minBig(a, b *big.Int) *big.Int {
+	if a.Cmp(b) <= 0 {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,299 @@
+package bridge
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"loyalty-points-system/internal/blockchain"
+	"loyalty-points-system/internal/db"
+	"loyalty-points-system/internal/metrics"
+)
+
+// redeemSelector is ArbRetryableTx.redeem(bytes32 ticketId)
+var redeemSelector = crypto.Keccak256([]byte("redeem(bytes32)"))[:4]
+
+// errSpendCapReached stops a round when the next redeem would exceed the budget
+var errSpendCapReached = errors.New("redeem spend cap reached")
+
+// RedeemerConfig bounds what the redeemer may spend
+type RedeemerConfig struct {
+	MaxSpendPerHour *big.Int      // wei of operator gas per rolling hour
+	Interval        time.Duration // how often unredeemed tickets are looked up
+	MaxAttempts     int           // redeems per ticket before giving up
+	GasMargin       float64       // multiplier over the gas estimate
+	ReceiptTimeout  time.Duration // how long to wait for a redeem to be mined
+}
+
+// Redeemer redeems retryable tickets whose auto-redeem failed on L2, so
+// deposits do not sit in the retry buffer until the user redeems them.
+// Tickets are leased from the redeem queue in priority order, so several
+// redeemers can run side by side. The operator's gas spend over the last hour
+// is capped: each redeem reserves its cost in bridge_redeems before it is
+// signed, and redeemers sharing the operator key take nonces one at a time.
+type Redeemer struct {
+	database *sql.DB
+	client   *blockchain.Client
+	queue    *MessageQueue
+	config   RedeemerConfig
+	mu       sync.RWMutex
+	running  bool
+}
+
+// NewRedeemer creates a redeemer sending from the client's L2 operator key.
+// MaxSpendPerHour is required: the redeemer never runs without a budget.
+func NewRedeemer(database *sql.DB, client *blockchain.Client, config RedeemerConfig) (*Redeemer, error) {
+	if config.MaxSpendPerHour == nil || config.MaxSpendPerHour.Sign() <= 0 {
+		return nil, fmt.Errorf("redeemer needs a positive MaxSpendPerHour, got %v", config.MaxSpendPerHour)
+	}
+	if config.Interval <= 0 {
+		config.Interval = time.Minute
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 3
+	}
+	if config.GasMargin < 1 {
+		config.GasMargin = 1.2
+	}
+	if config.ReceiptTimeout <= 0 {
+		config.ReceiptTimeout = 2 * time.Minute
+	}
+	return &Redeemer{
+		database: database,
+		client:   client,
+		queue:    NewMessageQueue(database, QueueRedeem),
+		config:   config,
+	}, nil
+}
+
+// Start redeems unredeemed tickets until ctx is cancelled
+func (r *Redeemer) Start(ctx context.Context) error {
+	r.mu.Lock()
+	if r.running {
+		r.mu.Unlock()
+		return nil
+	}
+	r.running = true
+	r.mu.Unlock()
+
+	log.Printf("🎟️  [Bridge Redeemer] Started (operator %s, cap %s wei/hour)",
+		r.client.FromAddress.Hex(), r.config.MaxSpendPerHour)
+
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("🛑 [Bridge Redeemer] Stopped")
+			return nil
+		case <-ticker.C:
+			if err := r.redeemPending(ctx); err != nil {
+				log.Printf("❌ [Bridge Redeemer] Error redeeming tickets: %v", err)
+			}
+		}
+	}
+}
+
+// Stop halts the redeemer
+func (r *Redeemer) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.running = false
+}
+
+// redeemPending queues the current candidates and redeems them in priority
+// order until the queue is empty or the hourly budget is used up
+func (r *Redeemer) redeemPending(ctx context.Context) error {
+	candidates, err := db.GetRetryableRedeemCandidates(r.database, r.config.MaxAttempts)
+	if err != nil {
+		return err
+	}
+
+	// Queue the candidates at their priority; items that stopped being
+	// candidates (redeemed, expired, in flight) are dropped when leased
+	tickets := make(map[string]string, len(candidates))
+	for _, c := range candidates {
+		tickets[c.Message.MessageHash] = c.TicketID
+		if err := r.queue.Enqueue(c.Message, r.queue.determinePriority(c.Message)); err != nil {
+			return err
+		}
+	}
+	if len(tickets) == 0 {
+		return nil
+	}
+
+	spent, err := db.GetBridgeRedeemSpend(r.database, time.Now().Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+	metrics.BridgeRedeemSpendWei.Set(weiFloat(spent))
+
+	// Lease one ticket at a time so other redeemers can share the queue; the
+	// lease outlives a redeem waiting for its receipt
+	visibility := r.config.ReceiptTimeout + time.Minute
+	for ctx.Err() == nil {
+		items, err := r.queue.Lease(1, visibility)
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		msg := items[0].Message
+
+		ticket, ok := tickets[msg.MessageHash]
+		if !ok {
+			if err := r.ack(msg.MessageHash); err != nil {
+				return err
+			}
+			continue
+		}
+
+		err = r.redeem(ctx, msg.MessageHash, ticket)
+		if errors.Is(err, errSpendCapReached) {
+			log.Printf("💸 [Bridge Redeemer] %v, tickets wait", err)
+			metrics.BridgeRedeemsTotal.WithLabelValues("capped").Inc()
+			return r.queue.Release(msg.MessageHash, 0)
+		}
+		if err != nil {
+			log.Printf("❌ [Bridge Redeemer] Redeem of %s failed: %v", msg.MessageHash, err)
+		}
+		if err := r.ack(msg.MessageHash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ack removes a leased ticket from the queue
+func (r *Redeemer) ack(messageHash string) error {
+	removed, err := r.queue.Remove(messageHash)
+	if err == nil && !removed {
+		log.Printf("⚠️  [Bridge Redeemer] Lease on %s expired before it was acknowledged", messageHash)
+	}
+	return err
+}
+
+// redeem estimates, reserves, submits and waits for one redeem. The
+// reservation stays charged against the budget until the receipt replaces it
+// with the gas paid; a redeem that never went out is charged nothing.
+func (r *Redeemer) redeem(ctx context.Context, messageHash, ticketID string) error {
+	attempt := &db.BridgeRedeem{MessageHash: messageHash, TicketID: ticketID, GasPrice: "0", CostWei: "0"}
+	fail := func(err error) error {
+		attempt.Status, attempt.ErrorMsg = "failed", err.Error()
+		metrics.BridgeRedeemsTotal.WithLabelValues("failed").Inc()
+		if _, dbErr := db.InsertBridgeRedeem(r.database, attempt); dbErr != nil {
+			log.Printf("⚠️  [Bridge Redeemer] Failed to record redeem of %s: %v", messageHash, dbErr)
+		}
+		return err
+	}
+
+	opts, err := r.client.GetL2TransactOpts(ctx)
+	if err != nil {
+		return fail(err)
+	}
+	ticket := common.HexToHash(ticketID)
+	data := append(append([]byte{}, redeemSelector...), ticket.Bytes()...)
+
+	// Estimation fails when the ticket was redeemed or expired meanwhile
+	estimate, err := r.client.L2Client.EstimateGas(ctx, ethereum.CallMsg{From: opts.From, To: &arbRetryableTx, Data: data})
+	if err != nil {
+		return fail(fmt.Errorf("estimate gas: %w", err))
+	}
+	gasLimit := uint64(float64(estimate) * r.config.GasMargin)
+	reserved := new(big.Int).Mul(new(big.Int).SetUint64(gasLimit), opts.GasPrice)
+
+	// Reserve the cost before signing, so concurrent redeemers see it
+	attempt.GasLimit = gasLimit
+	attempt.GasPrice = opts.GasPrice.String()
+	attempt.CostWei = reserved.String()
+	id, spent, err := db.ReserveBridgeRedeem(ctx, r.database, attempt, time.Now().Add(-time.Hour), r.config.MaxSpendPerHour)
+	if err != nil {
+		return fail(fmt.Errorf("reserve: %w", err))
+	}
+	if id == 0 {
+		metrics.BridgeRedeemSpendWei.Set(weiFloat(spent))
+		return fmt.Errorf("%w: %s of %s wei spent in the last hour, next redeem reserves %s",
+			errSpendCapReached, spent, r.config.MaxSpendPerHour, reserved)
+	}
+	metrics.BridgeRedeemSpendWei.Set(weiFloat(new(big.Int).Add(spent, reserved)))
+
+	// The operator key is shared, so nonces are taken and used one redeem at a time
+	var tx *types.Transaction
+	err = db.WithSessionLock(ctx, r.database, "bridge_redeem_nonce:"+opts.From.Hex(), func() error {
+		nonce, err := r.client.L2Client.PendingNonceAt(ctx, opts.From)
+		if err != nil {
+			return fmt.Errorf("nonce: %w", err)
+		}
+		tx, err = opts.Signer(opts.From, types.NewTransaction(nonce, arbRetryableTx, new(big.Int), gasLimit, opts.GasPrice, data))
+		if err != nil {
+			return fmt.Errorf("sign: %w", err)
+		}
+		if err := r.client.L2Client.SendTransaction(ctx, tx); err != nil {
+			return fmt.Errorf("send: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		// Nothing went out; release the reservation
+		metrics.BridgeRedeemsTotal.WithLabelValues("failed").Inc()
+		if dbErr := db.FinishBridgeRedeem(r.database, id, "failed", "0", err.Error()); dbErr != nil {
+			log.Printf("⚠️  [Bridge Redeemer] Failed to record redeem of %s: %v", messageHash, dbErr)
+		}
+		return err
+	}
+	attempt.TxHash = tx.Hash().Hex()
+	if err := db.SetBridgeRedeemTx(r.database, id, attempt.TxHash); err != nil {
+		log.Printf("⚠️  [Bridge Redeemer] Failed to record redeem %s of %s: %v", attempt.TxHash, messageHash, err)
+	}
+	log.Printf("🎟️  [Bridge Redeemer] Redeeming ticket %s of %s in %s (gas %d)", ticketID, messageHash, attempt.TxHash, gasLimit)
+
+	waitCtx, cancel := context.WithTimeout(ctx, r.config.ReceiptTimeout)
+	defer cancel()
+	receipt, err := r.client.WaitForL2Transaction(waitCtx, tx)
+	if receipt == nil {
+		// Still pending; the reservation stays on the books and the ticket is
+		// not retried until the in-flight window passes
+		metrics.BridgeRedeemsTotal.WithLabelValues("submitted").Inc()
+		return fmt.Errorf("wait for redeem %s: %w", attempt.TxHash, err)
+	}
+
+	price := receipt.EffectiveGasPrice
+	if price == nil {
+		price = opts.GasPrice
+	}
+	paid := new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), price)
+	status, errMsg := "mined", ""
+	if err != nil {
+		status, errMsg = "reverted", err.Error()
+	}
+	metrics.BridgeRedeemsTotal.WithLabelValues(status).Inc()
+	if dbErr := db.FinishBridgeRedeem(r.database, id, status, paid.String(), errMsg); dbErr != nil {
+		log.Printf("⚠️  [Bridge Redeemer] Failed to record outcome of %s: %v", attempt.TxHash, dbErr)
+	}
+	if err != nil {
+		return err
+	}
+
+	// A mined redeem only schedules the retry; the bridge monitor confirms
+	// the message once the retry succeeded
+	log.Printf("✅ [Bridge Redeemer] Redeem %s of %s mined (paid %s wei)", attempt.TxHash, messageHash, paid)
+	return nil
+}
+
+// weiFloat converts wei to a float for gauges
+func weiFloat(wei *big.Int) float64 {
+	f, _ := new(big.Float).SetInt(wei).Float64()
+	return f
+}
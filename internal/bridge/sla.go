@@ -0,0 +1,154 @@
+package bridge
+
+import (
+	"database/sql"
+	"time"
+
+	"loyalty-points-system/internal/models"
+)
+
+// SLATargets are the advertised confirmation times per direction
+type SLATargets struct {
+	L1ToL2 time.Duration
+	L2ToL1 time.Duration
+}
+
+// target returns the advertised time of a direction
+func (t SLATargets) target(direction string) time.Duration {
+	if direction == models.BridgeL2ToL1 {
+		return t.L2ToL1
+	}
+	return t.L1ToL2
+}
+
+// Percentiles summarises a latency distribution in seconds
+type Percentiles struct {
+	Count int     `json:"count"`
+	P50   float64 `json:"p50"`
+	P95   float64 `json:"p95"`
+	P99   float64 `json:"p99"`
+}
+
+// DirectionSLA is the SLA report of one bridge direction
+type DirectionSLA struct {
+	Direction     string  `json:"direction"`
+	TargetSeconds float64 `json:"target_seconds"`
+	Confirmed     int     `json:"confirmed"`
+	Failed        int     `json:"failed"`
+	Open          int     `json:"open"`
+	WithinTarget  int     `json:"within_target"` // confirmed no later than the target
+	OpenBreached  int     `json:"open_breached"` // still open and already past the target
+	Attainment    float64 `json:"attainment"`    // share of settled messages confirmed within the target
+
+	Latency Percentiles `json:"latency"` // initiated → confirmed
+	// Time-in-state of confirmed messages; messages confirmed straight from
+	// initiated spend no time pending
+	InitiatedTime Percentiles `json:"initiated_time"` // initiated → pending
+	PendingTime   Percentiles `json:"pending_time"`   // pending → confirmed
+}
+
+// DailyLatency is the confirmation latency of the messages of one direction
+// confirmed on one (UTC) day
+type DailyLatency struct {
+	Day       string `json:"day"` // YYYY-MM-DD
+	Direction string `json:"direction"`
+	Percentiles
+}
+
+// SLAReport covers the messages initiated (directions) or confirmed (daily)
+// since a point in time
+type SLAReport struct {
+	Since      time.Time       `json:"since"`
+	Directions []*DirectionSLA `json:"directions"`
+	Daily      []*DailyLatency `json:"daily"`
+}
+
+// GetSLA computes latency percentiles, time-in-state breakdowns and target
+// attainment per direction, and latency percentiles per direction and day,
+// from bridge_messages
+func GetSLA(database *sql.DB, since time.Time, targets SLATargets) (*SLAReport, error) {
+	report := &SLAReport{Since: since, Directions: []*DirectionSLA{}, Daily: []*DailyLatency{}}
+
+	for _, direction := range []string{models.BridgeL1ToL2, models.BridgeL2ToL1} {
+		sla, err := directionSLA(database, direction, since, targets.target(direction))
+		if err != nil {
+			return nil, err
+		}
+		report.Directions = append(report.Directions, sla)
+	}
+
+	rows, err := database.Query(`
+		SELECT to_char(date_trunc('day', confirmed_at), 'YYYY-MM-DD'), direction, COUNT(*),
+		       percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM confirmed_at - initiated_at)::float8),
+		       percentile_cont(0.95) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM confirmed_at - initiated_at)::float8),
+		       percentile_cont(0.99) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM confirmed_at - initiated_at)::float8)
+		FROM bridge_messages
+		WHERE status = 'confirmed' AND confirmed_at >= $1
+		GROUP BY 1, 2
+		ORDER BY 1, 2
+	`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		d := &DailyLatency{}
+		if err := rows.Scan(&d.Day, &d.Direction, &d.Count, &d.P50, &d.P95, &d.P99); err != nil {
+			return nil, err
+		}
+		report.Daily = append(report.Daily, d)
+	}
+	return report, rows.Err()
+}
+
+// directionSLA reports on the messages of one direction initiated since a point in time
+func directionSLA(database *sql.DB, direction string, since time.Time, target time.Duration) (*DirectionSLA, error) {
+	sla := &DirectionSLA{Direction: direction, TargetSeconds: target.Seconds()}
+
+	err := database.QueryRow(`
+		WITH m AS (
+			SELECT status, initiated_at,
+			       EXTRACT(EPOCH FROM confirmed_at - initiated_at)::float8 AS total,
+			       EXTRACT(EPOCH FROM COALESCE(pending_at, confirmed_at) - initiated_at)::float8 AS initiated,
+			       EXTRACT(EPOCH FROM confirmed_at - COALESCE(pending_at, confirmed_at))::float8 AS pending
+			FROM bridge_messages
+			WHERE direction = $1 AND initiated_at >= $2
+		), c AS (
+			SELECT * FROM m WHERE status = 'confirmed' AND total IS NOT NULL
+		)
+		SELECT
+			(SELECT COUNT(*) FROM c),
+			(SELECT COUNT(*) FROM m WHERE status = 'failed'),
+			(SELECT COUNT(*) FROM m WHERE status IN ('initiated', 'pending')),
+			(SELECT COUNT(*) FROM c WHERE total <= $3::float8),
+			(SELECT COUNT(*) FROM m WHERE status IN ('initiated', 'pending')
+			    AND initiated_at < NOW() - make_interval(secs => $3::float8)),
+			COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY total), 0),
+			COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY total), 0),
+			COALESCE(percentile_cont(0.99) WITHIN GROUP (ORDER BY total), 0),
+			COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY initiated), 0),
+			COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY initiated), 0),
+			COALESCE(percentile_cont(0.99) WITHIN GROUP (ORDER BY initiated), 0),
+			COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY pending), 0),
+			COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY pending), 0),
+			COALESCE(percentile_cont(0.99) WITHIN GROUP (ORDER BY pending), 0)
+		FROM c
+	`, direction, since, target.Seconds()).Scan(
+		&sla.Confirmed, &sla.Failed, &sla.Open, &sla.WithinTarget, &sla.OpenBreached,
+		&sla.Latency.P50, &sla.Latency.P95, &sla.Latency.P99,
+		&sla.InitiatedTime.P50, &sla.InitiatedTime.P95, &sla.InitiatedTime.P99,
+		&sla.PendingTime.P50, &sla.PendingTime.P95, &sla.PendingTime.P99,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sla.Latency.Count = sla.Confirmed
+	sla.InitiatedTime.Count = sla.Confirmed
+	sla.PendingTime.Count = sla.Confirmed
+	if settled := sla.Confirmed + sla.Failed; settled > 0 {
+		sla.Attainment = float64(sla.WithinTarget) / float64(settled)
+	}
+	return sla, nil
+}
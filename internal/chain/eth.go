@@ -15,6 +15,8 @@ import (
   "github.com/ethereum/go-ethereum/ethclient"
   k "github.com/segmentio/kafka-go"
 
+  "loyalty-points-system/internal/kafka"
+  "loyalty-points-system/internal/listener"
   "loyalty-points-system/internal/models"
 )
 
@@ -24,6 +26,7 @@ type ChainCfg struct {
   TokenAddress   string `json:"token_address"`
   StakingAddress string `json:"staking_address"`
   Confirmations  int    `json:"confirmations"`
+  StartBlock     uint64 `json:"start_block"` // first block to backfill without a checkpoint (0 = head)
 }
 type Writer interface{ WriteMessages(ctx context.Context, msgs ...k.Message) error }
 
@@ -42,6 +45,11 @@ type Worker struct {
   pending   map[uint64][]models.BalanceEvent
   pendingMu sync.Mutex
   head      uint64
+
+  checkpoints  listener.CheckpointStore
+  cursor       *listener.BlockCursor
+  contracts    []common.Address
+  backfilledTo uint64
 }
 
 func NewWorker(cfg ChainCfg, w Writer) *Worker {
@@ -63,12 +71,22 @@ func NewWorker(cfg ChainCfg, w Writer) *Worker {
   }
 }
 
+// WithCheckpoints enables backfill from, and checkpointing to, the given store
+func (wk *Worker) WithCheckpoints(store listener.CheckpointStore) *Worker {
+  wk.checkpoints = store
+  return wk
+}
+
 func (wk *Worker) Start(ctx context.Context) error {
   var err error
   wk.client, err = ethclient.DialContext(ctx, wk.cfg.WSSURL)
   if err != nil { return err }
   log.Printf("🔌 [%s] connected", wk.cfg.Name)
 
+  chainID, err := wk.client.ChainID(ctx)
+  if err != nil { return err }
+  wk.cursor = listener.NewBlockCursor(wk.checkpoints, wk.cfg.Name, chainID.Int64(), wk.cfg.StartBlock, 0)
+
   headers := make(chan *types.Header, 32)
   subHead, err := wk.client.SubscribeNewHead(ctx, headers)
   if err != nil { return err }
@@ -80,6 +98,7 @@ func (wk *Worker) Start(ctx context.Context) error {
     q := ethereum.FilterQuery{Addresses: []common.Address{addr}, Topics: [][]common.Hash{{wk.transferTopic}}}
     subLogs, err = wk.client.SubscribeFilterLogs(ctx, q, logsCh)
     if err != nil { return err }
+    wk.contracts = append(wk.contracts, addr)
     log.Printf("👂 [%s] subscribed Transfer for %s", wk.cfg.Name, addr.Hex())
   } else {
     log.Printf("ℹ️  [%s] token_address not set; skip Transfer subscription", wk.cfg.Name)
@@ -92,6 +111,7 @@ func (wk *Worker) Start(ctx context.Context) error {
     }
     sub2, err2 := wk.client.SubscribeFilterLogs(ctx, q2, logsCh)
     if err2 != nil { return err2 }
+    wk.contracts = append(wk.contracts, wk.stakingAddr)
     log.Printf("👂 [%s] subscribed Staking logs for %s", wk.cfg.Name, wk.stakingAddr.Hex())
     go func(){ <-sub2.Err(); log.Printf("❌ [%s] staking logs sub err", wk.cfg.Name) }()
   } else {
@@ -105,6 +125,13 @@ func (wk *Worker) Start(ctx context.Context) error {
   }
   conf := wk.cfg.Confirmations; if conf <= 0 { conf = 6 }
 
+  // catch up from checkpoints; head is read after subscribing so nothing is skipped
+  head, err := wk.client.BlockNumber(ctx)
+  if err != nil { return err }
+  if err := wk.cursor.CatchUp(ctx, wk.client, wk.contracts, head, wk.onLog); err != nil { return err }
+  wk.backfilledTo = head
+  wk.onHead(head, conf)
+
   go func() {
     for {
       select {
@@ -115,6 +142,7 @@ func (wk *Worker) Start(ctx context.Context) error {
       case err := <-logsErr:
         if err!=nil { log.Printf("❌ [%s] logs sub err: %v", wk.cfg.Name, err); return }
       case lg := <-logsCh:
+        if lg.BlockNumber <= wk.backfilledTo { continue }
         wk.onLog(lg)
       case <-ctx.Done():
         return
@@ -140,6 +168,7 @@ func (wk *Worker) onLog(lg types.Log) {
     evt := models.BalanceEvent{
       UserAddress: user, Amount: amt, EventType: etype,
       TxHash: lg.TxHash.Hex(), Chain: wk.cfg.Name, BlockNumber: int64(lg.BlockNumber),
+      TxIndex: lg.TxIndex, LogIndex: lg.Index, Sequence: models.EventSequence(lg.BlockNumber, lg.Index),
       Confirmed: false, Timestamp: time.Now().Unix(),
     }
     wk.pendingMu.Lock(); wk.pending[lg.BlockNumber] = append(wk.pending[lg.BlockNumber], evt); wk.pendingMu.Unlock()
@@ -154,9 +183,10 @@ func (wk *Worker) onLog(lg types.Log) {
   amt  := new(big.Int).SetBytes(lg.Data).String()
   txHash := lg.TxHash.Hex()
   block  := lg.BlockNumber
+  seq    := models.EventSequence(block, lg.Index)
   evts := []models.BalanceEvent{
-    {UserAddress: from.Hex(), Amount: amt, EventType: "transfer_out", TxHash: txHash, Chain: wk.cfg.Name, BlockNumber: int64(block), Confirmed: false, Timestamp: time.Now().Unix()},
-    {UserAddress: to.Hex(),   Amount: amt, EventType: "transfer_in",  TxHash: txHash, Chain: wk.cfg.Name, BlockNumber: int64(block), Confirmed: false, Timestamp: time.Now().Unix()},
+    {UserAddress: from.Hex(), Amount: amt, EventType: "transfer_out", TxHash: txHash, Chain: wk.cfg.Name, BlockNumber: int64(block), TxIndex: lg.TxIndex, LogIndex: lg.Index, Sequence: seq, Confirmed: false, Timestamp: time.Now().Unix()},
+    {UserAddress: to.Hex(),   Amount: amt, EventType: "transfer_in",  TxHash: txHash, Chain: wk.cfg.Name, BlockNumber: int64(block), TxIndex: lg.TxIndex, LogIndex: lg.Index, Sequence: seq, Confirmed: false, Timestamp: time.Now().Unix()},
   }
   wk.pendingMu.Lock(); wk.pending[block] = append(wk.pending[block], evts...); wk.pendingMu.Unlock()
   log.Printf("🕒 [%s] pending Transfer %s blk=%d from=%s to=%s amt=%s", wk.cfg.Name, txHash, block, from.Hex(), to.Hex(), amt)
@@ -173,15 +203,18 @@ func (wk *Worker) onHead(n uint64, conf int) {
   wk.pending = keep
   wk.pendingMu.Unlock()
 
-  if len(out) == 0 { return }
+  if n < uint64(conf) { return }
   ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second); defer cancel()
+  published := true
   for _, evt := range out {
     evt.Confirmed = true
     b, _ := json.Marshal(evt)
-    if err := wk.w.WriteMessages(ctx, k.Message{Value: b}); err != nil {
+    if err := wk.w.WriteMessages(ctx, k.Message{Key: kafka.UserKey(evt.UserAddress), Value: b}); err != nil {
       log.Printf("write kafka err: %v", err)
+      published = false
     } else {
       log.Printf("✅ [%s] confirmed tx=%s block=%d", wk.cfg.Name, evt.TxHash, evt.BlockNumber)
     }
   }
+  if published { wk.cursor.Commit(ctx, wk.contracts, n-uint64(conf)) }
 }
@@ -0,0 +1,209 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Chain kinds: which listener (and Kafka topic) a chain is served by
+const (
+	ChainKindL1 = "l1"
+	ChainKindL2 = "l2"
+)
+
+// ChainConfig describes one chain the listener service watches
+type ChainConfig struct {
+	Name            string         `yaml:"name"` // unique; names the listener on /status and in metrics
+	Kind            string         `yaml:"kind"` // l1 or l2
+	ChainID         int64          `yaml:"chain_id"`
+	RPCURL          string         `yaml:"rpc_url"`
+	WSSURL          string         `yaml:"wss_url"`
+	Confirmations   int            `yaml:"confirmations"`
+	Finality        string         `yaml:"finality"`          // depth (default), safe, finalized or l1_batch
+	StartBlock      int64          `yaml:"start_block"`       // first block to backfill without a checkpoint (0 = head)
+	PollIntervalSec int            `yaml:"poll_interval_sec"` // 0 = LISTENER_POLL_INTERVAL_SEC
+	Parent          string         `yaml:"parent"`            // L2 only: name of the L1 it settles to; enables the bridge listener
+	Contracts       ChainContracts `yaml:"contracts"`
+}
+
+// ChainContracts are the contract addresses watched on a chain. L1 chains use
+// the first block, L2 chains the rest; gateway is used on both sides of a bridge.
+type ChainContracts struct {
+	CollateralVault string `yaml:"collateral_vault"`
+	StateRegistry   string `yaml:"state_registry"`
+	LoyaltyUSD      string `yaml:"loyalty_usd"`
+	Gateway         string `yaml:"gateway"`
+
+	IntegratedVault     string `yaml:"integrated_vault"`
+	StateAggregator     string `yaml:"state_aggregator"`
+	AaveAdapter         string `yaml:"aave_adapter"`
+	CompoundAdapter     string `yaml:"compound_adapter"`
+	UniswapAdapter      string `yaml:"uniswap_adapter"`
+	RWAFactory          string `yaml:"rwa_factory"`
+	RWAMarketplace      string `yaml:"rwa_marketplace"`
+	RWAYieldDistributor string `yaml:"rwa_yield_distributor"`
+	RWACompliance       string `yaml:"rwa_compliance"`
+	RWAValuation        string `yaml:"rwa_valuation"`
+	RWAGovernance       string `yaml:"rwa_governance"`
+
+	// Arbitrum rollup contracts on the parent chain; the bridge monitor
+	// resolves retryable tickets and outbox messages through them
+	Inbox  string `yaml:"inbox"`
+	Outbox string `yaml:"outbox"`
+}
+
+// finalityModes are the accepted finality policies; l1_batch needs an L2 with a parent
+var finalityModes = map[string]bool{"": true, "depth": true, "safe": true, "finalized": true, "l1_batch": true}
+
+// chainsFile is the layout of CHAINS_FILE
+type chainsFile struct {
+	Chains []ChainConfig `yaml:"chains"`
+}
+
+// LoadChains reads a chains file. YAML is a superset of JSON, so both formats
+// are accepted; unknown keys are rejected to catch typos.
+func LoadChains(path string) ([]ChainConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read chains file: %w", err)
+	}
+
+	var file chainsFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("parse chains file %s: %w", path, err)
+	}
+	if err := validateChains(file.Chains); err != nil {
+		return nil, fmt.Errorf("chains file %s: %w", path, err)
+	}
+	return file.Chains, nil
+}
+
+// validateChains checks that names and chain IDs are unique and that every
+// parent refers to an L1 chain
+func validateChains(chains []ChainConfig) error {
+	if len(chains) == 0 {
+		return fmt.Errorf("no chains defined")
+	}
+
+	names := make(map[string]ChainConfig, len(chains))
+	ids := make(map[int64]string, len(chains))
+	for _, c := range chains {
+		if c.Name == "" {
+			return fmt.Errorf("chain %d has no name", c.ChainID)
+		}
+		if _, dup := names[c.Name]; dup {
+			return fmt.Errorf("duplicate chain name %q", c.Name)
+		}
+		if other, dup := ids[c.ChainID]; dup {
+			return fmt.Errorf("chains %q and %q share chain_id %d", other, c.Name, c.ChainID)
+		}
+		if c.Kind != ChainKindL1 && c.Kind != ChainKindL2 {
+			return fmt.Errorf("chain %q: kind must be %q or %q, got %q", c.Name, ChainKindL1, ChainKindL2, c.Kind)
+		}
+		if c.ChainID <= 0 {
+			return fmt.Errorf("chain %q: chain_id is required", c.Name)
+		}
+		if c.RPCURL == "" {
+			return fmt.Errorf("chain %q: rpc_url is required", c.Name)
+		}
+		if !finalityModes[c.Finality] {
+			return fmt.Errorf("chain %q: unknown finality %q", c.Name, c.Finality)
+		}
+		if c.Finality == "l1_batch" && (c.Kind != ChainKindL2 || c.Parent == "") {
+			return fmt.Errorf("chain %q: finality l1_batch needs an l2 chain with a parent", c.Name)
+		}
+		names[c.Name] = c
+		ids[c.ChainID] = c.Name
+	}
+
+	for _, c := range chains {
+		if c.Parent == "" {
+			continue
+		}
+		if c.Kind != ChainKindL2 {
+			return fmt.Errorf("chain %q: only l2 chains have a parent", c.Name)
+		}
+		if p, ok := names[c.Parent]; !ok || p.Kind != ChainKindL1 {
+			return fmt.Errorf("chain %q: parent %q is not an l1 chain", c.Name, c.Parent)
+		}
+	}
+	return nil
+}
+
+// envChains describes the single L1 and L2 configured through L1_* and L2_*
+// variables, used when no CHAINS_FILE is given
+func (c *Config) envChains() []ChainConfig {
+	return []ChainConfig{
+		{
+			Name:          ChainKindL1,
+			Kind:          ChainKindL1,
+			ChainID:       c.L1ChainID,
+			RPCURL:        c.L1RPCURL,
+			WSSURL:        c.L1WSSURL,
+			Confirmations: c.L1Confirmations,
+			Finality:      c.L1Finality,
+			StartBlock:    c.L1StartBlock,
+			Contracts: ChainContracts{
+				CollateralVault: c.L1CollateralVault,
+				StateRegistry:   c.L1StateRegistry,
+				LoyaltyUSD:      c.L1LoyaltyUSD,
+				Gateway:         c.L1Gateway,
+			},
+		},
+		{
+			Name:          ChainKindL2,
+			Kind:          ChainKindL2,
+			ChainID:       c.L2ChainID,
+			RPCURL:        c.L2RPCURL,
+			WSSURL:        c.L2WSSURL,
+			Confirmations: c.L2Confirmations,
+			Finality:      c.L2Finality,
+			StartBlock:    c.L2StartBlock,
+			Parent:        ChainKindL1,
+			Contracts: ChainContracts{
+				IntegratedVault:     c.L2IntegratedVault,
+				StateAggregator:     c.L2StateAggregator,
+				AaveAdapter:         c.L2AaveAdapter,
+				CompoundAdapter:     c.L2CompoundAdapter,
+				UniswapAdapter:      c.L2UniswapAdapter,
+				RWAFactory:          c.L2RWAFactory,
+				RWAMarketplace:      c.L2RWAMarketplace,
+				RWAYieldDistributor: c.L2RWAYieldDistributor,
+				RWACompliance:       c.L2RWACompliance,
+				RWAValuation:        c.L2RWAValuation,
+				RWAGovernance:       c.L2RWAGovernance,
+				Inbox:               c.ArbitrumInbox,
+				Outbox:              c.ArbitrumOutbox,
+			},
+		},
+	}
+}
+
+// ArbitrumChain returns the first L2 with a parent and Arbitrum Inbox and
+// Outbox addresses, together with that parent
+func (c *Config) ArbitrumChain() (l1, l2 ChainConfig, ok bool) {
+	for _, ch := range c.Chains {
+		if ch.Kind != ChainKindL2 || ch.Contracts.Inbox == "" || ch.Contracts.Outbox == "" {
+			continue
+		}
+		if parent, found := c.ChainByName(ch.Parent); found {
+			return parent, ch, true
+		}
+	}
+	return ChainConfig{}, ChainConfig{}, false
+}
+
+// ChainByName returns the chain with the given name
+func (c *Config) ChainByName(name string) (ChainConfig, bool) {
+	for _, ch := range c.Chains {
+		if ch.Name == name {
+			return ch, true
+		}
+	}
+	return ChainConfig{}, false
+}
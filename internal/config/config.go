@@ -2,8 +2,10 @@ package config
 
 import (
 	"fmt"
+	"math/big"
 	"os"
 	"strconv"
+	"strings"
 )
 
 // Config holds all configuration for the loyalty points system
@@ -17,18 +19,38 @@ type Config struct {
 	KafkaTopicL1     string
 	KafkaTopicL2     string
 	KafkaTopicBridge string
+	EventCodec       string // Envelope encoding for published events: json or protobuf
+
+	// Consumer worker pool (per topic)
+	ConsumerWorkers   int
+	ConsumerBatchSize int
 
 	// L1 Configuration (Ethereum)
 	L1ChainID       int64
 	L1RPCURL        string
 	L1WSSURL        string
 	L1Confirmations int
+	L1Finality      string // Release policy: depth (L1Confirmations), safe or finalized
 
 	// L2 Configuration (Arbitrum)
 	L2ChainID       int64
 	L2RPCURL        string
 	L2WSSURL        string
 	L2Confirmations int
+	L2Finality      string // Release policy: depth (L2Confirmations), safe, finalized or l1_batch
+
+	// Listener catch-up (used when no checkpoint exists yet)
+	L1StartBlock      int64
+	L2StartBlock      int64
+	BackfillBatchSize int
+
+	// Listener poll mode (HTTP-only endpoints)
+	ListenerPollIntervalSec int
+
+	// Chains watched by the listener service. Loaded from ChainsFile when set,
+	// otherwise built from the L1_* and L2_* variables above.
+	ChainsFile string
+	Chains     []ChainConfig
 
 	// L1 Contract Addresses
 	L1CollateralVault string
@@ -57,6 +79,35 @@ type Config struct {
 	ArbitrumInbox  string
 	ArbitrumOutbox string
 
+	// Retryable ticket auto-redeem
+	BridgeAutoRedeem        bool
+	BridgeOperatorKey       string
+	BridgeRedeemMaxSpendWei string // per rolling hour
+	BridgeRedeemIntervalSec int
+	BridgeRedeemMaxAttempts int
+
+	// L2→L1 withdrawal finalization
+	BridgeAutoFinalize        bool
+	BridgeFinalizeIntervalSec int
+
+	// Bridge request policy (API); "0" volumes and 0 failures disable a check
+	BridgeUserMaxVolumeWei   string
+	BridgeUserWindowSec      int
+	BridgeGlobalMaxVolumeWei string
+	BridgeGlobalWindowSec    int
+	BridgeBreakerMaxFailures int
+	BridgeBreakerWindowSec   int
+	BridgeBreakerCooldownSec int
+
+	// Advertised confirmation times the bridge SLA report measures against
+	BridgeSLAL1ToL2Sec int
+	BridgeSLAL2ToL1Sec int
+
+	// Airdrop claim signatures (EIP-712 domain and server nonce lifetime)
+	AirdropChainID     int64
+	AirdropDistributor string // verifyingContract; empty signs against the zero address
+	AirdropNonceTTLSec int
+
 	// API Configuration
 	APIPort        string
 	APIAllowOrigin string
@@ -84,18 +135,36 @@ func LoadConfig() (*Config, error) {
 		KafkaTopicL1:     getEnvOrDefault("KAFKA_TOPIC_L1", "events.l1"),
 		KafkaTopicL2:     getEnvOrDefault("KAFKA_TOPIC_L2", "events.l2"),
 		KafkaTopicBridge: getEnvOrDefault("KAFKA_TOPIC_BRIDGE", "events.bridge"),
+		EventCodec:       getEnvOrDefault("EVENT_CODEC", "json"),
+
+		// Consumer worker pool
+		ConsumerWorkers:   getEnvInt("CONSUMER_WORKERS", 4),
+		ConsumerBatchSize: getEnvInt("CONSUMER_BATCH_SIZE", 50),
 
 		// L1 Configuration
 		L1ChainID:       getEnvInt64("L1_CHAIN_ID", 11155111), // Sepolia by default
 		L1RPCURL:        os.Getenv("L1_RPC_URL"),
 		L1WSSURL:        os.Getenv("L1_WSS_URL"),
 		L1Confirmations: getEnvInt("L1_CONFIRMATIONS", 12),
+		L1Finality:      os.Getenv("L1_FINALITY"),
 
 		// L2 Configuration
 		L2ChainID:       getEnvInt64("L2_CHAIN_ID", 421614), // Arbitrum Sepolia by default
 		L2RPCURL:        os.Getenv("L2_RPC_URL"),
 		L2WSSURL:        os.Getenv("L2_WSS_URL"),
 		L2Confirmations: getEnvInt("L2_CONFIRMATIONS", 1),
+		L2Finality:      os.Getenv("L2_FINALITY"),
+
+		// Listener catch-up
+		L1StartBlock:      getEnvInt64("L1_START_BLOCK", 0),
+		L2StartBlock:      getEnvInt64("L2_START_BLOCK", 0),
+		BackfillBatchSize: getEnvInt("LISTENER_BACKFILL_BATCH", 2000),
+
+		// Listener poll mode
+		ListenerPollIntervalSec: getEnvInt("LISTENER_POLL_INTERVAL_SEC", 4),
+
+		// Chains file (YAML or JSON)
+		ChainsFile: os.Getenv("CHAINS_FILE"),
 
 		// L1 Contract Addresses
 		L1CollateralVault: os.Getenv("L1_COLLATERAL_VAULT"),
@@ -124,6 +193,36 @@ func LoadConfig() (*Config, error) {
 		ArbitrumInbox:  getEnvOrDefault("ARBITRUM_SEPOLIA_INBOX", "0xaAe29B0366299461418F5324a79Afc425BE5ae21"),
 		ArbitrumOutbox: getEnvOrDefault("ARBITRUM_SEPOLIA_OUTBOX", "0x65f07C7D521164a4d5DaC6eB8Fac8DA067A3B78F"),
 
+		// Retryable ticket auto-redeem (operator key defaults to PRIVATE_KEY)
+		BridgeAutoRedeem:        os.Getenv("BRIDGE_AUTO_REDEEM") == "true",
+		BridgeOperatorKey:       getEnvOrDefault("BRIDGE_OPERATOR_KEY", os.Getenv("PRIVATE_KEY")),
+		BridgeRedeemMaxSpendWei: getEnvOrDefault("BRIDGE_REDEEM_MAX_SPEND_WEI", "10000000000000000"), // 0.01 ETH/hour
+		BridgeRedeemIntervalSec: getEnvInt("BRIDGE_REDEEM_INTERVAL_SEC", 60),
+		BridgeRedeemMaxAttempts: getEnvInt("BRIDGE_REDEEM_MAX_ATTEMPTS", 3),
+
+		// L2→L1 withdrawal finalization (same operator key)
+		BridgeAutoFinalize:        os.Getenv("BRIDGE_AUTO_FINALIZE") == "true",
+		BridgeFinalizeIntervalSec: getEnvInt("BRIDGE_FINALIZE_INTERVAL_SEC", 60),
+
+		// Bridge request policy
+		BridgeUserMaxVolumeWei:   getEnvOrDefault("BRIDGE_USER_MAX_VOLUME_WEI", "0"),
+		BridgeUserWindowSec:      getEnvInt("BRIDGE_USER_WINDOW_SEC", 86400),
+		BridgeGlobalMaxVolumeWei: getEnvOrDefault("BRIDGE_GLOBAL_MAX_VOLUME_WEI", "0"),
+		BridgeGlobalWindowSec:    getEnvInt("BRIDGE_GLOBAL_WINDOW_SEC", 3600),
+		BridgeBreakerMaxFailures: getEnvInt("BRIDGE_BREAKER_MAX_FAILURES", 5),
+		BridgeBreakerWindowSec:   getEnvInt("BRIDGE_BREAKER_WINDOW_SEC", 600),
+		BridgeBreakerCooldownSec: getEnvInt("BRIDGE_BREAKER_COOLDOWN_SEC", 900),
+
+		// Bridge SLA targets: deposits within the monitor's timeout,
+		// withdrawals within the challenge period
+		BridgeSLAL1ToL2Sec: getEnvInt("BRIDGE_SLA_L1_TO_L2_SEC", 900),
+		BridgeSLAL2ToL1Sec: getEnvInt("BRIDGE_SLA_L2_TO_L1_SEC", 7*86400),
+
+		// Airdrop claims are signed for the chain the distributor lives on (L1 by default)
+		AirdropChainID:     getEnvInt64("AIRDROP_CHAIN_ID", getEnvInt64("L1_CHAIN_ID", 11155111)),
+		AirdropDistributor: os.Getenv("AIRDROP_DISTRIBUTOR"),
+		AirdropNonceTTLSec: getEnvInt("AIRDROP_NONCE_TTL_SEC", 600),
+
 		// API Configuration
 		APIPort:        getEnvOrDefault("API_PORT", "8080"),
 		APIAllowOrigin: getEnvOrDefault("API_ALLOW_ORIGIN", "*"),
@@ -139,6 +238,16 @@ func LoadConfig() (*Config, error) {
 		SchedulerIntervalSec: getEnvInt("SCHEDULER_INTERVAL_SEC", 60),
 	}
 
+	if cfg.ChainsFile != "" {
+		chains, err := LoadChains(cfg.ChainsFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Chains = chains
+	} else {
+		cfg.Chains = cfg.envChains()
+	}
+
 	// Validate required fields
 	if err := cfg.Validate(); err != nil {
 		return nil, err
@@ -154,14 +263,48 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("DATABASE_URL is required")
 	}
 
-	// Check L1 RPC
-	if c.L1RPCURL == "" {
-		return fmt.Errorf("L1_RPC_URL is required")
+	// Check L1 and L2 RPC (a chains file carries its own)
+	if c.ChainsFile == "" {
+		if c.L1RPCURL == "" {
+			return fmt.Errorf("L1_RPC_URL is required")
+		}
+		if c.L2RPCURL == "" {
+			return fmt.Errorf("L2_RPC_URL is required")
+		}
 	}
 
-	// Check L2 RPC
-	if c.L2RPCURL == "" {
-		return fmt.Errorf("L2_RPC_URL is required")
+	// Check chain definitions (finality policies, parents)
+	if err := validateChains(c.Chains); err != nil {
+		return err
+	}
+
+	// Check auto-redeem and auto-finalize: they sign transactions, and
+	// redeems need a budget
+	if (c.BridgeAutoRedeem || c.BridgeAutoFinalize) && c.BridgeOperatorKey == "" {
+		return fmt.Errorf("BRIDGE_OPERATOR_KEY or PRIVATE_KEY is required when BRIDGE_AUTO_REDEEM or BRIDGE_AUTO_FINALIZE is set")
+	}
+	if c.BridgeAutoRedeem {
+		if spend, ok := new(big.Int).SetString(c.BridgeRedeemMaxSpendWei, 10); !ok || spend.Sign() <= 0 {
+			return fmt.Errorf("BRIDGE_REDEEM_MAX_SPEND_WEI must be a positive integer when BRIDGE_AUTO_REDEEM is set, got %q", c.BridgeRedeemMaxSpendWei)
+		}
+	}
+
+	// Check bridge policy volume limits
+	for name, v := range map[string]string{
+		"BRIDGE_USER_MAX_VOLUME_WEI":   c.BridgeUserMaxVolumeWei,
+		"BRIDGE_GLOBAL_MAX_VOLUME_WEI": c.BridgeGlobalMaxVolumeWei,
+	} {
+		if n, ok := new(big.Int).SetString(v, 10); !ok || n.Sign() < 0 {
+			return fmt.Errorf("%s must be a non-negative integer, got %q", name, v)
+		}
+	}
+
+	// Check airdrop claim domain
+	if c.AirdropDistributor != "" && (len(c.AirdropDistributor) != 42 || !strings.HasPrefix(c.AirdropDistributor, "0x")) {
+		return fmt.Errorf("AIRDROP_DISTRIBUTOR must be an address, got %q", c.AirdropDistributor)
+	}
+	if c.AirdropNonceTTLSec <= 0 {
+		return fmt.Errorf("AIRDROP_NONCE_TTL_SEC must be positive, got %d", c.AirdropNonceTTLSec)
 	}
 
 	// Check Kafka brokers
@@ -205,6 +348,12 @@ func (c *Config) IsTestnet() bool {
 	return c.L1ChainID == 11155111 && c.L2ChainID == 421614 // Sepolia + Arbitrum Sepolia
 }
 
+// Env returns the environment variable key, or defaultVal when it is unset.
+// For services that read a few settings without loading the full Config.
+func Env(key, defaultVal string) string {
+	return getEnvOrDefault(key, defaultVal)
+}
+
 // Helper functions
 
 func getEnvOrDefault(key, defaultVal string) string {
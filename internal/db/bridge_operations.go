@@ -1,33 +1,42 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"math/big"
+	"time"
 
 	"loyalty-points-system/internal/models"
 )
 
-// UpsertBridgeMessage inserts or updates a bridge message
+// UpsertBridgeMessage inserts or updates a bridge message. An update keeps the
+// stored direction and any known hashes and blocks, and only moves the status
+// along models.CanTransitionBridgeStatus, so replayed or out-of-order events
+// never move a message backwards.
 func UpsertBridgeMessage(tx *sql.Tx, evt *models.BridgeEvent) error {
 	query := `
 		INSERT INTO bridge_messages (
 			message_hash, direction, user_address, amount, status,
 			l1_tx_hash, l2_tx_hash, l1_block_number, l2_block_number,
-			initiated_at, confirmed_at, retry_count, error_msg
+			initiated_at, confirmed_at, retry_count, error_msg, pending_at
 		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9,
 		          to_timestamp($10),
 		          CASE WHEN $11 > 0 THEN to_timestamp($11) ELSE NULL END,
-		          $12, $13)
+		          $12, $13,
+		          CASE WHEN $5 = 'pending' THEN NOW() END)
 		ON CONFLICT (message_hash)
 		DO UPDATE SET
-			status = $5,
-			l1_tx_hash = COALESCE($6, bridge_messages.l1_tx_hash),
-			l2_tx_hash = COALESCE($7, bridge_messages.l2_tx_hash),
+			status = CASE WHEN bridge_messages.status = ANY($14) THEN $5 ELSE bridge_messages.status END,
+			l1_tx_hash = COALESCE(NULLIF($6, ''), bridge_messages.l1_tx_hash),
+			l2_tx_hash = COALESCE(NULLIF($7, ''), bridge_messages.l2_tx_hash),
 			l1_block_number = CASE WHEN $8 > 0 THEN $8 ELSE bridge_messages.l1_block_number END,
 			l2_block_number = CASE WHEN $9 > 0 THEN $9 ELSE bridge_messages.l2_block_number END,
-			confirmed_at = CASE WHEN $11 > 0 THEN to_timestamp($11) ELSE bridge_messages.confirmed_at END,
-			retry_count = $12,
-			error_msg = $13,
+			confirmed_at = COALESCE(bridge_messages.confirmed_at, CASE WHEN $11 > 0 THEN to_timestamp($11) END),
+			retry_count = GREATEST(bridge_messages.retry_count, $12),
+			error_msg = COALESCE(NULLIF($13, ''), bridge_messages.error_msg),
+			pending_at = CASE WHEN $5 = 'pending' AND bridge_messages.status = ANY($14)
+			                  THEN COALESCE(bridge_messages.pending_at, NOW()) ELSE bridge_messages.pending_at END,
 			updated_at = NOW()
 	`
 
@@ -45,11 +54,84 @@ func UpsertBridgeMessage(tx *sql.Tx, evt *models.BridgeEvent) error {
 		evt.ConfirmedAt,
 		evt.RetryCount,
 		evt.ErrorMsg,
+		models.BridgeStatusesInto(evt.Status),
 	)
 
 	return err
 }
 
+// BridgeStore records the legs the bridge listener observes straight into
+// bridge_messages, correlating L1 and L2 by message hash
+type BridgeStore struct {
+	DB *sql.DB
+}
+
+// NewBridgeStore creates a bridge store backed by Postgres
+func NewBridgeStore(database *sql.DB) *BridgeStore {
+	return &BridgeStore{DB: database}
+}
+
+// RecordBridgeLeg merges a leg into the stored message with the same hash (see
+// models.MergeBridgeLeg) and returns the message as stored, and whether this
+// leg confirmed it. Legs of one hash are serialized, so the two sides of a
+// message cannot both create it.
+func (s *BridgeStore) RecordBridgeLeg(ctx context.Context, leg *models.BridgeEvent) (*models.BridgeEvent, bool, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, leg.MessageHash); err != nil {
+		return nil, false, err
+	}
+
+	current := &models.BridgeEvent{}
+	err = tx.QueryRowContext(ctx, `
+		SELECT message_hash, direction, user_address, amount::text, status,
+		       COALESCE(l1_tx_hash, ''), COALESCE(l2_tx_hash, ''),
+		       COALESCE(l1_block_number, 0), COALESCE(l2_block_number, 0),
+		       EXTRACT(EPOCH FROM initiated_at)::bigint,
+		       COALESCE(EXTRACT(EPOCH FROM confirmed_at)::bigint, 0),
+		       retry_count, COALESCE(error_msg, '')
+		FROM bridge_messages
+		WHERE message_hash = $1
+	`, leg.MessageHash).Scan(
+		&current.MessageHash,
+		&current.Direction,
+		&current.UserAddress,
+		&current.Amount,
+		&current.Status,
+		&current.L1TxHash,
+		&current.L2TxHash,
+		&current.L1BlockNumber,
+		&current.L2BlockNumber,
+		&current.InitiatedAt,
+		&current.ConfirmedAt,
+		&current.RetryCount,
+		&current.ErrorMsg,
+	)
+	if err == sql.ErrNoRows {
+		current = nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	merged := models.MergeBridgeLeg(current, leg)
+	confirmed := merged.Status == models.BridgeStatusConfirmed &&
+		(current == nil || current.Status != models.BridgeStatusConfirmed)
+	if err := ensureUserExists(tx, merged.UserAddress); err != nil {
+		return nil, false, fmt.Errorf("ensure user failed: %w", err)
+	}
+	if err := UpsertBridgeMessage(tx, merged); err != nil {
+		return nil, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+	return merged, confirmed, nil
+}
+
 // ProcessBridgeEvent processes a bridge event and updates database
 func ProcessBridgeEvent(tx *sql.Tx, evt *models.BridgeEvent) error {
 	// Ensure user exists
@@ -120,13 +202,566 @@ func GetPendingBridgeMessages(db *sql.DB) ([]*models.BridgeEvent, error) {
 	return messages, rows.Err()
 }
 
-// MarkBridgeMessageFailed marks a bridge message as failed
+// MarkBridgeMessageFailed marks a bridge message as failed unless it was confirmed meanwhile
 func MarkBridgeMessageFailed(db *sql.DB, messageHash string, errorMsg string) error {
 	query := `
 		UPDATE bridge_messages
 		SET status = 'failed', error_msg = $2, updated_at = NOW()
+		WHERE message_hash = $1 AND status = ANY($3)
+	`
+	_, err := db.Exec(query, messageHash, errorMsg, models.BridgeStatusesInto(models.BridgeStatusFailed))
+	return err
+}
+
+// BridgeVolumeLimit caps the wei moved in a direction since a point in time;
+// a nil or zero Max disables it
+type BridgeVolumeLimit struct {
+	Max   *big.Int
+	Since time.Time
+}
+
+// BridgeVolumeReservation is the outcome of ReserveBridgeVolume
+type BridgeVolumeReservation struct {
+	ID         int64    // zero when nothing was reserved
+	UserUsed   *big.Int // volume of the user before this request
+	GlobalUsed *big.Int // volume of everyone before this request
+	UserOver   bool     // refused by the user limit
+	GlobalOver bool     // refused by the global limit
+}
+
+// ReserveBridgeVolume counts amount against the user and global volume
+// windows of a direction and, if it fits both, records it in
+// bridge_volume_reservations in the same transaction. Reservations of one
+// direction are serialized, so concurrent requests cannot all pass before any
+// of them is written.
+//
+// The windows sum non-failed bridge_messages and reservations. An accepted
+// request whose transaction lands is counted by both until it leaves the
+// window, so the limits err on the side of refusing.
+func ReserveBridgeVolume(ctx context.Context, db *sql.DB, direction, userAddress string, amount *big.Int, user, global BridgeVolumeLimit) (*BridgeVolumeReservation, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('bridge_volume:' || $1))`, direction); err != nil {
+		return nil, err
+	}
+
+	res := &BridgeVolumeReservation{UserUsed: new(big.Int), GlobalUsed: new(big.Int)}
+	if user.Max != nil && user.Max.Sign() > 0 {
+		if res.UserUsed, err = bridgeVolume(ctx, tx, direction, userAddress, user.Since); err != nil {
+			return nil, err
+		}
+		res.UserOver = new(big.Int).Add(res.UserUsed, amount).Cmp(user.Max) > 0
+	}
+	if global.Max != nil && global.Max.Sign() > 0 {
+		if res.GlobalUsed, err = bridgeVolume(ctx, tx, direction, "", global.Since); err != nil {
+			return nil, err
+		}
+		res.GlobalOver = new(big.Int).Add(res.GlobalUsed, amount).Cmp(global.Max) > 0
+	}
+	if res.UserOver || res.GlobalOver {
+		return res, nil
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO bridge_volume_reservations (direction, user_address, amount)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`, direction, userAddress, amount.String()).Scan(&res.ID)
+	if err != nil {
+		return nil, err
+	}
+	return res, tx.Commit()
+}
+
+// bridgeVolume returns the wei moved and reserved in a direction since a point
+// in time, by one user or, with an empty userAddress, by everyone. Failed
+// messages do not count.
+func bridgeVolume(ctx context.Context, tx *sql.Tx, direction string, userAddress string, since time.Time) (*big.Int, error) {
+	query := `
+		SELECT COALESCE(SUM(amount), 0)::numeric(78, 0)::text
+		FROM (
+			SELECT amount FROM bridge_messages
+			WHERE direction = $1
+			  AND initiated_at >= $2
+			  AND status <> 'failed'
+			  AND ($3 = '' OR lower(user_address) = lower($3))
+			UNION ALL
+			SELECT amount FROM bridge_volume_reservations
+			WHERE direction = $1
+			  AND reserved_at >= $2
+			  AND ($3 = '' OR lower(user_address) = lower($3))
+		) v
+	`
+	var total string
+	if err := tx.QueryRowContext(ctx, query, direction, since, userAddress).Scan(&total); err != nil {
+		return nil, err
+	}
+	volume, ok := new(big.Int).SetString(total, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid bridge volume %q", total)
+	}
+	return volume, nil
+}
+
+// BridgeBreakerState is the circuit breaker shared by every process applying
+// the bridge policy, as left by CheckBridgeBreaker
+type BridgeBreakerState struct {
+	OpenUntil time.Time // zero while closed
+	Failures  int       // failed messages counted by this check
+	Opened    bool      // this check opened the breaker
+	Closed    bool      // this check closed a breaker whose cooldown had passed
+}
+
+// CheckBridgeBreaker opens the shared breaker when maxFailures messages
+// failed within window, and keeps it open for cooldown. After a cooldown
+// only failures from then on count, so the burst that opened it does not
+// open it again. The breaker row is locked for the check, so replicas agree
+// on when it opened.
+func CheckBridgeBreaker(ctx context.Context, db *sql.DB, maxFailures int, window, cooldown time.Duration) (*BridgeBreakerState, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var openUntil sql.NullTime
+	var failuresSince time.Time
+	err = tx.QueryRowContext(ctx, `
+		SELECT open_until, failures_since FROM bridge_circuit_breaker WHERE id = 1 FOR UPDATE
+	`).Scan(&openUntil, &failuresSince)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &BridgeBreakerState{}
+	now := time.Now()
+	if openUntil.Valid && now.Before(openUntil.Time) {
+		state.OpenUntil = openUntil.Time
+		return state, nil
+	}
+	if maxFailures <= 0 {
+		return state, nil
+	}
+	state.Closed = openUntil.Valid
+
+	since := now.Add(-window)
+	if since.Before(failuresSince) {
+		since = failuresSince
+	}
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM bridge_messages
+		WHERE status = 'failed' AND updated_at >= $1
+	`, since).Scan(&state.Failures)
+	if err != nil {
+		return nil, err
+	}
+
+	if state.Failures >= maxFailures {
+		state.OpenUntil, state.Opened = now.Add(cooldown), true
+		_, err = tx.ExecContext(ctx, `
+			UPDATE bridge_circuit_breaker
+			SET open_until = $1, failures_since = $1, updated_at = NOW()
+			WHERE id = 1
+		`, state.OpenUntil)
+	} else if state.Closed {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE bridge_circuit_breaker SET open_until = NULL, updated_at = NOW() WHERE id = 1
+		`)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return state, tx.Commit()
+}
+
+// BridgeMessageTracking is the on-chain state of a message as resolved by the bridge monitor
+type BridgeMessageTracking struct {
+	Status         string // initiated, pending, confirmed or failed
+	ArbitrumStatus string // protocol step; empty while nothing is known yet
+	TicketID       string // L1→L2 retryable ticket
+	OutboxPosition string // L2→L1 outbox position (decimal)
+	L2TxHash       string // L1→L2: the successful redeem
+	ErrorMsg       string
+}
+
+// UpdateBridgeMessageTracking records the resolved state of a message. Known
+// hashes, ticket and position are never cleared, confirmed_at is set once, and
+// the status only moves along the shared state machine.
+func UpdateBridgeMessageTracking(db *sql.DB, messageHash string, t *BridgeMessageTracking) error {
+	query := `
+		UPDATE bridge_messages
+		SET status = CASE WHEN status = ANY($8) THEN $2 ELSE status END,
+		    arbitrum_status = COALESCE(NULLIF($3, ''), arbitrum_status),
+		    retryable_ticket_id = COALESCE(NULLIF($4, ''), retryable_ticket_id),
+		    outbox_position = COALESCE(NULLIF($5, '')::numeric, outbox_position),
+		    l2_tx_hash = COALESCE(NULLIF($6, ''), l2_tx_hash),
+		    confirmed_at = CASE WHEN $2 = 'confirmed' THEN COALESCE(confirmed_at, NOW()) ELSE confirmed_at END,
+		    pending_at = CASE WHEN $2 = 'pending' AND status = ANY($8) THEN COALESCE(pending_at, NOW()) ELSE pending_at END,
+		    error_msg = NULLIF($7, ''),
+		    checked_at = NOW(),
+		    updated_at = NOW()
+		WHERE message_hash = $1
+	`
+	_, err := db.Exec(query, messageHash, t.Status, t.ArbitrumStatus, t.TicketID, t.OutboxPosition, t.L2TxHash, t.ErrorMsg,
+		models.BridgeStatusesInto(t.Status))
+	return err
+}
+
+// RecordBridgeMessageCheckFailure counts a failed on-chain check against the message's retries
+func RecordBridgeMessageCheckFailure(db *sql.DB, messageHash string, errorMsg string) error {
+	query := `
+		UPDATE bridge_messages
+		SET retry_count = retry_count + 1, error_msg = $2, checked_at = NOW(), updated_at = NOW()
 		WHERE message_hash = $1
 	`
 	_, err := db.Exec(query, messageHash, errorMsg)
 	return err
 }
+
+// RetryableRedeemCandidate is a deposit whose retryable ticket exists on L2 but
+// was not redeemed
+type RetryableRedeemCandidate struct {
+	Message  *models.BridgeEvent
+	TicketID string
+	Attempts int
+}
+
+// GetRetryableRedeemCandidates returns pending deposits with a live, unredeemed
+// retryable ticket. Tickets with a redeem still in flight or with maxAttempts
+// redeems already are left out.
+func GetRetryableRedeemCandidates(db *sql.DB, maxAttempts int) ([]*RetryableRedeemCandidate, error) {
+	query := `
+		SELECT m.message_hash, m.direction, m.user_address, m.amount, m.status,
+		       m.l1_tx_hash, m.l2_tx_hash, m.l1_block_number, m.l2_block_number,
+		       EXTRACT(EPOCH FROM m.initiated_at)::bigint,
+		       COALESCE(EXTRACT(EPOCH FROM m.confirmed_at)::bigint, 0),
+		       m.retry_count, COALESCE(m.error_msg, ''),
+		       m.retryable_ticket_id,
+		       (SELECT COUNT(*) FROM bridge_redeems r WHERE r.message_hash = m.message_hash)
+		FROM bridge_messages m
+		WHERE m.status IN ('initiated', 'pending')
+		  AND m.arbitrum_status = 'retryable_created'
+		  AND m.retryable_ticket_id IS NOT NULL
+		  AND NOT EXISTS (
+		      SELECT 1 FROM bridge_redeems r
+		      WHERE r.message_hash = m.message_hash
+		        AND r.status = 'submitted'
+		        AND r.created_at > NOW() - INTERVAL '30 minutes'
+		  )
+		  AND (SELECT COUNT(*) FROM bridge_redeems r WHERE r.message_hash = m.message_hash) < $1
+		ORDER BY m.initiated_at ASC
+		LIMIT 100
+	`
+
+	rows, err := db.Query(query, maxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []*RetryableRedeemCandidate
+	for rows.Next() {
+		evt := &models.BridgeEvent{}
+		c := &RetryableRedeemCandidate{Message: evt}
+		err := rows.Scan(
+			&evt.MessageHash,
+			&evt.Direction,
+			&evt.UserAddress,
+			&evt.Amount,
+			&evt.Status,
+			&evt.L1TxHash,
+			&evt.L2TxHash,
+			&evt.L1BlockNumber,
+			&evt.L2BlockNumber,
+			&evt.InitiatedAt,
+			&evt.ConfirmedAt,
+			&evt.RetryCount,
+			&evt.ErrorMsg,
+			&c.TicketID,
+			&c.Attempts,
+		)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+
+	return candidates, rows.Err()
+}
+
+// BridgeRedeem is one operator redeem of a retryable ticket
+type BridgeRedeem struct {
+	MessageHash string
+	TicketID    string
+	TxHash      string
+	GasLimit    uint64
+	GasPrice    string // wei, decimal
+	CostWei     string // decimal
+	Status      string // submitted, mined, reverted or failed
+	ErrorMsg    string
+}
+
+// InsertBridgeRedeem records a redeem attempt and returns its id
+func InsertBridgeRedeem(db *sql.DB, r *BridgeRedeem) (int64, error) {
+	query := `
+		INSERT INTO bridge_redeems (message_hash, ticket_id, tx_hash, gas_limit, gas_price, cost_wei, status, error_msg, finished_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5::numeric, $6::numeric, $7, NULLIF($8, ''),
+		        CASE WHEN $7 = 'submitted' THEN NULL ELSE NOW() END)
+		RETURNING id
+	`
+	var id int64
+	err := db.QueryRow(query, r.MessageHash, r.TicketID, r.TxHash, int64(r.GasLimit), r.GasPrice, r.CostWei, r.Status, r.ErrorMsg).Scan(&id)
+	return id, err
+}
+
+// FinishBridgeRedeem records the outcome of a submitted redeem and the gas it actually cost
+func FinishBridgeRedeem(db *sql.DB, id int64, status string, costWei string, errorMsg string) error {
+	query := `
+		UPDATE bridge_redeems
+		SET status = $2, cost_wei = $3::numeric, error_msg = NULLIF($4, ''), finished_at = NOW()
+		WHERE id = $1
+	`
+	_, err := db.Exec(query, id, status, costWei, errorMsg)
+	return err
+}
+
+// ReserveBridgeRedeem records a submitted redeem with its reserved cost if the
+// redeems since the given time plus this one stay within maxSpend. Redeemers
+// reserve one at a time, so several of them cannot together overrun the cap.
+// Returns the new id, zero when the cap would be exceeded, and the spend
+// before this redeem.
+func ReserveBridgeRedeem(ctx context.Context, db *sql.DB, r *BridgeRedeem, since time.Time, maxSpend *big.Int) (int64, *big.Int, error) {
+	cost, ok := new(big.Int).SetString(r.CostWei, 10)
+	if !ok {
+		return 0, nil, fmt.Errorf("invalid redeem cost %q", r.CostWei)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('bridge_redeem_spend'))`); err != nil {
+		return 0, nil, err
+	}
+	spent, err := bridgeRedeemSpend(tx.QueryRowContext(ctx, bridgeRedeemSpendQuery, since))
+	if err != nil {
+		return 0, nil, err
+	}
+	if new(big.Int).Add(spent, cost).Cmp(maxSpend) > 0 {
+		return 0, spent, nil
+	}
+
+	var id int64
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO bridge_redeems (message_hash, ticket_id, gas_limit, gas_price, cost_wei, status)
+		VALUES ($1, $2, $3, $4::numeric, $5::numeric, 'submitted')
+		RETURNING id
+	`, r.MessageHash, r.TicketID, int64(r.GasLimit), r.GasPrice, r.CostWei).Scan(&id)
+	if err != nil {
+		return 0, nil, err
+	}
+	return id, spent, tx.Commit()
+}
+
+// SetBridgeRedeemTx records the transaction of a reserved redeem once sent
+func SetBridgeRedeemTx(db *sql.DB, id int64, txHash string) error {
+	_, err := db.Exec(`UPDATE bridge_redeems SET tx_hash = $2 WHERE id = $1`, id, txHash)
+	return err
+}
+
+// WithSessionLock runs fn while holding a Postgres session advisory lock on
+// key, serializing fn across every process sharing the database
+func WithSessionLock(ctx context.Context, db *sql.DB, key string, fn func() error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock(hashtext($1))`, key); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock(hashtext($1))`, key)
+	return fn()
+}
+
+const bridgeRedeemSpendQuery = `
+	SELECT COALESCE(SUM(cost_wei), 0)::text
+	FROM bridge_redeems
+	WHERE created_at > $1
+`
+
+func bridgeRedeemSpend(row *sql.Row) (*big.Int, error) {
+	var total string
+	if err := row.Scan(&total); err != nil {
+		return nil, err
+	}
+	spend, ok := new(big.Int).SetString(total, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid redeem spend %q", total)
+	}
+	return spend, nil
+}
+
+// GetBridgeRedeemSpend returns the wei spent (or reserved) on redeems since the given time
+func GetBridgeRedeemSpend(db *sql.DB, since time.Time) (*big.Int, error) {
+	return bridgeRedeemSpend(db.QueryRow(bridgeRedeemSpendQuery, since))
+}
+
+// BridgeWithdrawal is an L2→L1 withdrawal and the outbox message that finalizes it
+type BridgeWithdrawal struct {
+	WithdrawalID   string
+	UserAddress    string
+	Amount         string // wei, decimal
+	OutboxPosition uint64
+	L2Sender       string
+	Destination    string
+	L2BlockNumber  int64
+	L1BlockNumber  int64 // as reported by ArbSys, not the execution block
+	L2Timestamp    int64
+	CallValue      string // wei, decimal
+	Calldata       []byte
+	L2TxHash       string
+	L1TxHash       string
+	Status         string // waiting, submitted, executed or failed
+	Attempts       int
+	CreatedAt      int64
+}
+
+// InsertBridgeWithdrawal records a withdrawal seen on L2. It reports false
+// when the withdrawal was already known.
+func InsertBridgeWithdrawal(db *sql.DB, w *BridgeWithdrawal) (bool, error) {
+	query := `
+		INSERT INTO bridge_withdrawals (
+			withdrawal_id, user_address, amount, outbox_position, l2_sender, destination,
+			l2_block_number, l1_block_number, l2_timestamp, call_value, calldata, l2_tx_hash
+		) VALUES ($1, $2, $3::numeric, $4, $5, $6, $7, $8, $9, $10::numeric, $11, $12)
+		ON CONFLICT (withdrawal_id) DO NOTHING
+	`
+	res, err := db.Exec(query,
+		w.WithdrawalID, w.UserAddress, w.Amount, int64(w.OutboxPosition), w.L2Sender, w.Destination,
+		w.L2BlockNumber, w.L1BlockNumber, w.L2Timestamp, w.CallValue, w.Calldata, w.L2TxHash,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// bridgeWithdrawalColumns are the columns scanned by queryBridgeWithdrawals
+const bridgeWithdrawalColumns = `
+	withdrawal_id, user_address, amount::text, outbox_position::bigint, l2_sender, destination,
+	l2_block_number, l1_block_number, l2_timestamp, call_value::text, calldata,
+	l2_tx_hash, COALESCE(l1_tx_hash, ''), status, attempts,
+	EXTRACT(EPOCH FROM created_at)::bigint`
+
+// GetOpenBridgeWithdrawals returns withdrawals sent at or below an L2 block
+// that are not executed yet, oldest first
+func GetOpenBridgeWithdrawals(db *sql.DB, maxL2Block uint64, maxAttempts int) ([]*BridgeWithdrawal, error) {
+	query := `
+		SELECT ` + bridgeWithdrawalColumns + `
+		FROM bridge_withdrawals
+		WHERE status IN ('waiting', 'submitted')
+		  AND l2_block_number <= $1
+		  AND attempts < $2
+		ORDER BY outbox_position ASC
+		LIMIT 50
+	`
+	return queryBridgeWithdrawals(db, query, int64(maxL2Block), maxAttempts)
+}
+
+// GetFailedBridgeWithdrawals returns the most recent withdrawals that ran out
+// of attempts, to check whether the outbox executed them after all
+func GetFailedBridgeWithdrawals(db *sql.DB, limit int) ([]*BridgeWithdrawal, error) {
+	query := `
+		SELECT ` + bridgeWithdrawalColumns + `
+		FROM bridge_withdrawals
+		WHERE status = 'failed'
+		ORDER BY outbox_position DESC
+		LIMIT $1
+	`
+	return queryBridgeWithdrawals(db, query, limit)
+}
+
+func queryBridgeWithdrawals(db *sql.DB, query string, args ...interface{}) ([]*BridgeWithdrawal, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var withdrawals []*BridgeWithdrawal
+	for rows.Next() {
+		w := &BridgeWithdrawal{}
+		var position int64
+		err := rows.Scan(
+			&w.WithdrawalID, &w.UserAddress, &w.Amount, &position, &w.L2Sender, &w.Destination,
+			&w.L2BlockNumber, &w.L1BlockNumber, &w.L2Timestamp, &w.CallValue, &w.Calldata,
+			&w.L2TxHash, &w.L1TxHash, &w.Status, &w.Attempts, &w.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		w.OutboxPosition = uint64(position)
+		withdrawals = append(withdrawals, w)
+	}
+
+	return withdrawals, rows.Err()
+}
+
+// MarkBridgeWithdrawalSubmitted records the L1 transaction executing a withdrawal
+func MarkBridgeWithdrawalSubmitted(db *sql.DB, withdrawalID string, l1TxHash string) error {
+	query := `
+		UPDATE bridge_withdrawals
+		SET status = 'submitted', l1_tx_hash = $2, error_msg = NULL
+		WHERE withdrawal_id = $1
+	`
+	_, err := db.Exec(query, withdrawalID, l1TxHash)
+	return err
+}
+
+// MarkBridgeWithdrawalExecuted records a withdrawal the outbox executed and
+// confirms its bridge message with the L1 execution in the same transaction;
+// evt.L1TxHash is empty when it was executed by someone else
+func MarkBridgeWithdrawalExecuted(db *sql.DB, evt *models.BridgeEvent) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE bridge_withdrawals
+		SET status = 'executed', l1_tx_hash = COALESCE(NULLIF($2, ''), l1_tx_hash), error_msg = NULL, executed_at = NOW()
+		WHERE withdrawal_id = $1
+	`
+	if _, err := tx.Exec(query, evt.MessageHash, evt.L1TxHash); err != nil {
+		return err
+	}
+	if err := ProcessBridgeEvent(tx, evt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RecordBridgeWithdrawalFailure counts a failed execution attempt; the
+// withdrawal is failed once maxAttempts is reached
+func RecordBridgeWithdrawalFailure(db *sql.DB, withdrawalID string, errorMsg string, maxAttempts int) error {
+	query := `
+		UPDATE bridge_withdrawals
+		SET attempts = attempts + 1,
+		    status = CASE WHEN attempts + 1 >= $3 THEN 'failed' ELSE 'waiting' END,
+		    error_msg = $2
+		WHERE withdrawal_id = $1
+	`
+	_, err := db.Exec(query, withdrawalID, errorMsg, maxAttempts)
+	return err
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// CheckpointStore persists listener block checkpoints in listener_checkpoints
+type CheckpointStore struct {
+	DB *sql.DB
+}
+
+// NewCheckpointStore creates a checkpoint store backed by Postgres
+func NewCheckpointStore(database *sql.DB) *CheckpointStore {
+	return &CheckpointStore{DB: database}
+}
+
+// LoadCheckpoint returns the last fully-confirmed block for a contract
+func (s *CheckpointStore) LoadCheckpoint(ctx context.Context, listener string, chainID int64, contract string) (uint64, bool, error) {
+	var block int64
+	err := s.DB.QueryRowContext(ctx, `
+		SELECT last_block FROM listener_checkpoints
+		WHERE listener = $1 AND chain_id = $2 AND contract_address = $3
+	`, listener, chainID, contract).Scan(&block)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return uint64(block), true, nil
+}
+
+// SaveCheckpoint records the last fully-confirmed block for a contract.
+// The checkpoint never moves backwards.
+func (s *CheckpointStore) SaveCheckpoint(ctx context.Context, listener string, chainID int64, contract string, block uint64) error {
+	_, err := s.DB.ExecContext(ctx, `
+		INSERT INTO listener_checkpoints (listener, chain_id, contract_address, last_block, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (listener, chain_id, contract_address)
+		DO UPDATE SET
+			last_block = GREATEST(listener_checkpoints.last_block, EXCLUDED.last_block),
+			updated_at = NOW()
+	`, listener, chainID, contract, int64(block))
+	return err
+}
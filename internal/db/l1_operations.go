@@ -42,16 +42,38 @@ func InsertL1StateSnapshot(tx *sql.Tx, evt *models.L1Event) error {
 		) VALUES ($1, $2, $3, $4, NOW())
 		ON CONFLICT (l2_block_number) DO NOTHING
 	`
-	// Extract L2 block number from metadata if available
+	// Extract L2 block number and state root from decoded event fields
 	l2BlockNumber := int64(0)
-	// TODO: Parse from event data when available
+	stateRoot := ""
+	if evt.Metadata != nil {
+		if bn, ok := evt.Metadata["l2_block"].(string); ok {
+			fmt.Sscanf(bn, "%d", &l2BlockNumber)
+		}
+		if root, ok := evt.Metadata["state_root"].(string); ok {
+			stateRoot = root
+		}
+	}
 
-	_, err := tx.Exec(query, l2BlockNumber, "", evt.TxHash, evt.BlockNumber)
+	_, err := tx.Exec(query, l2BlockNumber, stateRoot, evt.TxHash, evt.BlockNumber)
 	return err
 }
 
 // ProcessL1Event processes an L1 event and updates database
 func ProcessL1Event(tx *sql.Tx, evt *models.L1Event) error {
+	// Reorged-out events are compensated rather than applied
+	if evt.EventType == "reorg_retraction" {
+		return RetractL1Event(tx, evt)
+	}
+
+	// Skip events already applied (Kafka redelivery, replays)
+	fresh, err := MarkEventProcessed(tx, "L1", evt.TxHash, evt.LogIndex, evt.EventType)
+	if err != nil {
+		return fmt.Errorf("record processed event failed: %w", err)
+	}
+	if !fresh {
+		return nil
+	}
+
 	// Ensure user exists
 	if err := ensureUserExists(tx, evt.UserAddress); err != nil {
 		return fmt.Errorf("ensure user failed: %w", err)
@@ -64,23 +86,23 @@ func ProcessL1Event(tx *sql.Tx, evt *models.L1Event) error {
 
 	// Process based on event type
 	switch evt.EventType {
-	case "collateral_deposit", "collateral_operation":
+	case "collateral_deposit":
 		// Insert deposit record
 		if err := InsertL1CollateralDeposit(tx, evt); err != nil {
 			return fmt.Errorf("insert deposit failed: %w", err)
 		}
 		// Update balance
-		if err := UpsertL1CollateralBalance(tx, evt, true); err != nil {
+		if err := ProjectL1Event(tx, evt); err != nil {
 			return fmt.Errorf("update balance failed: %w", err)
 		}
 
-	case "collateral_withdraw":
+	case "collateral_withdraw", "collateral_emergency_withdraw":
 		// Insert withdrawal record (can reuse deposit table with negative amount)
 		if err := InsertL1CollateralDeposit(tx, evt); err != nil {
 			return fmt.Errorf("insert withdrawal failed: %w", err)
 		}
 		// Update balance
-		if err := UpsertL1CollateralBalance(tx, evt, false); err != nil {
+		if err := ProjectL1Event(tx, evt); err != nil {
 			return fmt.Errorf("update balance failed: %w", err)
 		}
 
@@ -90,7 +112,7 @@ func ProcessL1Event(tx *sql.Tx, evt *models.L1Event) error {
 			return fmt.Errorf("insert state snapshot failed: %w", err)
 		}
 
-	case "loyaltyusd_transfer", "gateway_message":
+	case "loyaltyusd_transfer", "gateway_deposit_initiated", "gateway_withdrawal_initiated":
 		// These are logged in balance_events only
 		// No specific table updates needed
 
@@ -107,11 +129,11 @@ func insertBalanceEvent(tx *sql.Tx, evt *models.L1Event) error {
 	query := `
 		INSERT INTO balance_events (
 			user_address, amount, event_type, tx_hash, chain, block_number, confirmed,
-			layer, token, contract_address
-		) VALUES ($1, $2, $3, $4, 'L1', $5, $6, 'L1', $7, $8)
-		ON CONFLICT (tx_hash, event_type) DO NOTHING
+			layer, token, contract_address, log_index
+		) VALUES ($1, $2, $3, $4, 'L1', $5, $6, 'L1', $7, $8, $9)
+		ON CONFLICT (tx_hash, log_index, event_type) DO NOTHING
 	`
-	_, err := tx.Exec(query,
+	res, err := tx.Exec(query,
 		evt.UserAddress,
 		evt.Amount,
 		evt.EventType,
@@ -120,8 +142,12 @@ func insertBalanceEvent(tx *sql.Tx, evt *models.L1Event) error {
 		evt.Confirmed,
 		evt.Token,
 		evt.ContractAddress,
+		int64(evt.LogIndex),
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	return reinstateIfRetracted(tx, res, "L1", evt.TxHash, evt.LogIndex, evt.EventType)
 }
 
 // ensureUserExists ensures user record exists
@@ -141,6 +141,31 @@ func InsertL2RWAListing(tx *sql.Tx, evt *models.L2Event) error {
 	return err
 }
 
+// resolveRWAListingAsset fills metadata asset_id from the listing a trade executed against
+func resolveRWAListingAsset(tx *sql.Tx, evt *models.L2Event) error {
+	if evt.Metadata == nil {
+		return nil
+	}
+	if _, ok := evt.Metadata["asset_id"].(string); ok {
+		return nil
+	}
+	lid, ok := evt.Metadata["listing_id"].(string)
+	if !ok {
+		return nil
+	}
+
+	var assetID int64
+	err := tx.QueryRow(`SELECT asset_id FROM l2_rwa_listings WHERE listing_id = $1`, lid).Scan(&assetID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	evt.Metadata["asset_id"] = fmt.Sprintf("%d", assetID)
+	return nil
+}
+
 // InsertL2RWAProposal inserts an RWA governance proposal
 func InsertL2RWAProposal(tx *sql.Tx, evt *models.L2Event) error {
 	proposalID := int64(0)
@@ -172,6 +197,20 @@ func InsertL2RWAProposal(tx *sql.Tx, evt *models.L2Event) error {
 
 // ProcessL2Event processes an L2 event and updates database
 func ProcessL2Event(tx *sql.Tx, evt *models.L2Event) error {
+	// Reorged-out events are compensated rather than applied
+	if evt.EventType == "reorg_retraction" {
+		return RetractL2Event(tx, evt)
+	}
+
+	// Skip events already applied (Kafka redelivery, replays)
+	fresh, err := MarkEventProcessed(tx, "L2", evt.TxHash, evt.LogIndex, evt.EventType)
+	if err != nil {
+		return fmt.Errorf("record processed event failed: %w", err)
+	}
+	if !fresh {
+		return nil
+	}
+
 	// Ensure user exists
 	if err := ensureUserExists(tx, evt.UserAddress); err != nil {
 		return fmt.Errorf("ensure user failed: %w", err)
@@ -184,13 +223,8 @@ func ProcessL2Event(tx *sql.Tx, evt *models.L2Event) error {
 
 	// Process based on event type
 	switch {
-	case evt.EventType == "vault_deposit" || evt.EventType == "vault_operation":
-		if err := UpsertL2VaultPosition(tx, evt, true); err != nil {
-			return fmt.Errorf("upsert vault position failed: %w", err)
-		}
-
-	case evt.EventType == "vault_withdraw":
-		if err := UpsertL2VaultPosition(tx, evt, false); err != nil {
+	case evt.EventType == "vault_deposit", evt.EventType == "vault_withdraw":
+		if err := projectL2VaultPosition(tx, evt); err != nil {
 			return fmt.Errorf("upsert vault position failed: %w", err)
 		}
 
@@ -199,26 +233,26 @@ func ProcessL2Event(tx *sql.Tx, evt *models.L2Event) error {
 			return fmt.Errorf("insert strategy allocation failed: %w", err)
 		}
 
-	case evt.EventType == "rwa_factory":
+	case evt.EventType == "rwa_asset_created":
 		if err := InsertL2RWAAsset(tx, evt); err != nil {
 			return fmt.Errorf("insert RWA asset failed: %w", err)
 		}
 
-	case evt.EventType == "rwa_marketplace":
-		// Could be listing or purchase
+	case evt.EventType == "rwa_listing_created":
 		if err := InsertL2RWAListing(tx, evt); err != nil {
 			return fmt.Errorf("insert RWA listing failed: %w", err)
 		}
-		// Also update holdings if it's a purchase
-		if evt.Metadata != nil {
-			if action, ok := evt.Metadata["action"].(string); ok && action == "purchase" {
-				if err := UpsertL2RWAHolding(tx, evt, true); err != nil {
-					return fmt.Errorf("upsert RWA holding failed: %w", err)
-				}
-			}
+
+	case evt.EventType == "rwa_trade_executed":
+		// Trades reference the listing; resolve its asset before crediting the buyer
+		if err := resolveRWAListingAsset(tx, evt); err != nil {
+			return fmt.Errorf("resolve RWA listing failed: %w", err)
+		}
+		if err := UpsertL2RWAHolding(tx, evt, true); err != nil {
+			return fmt.Errorf("upsert RWA holding failed: %w", err)
 		}
 
-	case evt.EventType == "rwa_governance":
+	case evt.EventType == "rwa_proposal_created":
 		if err := InsertL2RWAProposal(tx, evt); err != nil {
 			return fmt.Errorf("insert RWA proposal failed: %w", err)
 		}
@@ -274,11 +308,11 @@ func insertL2BalanceEvent(tx *sql.Tx, evt *models.L2Event) error {
 	query := `
 		INSERT INTO balance_events (
 			user_address, amount, event_type, tx_hash, chain, block_number, confirmed,
-			layer, token, contract_address
-		) VALUES ($1, $2, $3, $4, 'L2', $5, $6, 'L2', '', $7)
-		ON CONFLICT (tx_hash, event_type) DO NOTHING
+			layer, token, contract_address, log_index
+		) VALUES ($1, $2, $3, $4, 'L2', $5, $6, 'L2', '', $7, $8)
+		ON CONFLICT (tx_hash, log_index, event_type) DO NOTHING
 	`
-	_, err := tx.Exec(query,
+	res, err := tx.Exec(query,
 		evt.UserAddress,
 		evt.Amount,
 		evt.EventType,
@@ -286,9 +320,13 @@ func insertL2BalanceEvent(tx *sql.Tx, evt *models.L2Event) error {
 		evt.BlockNumber,
 		evt.Confirmed,
 		evt.ContractAddress,
+		int64(evt.LogIndex),
 	)
 	_ = metadataJSON // TODO: Store metadata in separate column if needed
-	return err
+	if err != nil {
+		return err
+	}
+	return reinstateIfRetracted(tx, res, "L2", evt.TxHash, evt.LogIndex, evt.EventType)
 }
 
 // ============================================
@@ -394,6 +432,32 @@ func ProcessTreasuryOrderMatched(tx *sql.Tx, evt *models.L2Event) error {
 		matchedAmount, price, totalValue, evt.TxHash)
 
 	// Update holdings for buyer and seller
+	if err := applyTreasuryTradeHoldings(tx, evt); err != nil {
+		return err
+	}
+
+	return err
+}
+
+// applyTreasuryTradeHoldings moves the matched tokens from the seller's to the buyer's holding
+func applyTreasuryTradeHoldings(tx *sql.Tx, evt *models.L2Event) error {
+	if evt.Metadata == nil {
+		return fmt.Errorf("missing metadata for treasury order matched event")
+	}
+
+	assetID := int64(0)
+	buyer := ""
+	seller := ""
+	if id, ok := evt.Metadata["asset_id"].(string); ok {
+		fmt.Sscanf(id, "%d", &assetID)
+	}
+	if b, ok := evt.Metadata["buyer"].(string); ok {
+		buyer = b
+	}
+	if s, ok := evt.Metadata["seller"].(string); ok {
+		seller = s
+	}
+
 	// Increase buyer's holdings
 	holdingQuery := `
 		INSERT INTO treasury_holdings (user_address, asset_id, tokens_held, last_updated)
@@ -403,7 +467,7 @@ func ProcessTreasuryOrderMatched(tx *sql.Tx, evt *models.L2Event) error {
 			tokens_held = treasury_holdings.tokens_held + $3,
 			last_updated = NOW()
 	`
-	if _, err := tx.Exec(holdingQuery, buyer, assetID, matchedAmount); err != nil {
+	if _, err := tx.Exec(holdingQuery, buyer, assetID, evt.Amount); err != nil {
 		return err
 	}
 
@@ -414,10 +478,7 @@ func ProcessTreasuryOrderMatched(tx *sql.Tx, evt *models.L2Event) error {
 		    last_updated = NOW()
 		WHERE user_address = $2 AND asset_id = $3
 	`
-	if _, err := tx.Exec(decreaseQuery, matchedAmount, seller, assetID); err != nil {
-		return err
-	}
-
+	_, err := tx.Exec(decreaseQuery, evt.Amount, seller, assetID)
 	return err
 }
 
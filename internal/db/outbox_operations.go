@@ -0,0 +1,131 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Outbox persists confirmed listener events in listener_outbox until the
+// relay has delivered them to Kafka
+type Outbox struct {
+	DB *sql.DB
+}
+
+// NewOutbox creates an outbox backed by Postgres
+func NewOutbox(database *sql.DB) *Outbox {
+	return &Outbox{DB: database}
+}
+
+// OutboxMessage is a pending outbox row
+type OutboxMessage struct {
+	ID       int64
+	Topic    string
+	Key      []byte
+	Value    []byte
+	Headers  []kafka.Header
+	Attempts int
+}
+
+// OutboxWriter enqueues messages for one topic. It satisfies the listeners'
+// Writer interface, so a successful write means the message is durable.
+type OutboxWriter struct {
+	outbox *Outbox
+	topic  string
+}
+
+// Writer returns a writer that enqueues messages for topic
+func (o *Outbox) Writer(topic string) *OutboxWriter {
+	return &OutboxWriter{outbox: o, topic: topic}
+}
+
+// WriteMessages stores all messages atomically
+func (w *OutboxWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	tx, err := w.outbox.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, m := range msgs {
+		headers := []byte("[]")
+		if len(m.Headers) > 0 {
+			if headers, err = json.Marshal(m.Headers); err != nil {
+				return err
+			}
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO listener_outbox (topic, msg_key, payload, headers)
+			VALUES ($1, $2, $3, $4)
+		`, w.topic, m.Key, m.Value, string(headers))
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// PendingOutbox returns up to limit undelivered messages of a topic in insertion order
+func (o *Outbox) PendingOutbox(ctx context.Context, topic string, limit int) ([]OutboxMessage, error) {
+	rows, err := o.DB.QueryContext(ctx, `
+		SELECT id, topic, msg_key, payload, headers, attempts
+		FROM listener_outbox
+		WHERE topic = $1 AND delivered_at IS NULL
+		ORDER BY id
+		LIMIT $2
+	`, topic, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var msgs []OutboxMessage
+	for rows.Next() {
+		var m OutboxMessage
+		var headers []byte
+		if err := rows.Scan(&m.ID, &m.Topic, &m.Key, &m.Value, &headers, &m.Attempts); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(headers, &m.Headers); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, rows.Err()
+}
+
+// MarkOutboxDelivered records that Kafka acknowledged the given messages
+func (o *Outbox) MarkOutboxDelivered(ctx context.Context, ids []int64) error {
+	_, err := o.DB.ExecContext(ctx, `
+		UPDATE listener_outbox
+		SET delivered_at = NOW(), last_error = NULL
+		WHERE id = ANY($1)
+	`, ids)
+	return err
+}
+
+// MarkOutboxFailed records a failed delivery attempt for the given messages
+func (o *Outbox) MarkOutboxFailed(ctx context.Context, ids []int64, cause error) error {
+	_, err := o.DB.ExecContext(ctx, `
+		UPDATE listener_outbox
+		SET attempts = attempts + 1, last_error = $2
+		WHERE id = ANY($1)
+	`, ids, cause.Error())
+	return err
+}
+
+// PurgeOutbox deletes messages delivered before the given time
+func (o *Outbox) PurgeOutbox(ctx context.Context, before time.Time) (int64, error) {
+	res, err := o.DB.ExecContext(ctx, `
+		DELETE FROM listener_outbox
+		WHERE delivered_at IS NOT NULL AND delivered_at < $1
+	`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,33 @@
+package db
+
+import (
+	"database/sql"
+)
+
+// MarkEventProcessed records a chain event in the processed_events ledger.
+// Returns false when the event was already applied, in which case the caller
+// must skip its balance mutations. Must run in the transaction that applies it.
+func MarkEventProcessed(tx *sql.Tx, chain, txHash string, logIndex uint, eventType string) (bool, error) {
+	res, err := tx.Exec(`
+		INSERT INTO processed_events (chain, tx_hash, log_index, event_type)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (chain, tx_hash, log_index, event_type) DO NOTHING
+	`, chain, txHash, int64(logIndex), eventType)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// UnmarkEventProcessed removes an event from the ledger after it was retracted
+func UnmarkEventProcessed(tx *sql.Tx, chain, txHash string, logIndex uint, eventType string) error {
+	_, err := tx.Exec(`
+		DELETE FROM processed_events
+		WHERE chain = $1 AND tx_hash = $2 AND log_index = $3 AND event_type = $4
+	`, chain, txHash, int64(logIndex), eventType)
+	return err
+}
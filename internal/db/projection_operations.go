@@ -0,0 +1,381 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"loyalty-points-system/internal/models"
+)
+
+// Projection tables are derived from the event log and updated in place by
+// the consumer. The functions below are their only writers on the event path,
+// so a rebuild replays exactly what the live consumer applies.
+const (
+	ProjectionBalances         = "balances"
+	ProjectionL1Collateral     = "l1_collateral_balances"
+	ProjectionL2VaultPositions = "l2_vault_positions"
+	ProjectionTreasuryHoldings = "treasury_holdings"
+)
+
+// ProjectionTables lists every rebuildable projection
+var ProjectionTables = []string{
+	ProjectionBalances,
+	ProjectionL1Collateral,
+	ProjectionL2VaultPositions,
+	ProjectionTreasuryHoldings,
+}
+
+// ApplyBalanceEvent applies a legacy balance event to the balances projection
+func ApplyBalanceEvent(tx *sql.Tx, evt *models.BalanceEvent) error {
+	_, err := tx.Exec(`INSERT INTO balances(user_address, balance) VALUES($1, 0) ON CONFLICT (user_address) DO NOTHING`, evt.UserAddress)
+	if err != nil {
+		return err
+	}
+
+	op := "+"
+	if evt.EventType == "burn" || evt.EventType == "transfer_out" {
+		op = "-"
+	}
+
+	_, err = tx.Exec(`
+		UPDATE balances
+		SET balance = CASE WHEN $3 = '-' THEN balance - CAST($2 AS NUMERIC)
+		                   ELSE balance + CAST($2 AS NUMERIC) END, updated_at = NOW()
+		WHERE user_address = $1
+	`, evt.UserAddress, evt.Amount, op)
+	return err
+}
+
+// projected returns the event type whose effect applies and whether it is
+// being reverted by a reorg retraction
+func projected(eventType, retractedEventType string) (string, bool) {
+	if eventType == "reorg_retraction" {
+		return retractedEventType, true
+	}
+	return eventType, false
+}
+
+// ProjectL1Event applies an L1 event to l1_collateral_balances, or reverts it
+// for a reorg retraction
+func ProjectL1Event(tx *sql.Tx, evt *models.L1Event) error {
+	eventType, revert := projected(evt.EventType, evt.RetractedEventType)
+	switch eventType {
+	case "collateral_deposit":
+		return UpsertL1CollateralBalance(tx, evt, !revert)
+	case "collateral_withdraw", "collateral_emergency_withdraw":
+		return UpsertL1CollateralBalance(tx, evt, revert)
+	}
+	return nil
+}
+
+// projectL2VaultPosition applies an L2 event to l2_vault_positions, or reverts
+// it for a reorg retraction
+func projectL2VaultPosition(tx *sql.Tx, evt *models.L2Event) error {
+	eventType, revert := projected(evt.EventType, evt.RetractedEventType)
+	switch eventType {
+	case "vault_deposit":
+		return UpsertL2VaultPosition(tx, evt, !revert)
+	case "vault_withdraw":
+		return UpsertL2VaultPosition(tx, evt, revert)
+	}
+	return nil
+}
+
+// projectL2TreasuryHolding applies an L2 event to treasury_holdings.
+// Treasury events are not compensated on reorgs.
+func projectL2TreasuryHolding(tx *sql.Tx, evt *models.L2Event) error {
+	switch evt.EventType {
+	case "treasury_order_matched":
+		return applyTreasuryTradeHoldings(tx, evt)
+	case "treasury_yield_claimed":
+		return ProcessTreasuryYieldClaimed(tx, evt)
+	}
+	return nil
+}
+
+// Schemas used by a rebuild: the fresh tables are filled in the first, the
+// replaced rows are kept in the second until the next rebuild
+const (
+	rebuildSchema  = "projections_rebuild"
+	previousSchema = "projections_previous"
+)
+
+// ProjectionRebuild replays events into fresh copies of projection tables and
+// swaps them in atomically. While it runs, balance_events and the live tables
+// are locked against writes: the consumer pauses instead of applying events
+// the rebuild would miss, and reads keep being served from the old rows.
+// This covers events replayed from balance_events; a replay from Kafka needs
+// the consumer stopped, as an event it already fetched would be applied by
+// both.
+type ProjectionRebuild struct {
+	tx     *sql.Tx
+	tables map[string]bool
+	order  []string
+}
+
+// BeginProjectionRebuild locks the live tables and creates empty copies of
+// them. Unqualified table names resolve to the copies until Swap or Rollback.
+func BeginProjectionRebuild(ctx context.Context, database *sql.DB, tables []string) (*ProjectionRebuild, error) {
+	r := &ProjectionRebuild{tables: make(map[string]bool)}
+	for _, t := range tables {
+		if !isProjection(t) {
+			return nil, fmt.Errorf("%s is not a projection table", t)
+		}
+		if !r.tables[t] {
+			r.tables[t] = true
+			r.order = append(r.order, t)
+		}
+	}
+	if len(r.order) == 0 {
+		return nil, errors.New("no projection tables selected")
+	}
+
+	// The consumer takes the same locks in another order, so a rebuild can be
+	// picked as a deadlock victim; it simply starts over
+	var err error
+	for attempt := 1; attempt <= 5; attempt++ {
+		if err = r.begin(ctx, database); err == nil {
+			return r, nil
+		}
+		log.Printf("⚠️  Projection rebuild could not start (attempt %d/5): %v", attempt, err)
+		select {
+		case <-time.After(time.Duration(attempt) * time.Second):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return nil, err
+}
+
+func (r *ProjectionRebuild) begin(ctx context.Context, database *sql.DB) error {
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	live := make([]string, len(r.order))
+	for i, t := range r.order {
+		live[i] = "public." + t
+	}
+	stmts := []string{
+		`SET LOCAL lock_timeout = '30s'`,
+		`LOCK TABLE public.balance_events IN SHARE MODE`,
+		fmt.Sprintf(`LOCK TABLE %s IN EXCLUSIVE MODE`, strings.Join(live, ", ")),
+		`DROP SCHEMA IF EXISTS ` + rebuildSchema + ` CASCADE`,
+		`CREATE SCHEMA ` + rebuildSchema,
+	}
+	for _, t := range r.order {
+		// Defaults keep drawing ids from the live sequences, so copied rows never collide
+		stmts = append(stmts, fmt.Sprintf(
+			`CREATE TABLE %s.%s (LIKE public.%s INCLUDING DEFAULTS INCLUDING CONSTRAINTS INCLUDING INDEXES)`,
+			rebuildSchema, t, t))
+	}
+	stmts = append(stmts, `SET LOCAL search_path = `+rebuildSchema+`, public`)
+
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	r.tx = tx
+	return nil
+}
+
+// ApplyBalanceEvent replays a legacy balance event into the rebuilt tables
+func (r *ProjectionRebuild) ApplyBalanceEvent(evt *models.BalanceEvent) error {
+	if !r.tables[ProjectionBalances] {
+		return nil
+	}
+	return ApplyBalanceEvent(r.tx, evt)
+}
+
+// ApplyL1Event replays an L1 event into the rebuilt tables
+func (r *ProjectionRebuild) ApplyL1Event(evt *models.L1Event) error {
+	if !r.tables[ProjectionL1Collateral] {
+		return nil
+	}
+	return ProjectL1Event(r.tx, evt)
+}
+
+// ApplyL2Event replays an L2 event into the rebuilt tables
+func (r *ProjectionRebuild) ApplyL2Event(evt *models.L2Event) error {
+	if r.tables[ProjectionL2VaultPositions] {
+		if err := projectL2VaultPosition(r.tx, evt); err != nil {
+			return err
+		}
+	}
+	if r.tables[ProjectionTreasuryHoldings] {
+		return projectL2TreasuryHolding(r.tx, evt)
+	}
+	return nil
+}
+
+// ReplayBalanceEvents replays the stored balance_events log in insertion order.
+// Treasury events are stored without their metadata, so treasury_holdings can
+// only be rebuilt from Kafka.
+func (r *ProjectionRebuild) ReplayBalanceEvents(ctx context.Context) (int, error) {
+	if r.tables[ProjectionTreasuryHoldings] {
+		return 0, errors.New("treasury_holdings cannot be rebuilt from balance_events")
+	}
+
+	const page = 5000
+	var lastID int64
+	total := 0
+	for {
+		rows, err := r.loadBalanceEvents(ctx, lastID, page)
+		if err != nil {
+			return total, err
+		}
+		for _, row := range rows {
+			if err := r.replayRow(row); err != nil {
+				return total, fmt.Errorf("balance_events id %d: %w", row.id, err)
+			}
+			lastID = row.id
+		}
+		total += len(rows)
+		if len(rows) < page {
+			return total, nil
+		}
+	}
+}
+
+// storedEvent is a balance_events row
+type storedEvent struct {
+	id                                            int64
+	user, amount, eventType, txHash, chain, layer string
+	token, contract                               string
+	blockNumber, logIndex                         int64
+}
+
+func (r *ProjectionRebuild) loadBalanceEvents(ctx context.Context, afterID int64, limit int) ([]storedEvent, error) {
+	rows, err := r.tx.QueryContext(ctx, `
+		SELECT id, user_address, amount::TEXT, event_type, COALESCE(tx_hash, ''), COALESCE(chain, ''),
+		       COALESCE(layer, ''), COALESCE(token, ''), COALESCE(contract_address, ''),
+		       COALESCE(block_number, 0), COALESCE(log_index, 0)
+		FROM public.balance_events
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2
+	`, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []storedEvent
+	for rows.Next() {
+		var e storedEvent
+		if err := rows.Scan(&e.id, &e.user, &e.amount, &e.eventType, &e.txHash, &e.chain,
+			&e.layer, &e.token, &e.contract, &e.blockNumber, &e.logIndex); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// replayRow applies one stored event. Compensating entries written for reorgs
+// carry the negated amount and are replayed as the retraction they record.
+func (r *ProjectionRebuild) replayRow(e storedEvent) error {
+	eventType, retracted, amount := e.eventType, "", e.amount
+	if original, ok := strings.CutSuffix(e.eventType, "_retracted"); ok && e.layer != "" {
+		eventType, retracted, amount = "reorg_retraction", original, strings.TrimPrefix(e.amount, "-")
+	}
+
+	switch e.layer {
+	case "L1":
+		return r.ApplyL1Event(&models.L1Event{
+			UserAddress: e.user, Amount: amount, EventType: eventType, RetractedEventType: retracted,
+			Token: e.token, TxHash: e.txHash, BlockNumber: e.blockNumber, LogIndex: uint(e.logIndex),
+			ContractAddress: e.contract, Confirmed: true,
+		})
+	case "L2":
+		return r.ApplyL2Event(&models.L2Event{
+			UserAddress: e.user, Amount: amount, EventType: eventType, RetractedEventType: retracted,
+			TxHash: e.txHash, BlockNumber: e.blockNumber, LogIndex: uint(e.logIndex),
+			ContractAddress: e.contract, Confirmed: true,
+		})
+	default:
+		// Legacy events from events.raw
+		return r.ApplyBalanceEvent(&models.BalanceEvent{
+			UserAddress: e.user, Amount: e.amount, EventType: e.eventType, TxHash: e.txHash,
+			Chain: e.chain, BlockNumber: e.blockNumber, LogIndex: uint(e.logIndex), Confirmed: true,
+		})
+	}
+}
+
+// ProjectionCount compares the row counts of a live and a rebuilt table
+type ProjectionCount struct {
+	Table   string
+	Live    int64
+	Rebuilt int64
+}
+
+// Counts returns the live and rebuilt row counts of every table
+func (r *ProjectionRebuild) Counts(ctx context.Context) ([]ProjectionCount, error) {
+	out := make([]ProjectionCount, 0, len(r.order))
+	for _, t := range r.order {
+		c := ProjectionCount{Table: t}
+		err := r.tx.QueryRowContext(ctx, fmt.Sprintf(
+			`SELECT (SELECT COUNT(*) FROM public.%s), (SELECT COUNT(*) FROM %s.%s)`, t, rebuildSchema, t,
+		)).Scan(&c.Live, &c.Rebuilt)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// Swap replaces the contents of the live tables with the rebuilt ones and
+// commits. The replaced rows are kept in projections_previous.
+func (r *ProjectionRebuild) Swap(ctx context.Context) error {
+	stmts := []string{
+		`SET LOCAL search_path = public`,
+		`DROP SCHEMA IF EXISTS ` + previousSchema + ` CASCADE`,
+		`CREATE SCHEMA ` + previousSchema,
+	}
+	if r.tables[ProjectionBalances] {
+		// Demo balances are seeded directly, not through events
+		stmts = append(stmts, `
+			INSERT INTO `+rebuildSchema+`.balances (user_address, balance, is_demo, updated_at)
+			SELECT user_address, balance, is_demo, updated_at FROM public.balances WHERE is_demo
+			ON CONFLICT (user_address) DO UPDATE SET is_demo = TRUE`)
+	}
+	for _, t := range r.order {
+		stmts = append(stmts,
+			fmt.Sprintf(`CREATE TABLE %s.%s AS TABLE public.%s`, previousSchema, t, t),
+			fmt.Sprintf(`TRUNCATE public.%s`, t),
+			fmt.Sprintf(`INSERT INTO public.%s SELECT * FROM %s.%s`, t, rebuildSchema, t),
+		)
+	}
+	stmts = append(stmts, `DROP SCHEMA `+rebuildSchema+` CASCADE`)
+
+	for _, stmt := range stmts {
+		if _, err := r.tx.ExecContext(ctx, stmt); err != nil {
+			r.tx.Rollback()
+			return err
+		}
+	}
+	return r.tx.Commit()
+}
+
+// Rollback abandons the rebuild and leaves the live tables untouched
+func (r *ProjectionRebuild) Rollback() error {
+	return r.tx.Rollback()
+}
+
+func isProjection(table string) bool {
+	for _, t := range ProjectionTables {
+		if t == table {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"loyalty-points-system/internal/models"
+)
+
+// retractedEventType is the balance_events type of the compensating entry for a retracted event
+func retractedEventType(original string) string {
+	return original + "_retracted"
+}
+
+// insertRetraction records a compensating balance_events entry with the negated amount.
+// Returns false when the original was never applied or the retraction was already recorded.
+func insertRetraction(tx *sql.Tx, layer, userAddress, amount, eventType, txHash string, logIndex uint, blockNumber int64, contract string) (bool, error) {
+	var applied bool
+	err := tx.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM balance_events
+			WHERE tx_hash = $1 AND event_type = $2 AND layer = $3 AND log_index = $4
+		)
+	`, txHash, eventType, layer, int64(logIndex)).Scan(&applied)
+	if err != nil {
+		return false, err
+	}
+	if !applied {
+		return false, nil
+	}
+
+	res, err := tx.Exec(`
+		INSERT INTO balance_events (
+			user_address, amount, event_type, tx_hash, chain, block_number, confirmed,
+			layer, token, contract_address, log_index
+		) VALUES ($1, -CAST($2 AS NUMERIC), $3, $4, $5, $6, TRUE, $5, '', $7, $8)
+		ON CONFLICT (tx_hash, log_index, event_type) DO NOTHING
+	`, userAddress, amount, retractedEventType(eventType), txHash, layer, blockNumber, contract, int64(logIndex))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// reinstateIfRetracted handles an event applied again after its block was
+// reorged out and the transaction re-included. Its balance_events entry is
+// still there (res inserted nothing), so the compensating entry is removed
+// instead, keeping balance_events in step with the projections.
+func reinstateIfRetracted(tx *sql.Tx, res sql.Result, layer, txHash string, logIndex uint, eventType string) error {
+	n, err := res.RowsAffected()
+	if err != nil || n > 0 {
+		return err
+	}
+	_, err = tx.Exec(`
+		DELETE FROM balance_events
+		WHERE tx_hash = $1 AND log_index = $2 AND event_type = $3 AND layer = $4
+	`, txHash, int64(logIndex), retractedEventType(eventType), layer)
+	return err
+}
+
+// RetractL1Event applies the compensating entries for an L1 event whose block was reorged out
+func RetractL1Event(tx *sql.Tx, evt *models.L1Event) error {
+	inserted, err := insertRetraction(tx, "L1", evt.UserAddress, evt.Amount, evt.RetractedEventType,
+		evt.TxHash, evt.LogIndex, evt.BlockNumber, evt.ContractAddress)
+	if err != nil {
+		return fmt.Errorf("insert retraction failed: %w", err)
+	}
+	if !inserted {
+		// Never applied, or already compensated
+		return nil
+	}
+
+	// Let the event apply again if the transaction is re-included
+	if err := UnmarkEventProcessed(tx, "L1", evt.TxHash, evt.LogIndex, evt.RetractedEventType); err != nil {
+		return fmt.Errorf("clear processed event failed: %w", err)
+	}
+
+	switch evt.RetractedEventType {
+	case "collateral_deposit":
+		if _, err := tx.Exec(`DELETE FROM l1_collateral_deposits WHERE tx_hash = $1`, evt.TxHash); err != nil {
+			return fmt.Errorf("delete deposit failed: %w", err)
+		}
+		if err := ProjectL1Event(tx, evt); err != nil {
+			return fmt.Errorf("revert balance failed: %w", err)
+		}
+
+	case "collateral_withdraw", "collateral_emergency_withdraw":
+		if _, err := tx.Exec(`DELETE FROM l1_collateral_deposits WHERE tx_hash = $1`, evt.TxHash); err != nil {
+			return fmt.Errorf("delete withdrawal failed: %w", err)
+		}
+		if err := ProjectL1Event(tx, evt); err != nil {
+			return fmt.Errorf("revert balance failed: %w", err)
+		}
+
+	case "state_update":
+		if _, err := tx.Exec(`DELETE FROM l1_state_snapshots WHERE tx_hash = $1`, evt.TxHash); err != nil {
+			return fmt.Errorf("delete state snapshot failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// RetractL2Event applies the compensating entries for an L2 event whose block was reorged out
+func RetractL2Event(tx *sql.Tx, evt *models.L2Event) error {
+	inserted, err := insertRetraction(tx, "L2", evt.UserAddress, evt.Amount, evt.RetractedEventType,
+		evt.TxHash, evt.LogIndex, evt.BlockNumber, evt.ContractAddress)
+	if err != nil {
+		return fmt.Errorf("insert retraction failed: %w", err)
+	}
+	if !inserted {
+		// Never applied, or already compensated
+		return nil
+	}
+
+	// Let the event apply again if the transaction is re-included
+	if err := UnmarkEventProcessed(tx, "L2", evt.TxHash, evt.LogIndex, evt.RetractedEventType); err != nil {
+		return fmt.Errorf("clear processed event failed: %w", err)
+	}
+
+	switch evt.RetractedEventType {
+	case "vault_deposit":
+		if err := projectL2VaultPosition(tx, evt); err != nil {
+			return fmt.Errorf("revert vault position failed: %w", err)
+		}
+
+	case "vault_withdraw":
+		if err := projectL2VaultPosition(tx, evt); err != nil {
+			return fmt.Errorf("revert vault position failed: %w", err)
+		}
+
+	case "rwa_listing_created":
+		if lid, ok := evt.Metadata["listing_id"].(string); ok {
+			if _, err := tx.Exec(`UPDATE l2_rwa_listings SET status = 'cancelled' WHERE listing_id = $1`, lid); err != nil {
+				return fmt.Errorf("cancel RWA listing failed: %w", err)
+			}
+		}
+
+	case "rwa_trade_executed":
+		if err := resolveRWAListingAsset(tx, evt); err != nil {
+			return fmt.Errorf("resolve RWA listing failed: %w", err)
+		}
+		if err := UpsertL2RWAHolding(tx, evt, false); err != nil {
+			return fmt.Errorf("revert RWA holding failed: %w", err)
+		}
+
+	case "rwa_proposal_created":
+		if pid, ok := evt.Metadata["proposal_id"].(string); ok {
+			if _, err := tx.Exec(`DELETE FROM l2_rwa_proposals WHERE proposal_id = $1`, pid); err != nil {
+				return fmt.Errorf("delete RWA proposal failed: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
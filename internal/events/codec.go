@@ -0,0 +1,150 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	k "github.com/segmentio/kafka-go"
+)
+
+// Kafka headers describing an encoded envelope
+const (
+	HeaderContentType   = "content-type"
+	HeaderSchemaVersion = "schema-version"
+	HeaderEventID       = "event-id"
+	HeaderKind          = "kind"
+	HeaderChainID       = "chain-id"
+)
+
+// Codec serializes envelopes
+type Codec interface {
+	// ContentType is written to the content-type header and selects the codec on decode
+	ContentType() string
+	Encode(env *Envelope) ([]byte, error)
+	Decode(data []byte) (*Envelope, error)
+}
+
+// JSONCodec encodes envelopes as JSON
+type JSONCodec struct{}
+
+func (JSONCodec) ContentType() string { return "application/json" }
+
+func (JSONCodec) Encode(env *Envelope) ([]byte, error) {
+	return json.Marshal(env)
+}
+
+func (JSONCodec) Decode(data []byte) (*Envelope, error) {
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, err
+	}
+	return &env, nil
+}
+
+var codecs = map[string]Codec{
+	JSONCodec{}.ContentType():  JSONCodec{},
+	ProtoCodec{}.ContentType(): ProtoCodec{},
+}
+
+// CodecByName returns the codec configured by EVENT_CODEC ("json" or "protobuf")
+func CodecByName(name string) (Codec, error) {
+	switch name {
+	case "", "json":
+		return JSONCodec{}, nil
+	case "protobuf", "proto":
+		return ProtoCodec{}, nil
+	}
+	return nil, fmt.Errorf("unknown event codec %q", name)
+}
+
+// Message encodes env into a Kafka message with the envelope headers plus extra
+func Message(codec Codec, env *Envelope, key []byte, extra ...k.Header) (k.Message, error) {
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+	if err := env.validate(); err != nil {
+		return k.Message{}, err
+	}
+	data, err := codec.Encode(env)
+	if err != nil {
+		return k.Message{}, err
+	}
+
+	headers := []k.Header{
+		{Key: HeaderContentType, Value: []byte(codec.ContentType())},
+		{Key: HeaderSchemaVersion, Value: []byte(strconv.FormatUint(uint64(env.SchemaVersion), 10))},
+		{Key: HeaderEventID, Value: []byte(env.EventID)},
+		{Key: HeaderKind, Value: []byte(env.Kind)},
+		{Key: HeaderChainID, Value: []byte(strconv.FormatInt(env.ChainID, 10))},
+	}
+	return k.Message{Key: key, Value: data, Headers: append(headers, extra...)}, nil
+}
+
+// ErrUnsupported is returned for messages this build cannot interpret:
+// an unknown codec, kind or schema version
+var ErrUnsupported = errors.New("unsupported event")
+
+// Decode reads the envelope of a message expected to carry kind. Messages
+// without a content-type header predate the envelope and are read as the bare
+// JSON payload (schema version 0). Older versions are upgraded through the
+// registry; newer or unknown ones are rejected with ErrUnsupported.
+func Decode(m k.Message, kind Kind, registry *Registry) (*Envelope, error) {
+	var env *Envelope
+	var err error
+
+	contentType := header(m, HeaderContentType)
+	if contentType == "" {
+		env, err = decodeLegacy(m.Value, kind)
+	} else {
+		codec, ok := codecs[contentType]
+		if !ok {
+			return nil, fmt.Errorf("%w: content type %q", ErrUnsupported, contentType)
+		}
+		env, err = codec.Decode(m.Value)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if env.Kind != kind {
+		return nil, fmt.Errorf("%w: got %s, want %s", ErrUnsupported, env.Kind, kind)
+	}
+	if registry == nil {
+		registry = DefaultRegistry
+	}
+	if err := registry.Upgrade(env); err != nil {
+		return nil, err
+	}
+	return env, env.validate()
+}
+
+// decodeLegacy wraps a bare JSON payload in a version 0 envelope
+func decodeLegacy(data []byte, kind Kind) (*Envelope, error) {
+	env := &Envelope{Kind: kind}
+	var err error
+	switch kind {
+	case KindL1:
+		err = json.Unmarshal(data, &env.L1)
+	case KindL2:
+		err = json.Unmarshal(data, &env.L2)
+	case KindBridge:
+		err = json.Unmarshal(data, &env.Bridge)
+	default:
+		return nil, fmt.Errorf("%w: kind %q", ErrUnsupported, kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+func header(m k.Message, key string) string {
+	for _, h := range m.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,218 @@
+package events
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	k "github.com/segmentio/kafka-go"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"loyalty-points-system/internal/models"
+)
+
+func testEnvelopes() []struct {
+	name string
+	env  *Envelope
+} {
+	l1 := &models.L1Event{
+		UserAddress:     "0x1111111111111111111111111111111111111111",
+		Amount:          "1500000",
+		EventType:       "collateral_deposit",
+		Token:           "USDC",
+		TxHash:          "0xaaaa",
+		BlockNumber:     19000000,
+		BlockHash:       "0xbbbb",
+		TxIndex:         3,
+		LogIndex:        7,
+		Sequence:        19000000<<20 | 7,
+		Confirmed:       true,
+		Finality:        "final",
+		Timestamp:       1700000000,
+		ContractAddress: "0x2222222222222222222222222222222222222222",
+		L2TxHash:        "0xcccc",
+		Metadata: map[string]interface{}{
+			"token_address": "0x3333333333333333333333333333333333333333",
+			"decimals":      float64(6),
+			"bridged":       true,
+			"path":          []interface{}{"l1", "l2"},
+			"fees":          map[string]interface{}{"gas": "21000"},
+		},
+	}
+
+	retraction := &models.L1Event{
+		UserAddress:        l1.UserAddress,
+		Amount:             l1.Amount,
+		EventType:          "reorg_retraction",
+		Token:              l1.Token,
+		TxHash:             l1.TxHash,
+		BlockNumber:        l1.BlockNumber,
+		BlockHash:          l1.BlockHash,
+		LogIndex:           l1.LogIndex,
+		Timestamp:          l1.Timestamp,
+		ContractAddress:    l1.ContractAddress,
+		RetractedEventType: "collateral_deposit",
+	}
+
+	l2 := &models.L2Event{
+		UserAddress:     "0x4444444444444444444444444444444444444444",
+		Amount:          "250.5",
+		EventType:       "vault_deposit",
+		TxHash:          "0xdddd",
+		BlockNumber:     150000000,
+		BlockHash:       "0xeeee",
+		TxIndex:         1,
+		LogIndex:        2,
+		Sequence:        150000000<<20 | 2,
+		Confirmed:       true,
+		Finality:        "safe",
+		Timestamp:       1700000100,
+		ContractAddress: "0x5555555555555555555555555555555555555555",
+		Metadata:        map[string]interface{}{"protocol": "aave", "asset_id": float64(42)},
+	}
+
+	l2Retraction := &models.L2Event{
+		UserAddress:        l2.UserAddress,
+		Amount:             l2.Amount,
+		EventType:          "reorg_retraction",
+		TxHash:             l2.TxHash,
+		BlockNumber:        l2.BlockNumber,
+		LogIndex:           l2.LogIndex,
+		Timestamp:          l2.Timestamp,
+		ContractAddress:    l2.ContractAddress,
+		RetractedEventType: "vault_deposit",
+	}
+
+	bridge := &models.BridgeEvent{
+		UserAddress:   "0x6666666666666666666666666666666666666666",
+		Amount:        "1000000000000000000",
+		Direction:     "L2_TO_L1",
+		Status:        "failed",
+		L1TxHash:      "0xffff",
+		L2TxHash:      "0x9999",
+		MessageHash:   "0x8888",
+		L1BlockNumber: 19000100,
+		L2BlockNumber: 150000100,
+		InitiatedAt:   1700000200,
+		ConfirmedAt:   1700000300,
+		RetryCount:    3,
+		ErrorMsg:      "execution reverted",
+	}
+
+	return []struct {
+		name string
+		env  *Envelope
+	}{
+		{"l1", NewL1Envelope(1, l1)},
+		{"l1 retraction", NewL1Envelope(1, retraction)},
+		{"l2", NewL2Envelope(42161, l2)},
+		{"l2 retraction", NewL2Envelope(42161, l2Retraction)},
+		{"bridge", NewBridgeEnvelope(42161, bridge)},
+	}
+}
+
+func TestCodecsRoundTripEnvelopes(t *testing.T) {
+	for _, codec := range []Codec{JSONCodec{}, ProtoCodec{}} {
+		for _, tc := range testEnvelopes() {
+			t.Run(codec.ContentType()+"/"+tc.name, func(t *testing.T) {
+				data, err := codec.Encode(tc.env)
+				if err != nil {
+					t.Fatalf("encode: %v", err)
+				}
+				got, err := codec.Decode(data)
+				if err != nil {
+					t.Fatalf("decode: %v", err)
+				}
+				if !reflect.DeepEqual(got, tc.env) {
+					t.Fatalf("round trip mismatch\n got: %+v\nwant: %+v", got, tc.env)
+				}
+			})
+		}
+	}
+}
+
+func TestDecodeMessage(t *testing.T) {
+	for _, codec := range []Codec{JSONCodec{}, ProtoCodec{}} {
+		for _, tc := range testEnvelopes() {
+			t.Run(codec.ContentType()+"/"+tc.name, func(t *testing.T) {
+				m, err := Message(codec, tc.env, []byte("key"))
+				if err != nil {
+					t.Fatalf("message: %v", err)
+				}
+				got, err := Decode(m, tc.env.Kind, nil)
+				if err != nil {
+					t.Fatalf("decode: %v", err)
+				}
+				if !reflect.DeepEqual(got, tc.env) {
+					t.Fatalf("decoded mismatch\n got: %+v\nwant: %+v", got, tc.env)
+				}
+			})
+		}
+	}
+}
+
+func TestDecodeRejectsUnknownVersion(t *testing.T) {
+	for _, codec := range []Codec{JSONCodec{}, ProtoCodec{}} {
+		for _, tc := range testEnvelopes() {
+			t.Run(codec.ContentType()+"/"+tc.name, func(t *testing.T) {
+				env := *tc.env
+				env.SchemaVersion = SchemaVersion + 1
+				m, err := Message(codec, &env, nil)
+				if err != nil {
+					t.Fatalf("message: %v", err)
+				}
+				if _, err := Decode(m, env.Kind, nil); !errors.Is(err, ErrUnsupported) {
+					t.Fatalf("decode version %d: got %v, want ErrUnsupported", env.SchemaVersion, err)
+				}
+			})
+		}
+	}
+}
+
+func TestDecodeRejectsUnknownContentTypeAndKind(t *testing.T) {
+	env := testEnvelopes()[0].env
+	m, err := Message(JSONCodec{}, env, nil)
+	if err != nil {
+		t.Fatalf("message: %v", err)
+	}
+
+	if _, err := Decode(m, KindL2, nil); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("decode as %s: got %v, want ErrUnsupported", KindL2, err)
+	}
+
+	m.Headers[0] = k.Header{Key: HeaderContentType, Value: []byte("application/avro")}
+	if _, err := Decode(m, KindL1, nil); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("decode avro: got %v, want ErrUnsupported", err)
+	}
+}
+
+func TestDecodeLegacyPayload(t *testing.T) {
+	m := k.Message{Value: []byte(`{"user_address":"0x1111111111111111111111111111111111111111","amount":"5","event_type":"vault_deposit","tx_hash":"0xabc","block_number":10,"log_index":4,"timestamp":1700000000}`)}
+	env, err := Decode(m, KindL2, nil)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if env.SchemaVersion != SchemaVersion || env.EventID != "0:0xabc:4:vault_deposit" || env.BlockNumber != 10 || env.EmittedAt != 1700000000 {
+		t.Fatalf("legacy envelope not upgraded: %+v", env)
+	}
+}
+
+func TestProtoDecodeSkipsUnknownFields(t *testing.T) {
+	env := testEnvelopes()[2].env
+	data, err := ProtoCodec{}.Encode(env)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	data = protowire.AppendTag(data, 99, protowire.Fixed64Type)
+	data = protowire.AppendFixed64(data, 1)
+	data = protowire.AppendTag(data, 100, protowire.BytesType)
+	data = protowire.AppendString(data, "added later")
+
+	got, err := ProtoCodec{}.Decode(data)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !reflect.DeepEqual(got, env) {
+		t.Fatalf("unknown fields changed the envelope\n got: %+v\nwant: %+v", got, env)
+	}
+}
@@ -0,0 +1,117 @@
+package events
+
+import (
+	"fmt"
+	"time"
+
+	"loyalty-points-system/internal/models"
+)
+
+// SchemaVersion is the envelope version written by this build
+const SchemaVersion uint32 = 1
+
+// Kind identifies the payload carried by an envelope
+type Kind string
+
+const (
+	KindL1     Kind = "l1_event"
+	KindL2     Kind = "l2_event"
+	KindBridge Kind = "bridge_event"
+)
+
+// Envelope wraps every event published to Kafka with its identity, schema
+// version and chain coordinates. Exactly one payload field is set, matching Kind.
+type Envelope struct {
+	EventID       string `json:"event_id"`
+	SchemaVersion uint32 `json:"schema_version"`
+	Kind          Kind   `json:"kind"`
+	ChainID       int64  `json:"chain_id"`
+	BlockNumber   uint64 `json:"block_number"`
+	BlockHash     string `json:"block_hash,omitempty"`
+	TxHash        string `json:"tx_hash"`
+	TxIndex       uint32 `json:"tx_index"`
+	LogIndex      uint32 `json:"log_index"`
+	EmittedAt     int64  `json:"emitted_at"`
+
+	L1     *models.L1Event     `json:"l1,omitempty"`
+	L2     *models.L2Event     `json:"l2,omitempty"`
+	Bridge *models.BridgeEvent `json:"bridge,omitempty"`
+}
+
+// chainEventID identifies one event of one log. The event type is part of the
+// id because a reorg retraction shares the log of the event it compensates.
+func chainEventID(chainID int64, txHash string, logIndex uint, eventType string) string {
+	return fmt.Sprintf("%d:%s:%d:%s", chainID, txHash, logIndex, eventType)
+}
+
+// NewL1Envelope wraps an L1 event observed on chainID
+func NewL1Envelope(chainID int64, evt *models.L1Event) *Envelope {
+	return &Envelope{
+		EventID:       chainEventID(chainID, evt.TxHash, evt.LogIndex, evt.EventType),
+		SchemaVersion: SchemaVersion,
+		Kind:          KindL1,
+		ChainID:       chainID,
+		BlockNumber:   uint64(evt.BlockNumber),
+		BlockHash:     evt.BlockHash,
+		TxHash:        evt.TxHash,
+		TxIndex:       uint32(evt.TxIndex),
+		LogIndex:      uint32(evt.LogIndex),
+		EmittedAt:     time.Now().Unix(),
+		L1:            evt,
+	}
+}
+
+// NewL2Envelope wraps an L2 event observed on chainID
+func NewL2Envelope(chainID int64, evt *models.L2Event) *Envelope {
+	return &Envelope{
+		EventID:       chainEventID(chainID, evt.TxHash, evt.LogIndex, evt.EventType),
+		SchemaVersion: SchemaVersion,
+		Kind:          KindL2,
+		ChainID:       chainID,
+		BlockNumber:   uint64(evt.BlockNumber),
+		BlockHash:     evt.BlockHash,
+		TxHash:        evt.TxHash,
+		TxIndex:       uint32(evt.TxIndex),
+		LogIndex:      uint32(evt.LogIndex),
+		EmittedAt:     time.Now().Unix(),
+		L2:            evt,
+	}
+}
+
+// NewBridgeEnvelope wraps a bridge message status change. chainID is the
+// chain the message was initiated on; one event exists per message and status.
+func NewBridgeEnvelope(chainID int64, evt *models.BridgeEvent) *Envelope {
+	block, tx := evt.L1BlockNumber, evt.L1TxHash
+	if evt.Direction == "L2_TO_L1" {
+		block, tx = evt.L2BlockNumber, evt.L2TxHash
+	}
+	return &Envelope{
+		EventID:       fmt.Sprintf("bridge:%s:%s", evt.MessageHash, evt.Status),
+		SchemaVersion: SchemaVersion,
+		Kind:          KindBridge,
+		ChainID:       chainID,
+		BlockNumber:   uint64(block),
+		TxHash:        tx,
+		EmittedAt:     time.Now().Unix(),
+		Bridge:        evt,
+	}
+}
+
+// validate checks that the payload matches the kind
+func (e *Envelope) validate() error {
+	ok := false
+	switch e.Kind {
+	case KindL1:
+		ok = e.L1 != nil
+	case KindL2:
+		ok = e.L2 != nil
+	case KindBridge:
+		ok = e.Bridge != nil
+	default:
+		return fmt.Errorf("unknown event kind %q", e.Kind)
+	}
+	if !ok {
+		return fmt.Errorf("envelope %s has no %s payload", e.EventID, e.Kind)
+	}
+	return nil
+}
@@ -0,0 +1,382 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"loyalty-points-system/internal/models"
+)
+
+// ProtoCodec encodes envelopes in the Protobuf wire format described by
+// envelope.proto. Metadata values are carried as JSON strings so their types
+// survive the round trip. Unknown fields are skipped on decode, so fields can
+// be added without a version bump.
+type ProtoCodec struct{}
+
+func (ProtoCodec) ContentType() string { return "application/x-protobuf" }
+
+func (ProtoCodec) Encode(env *Envelope) ([]byte, error) {
+	var e protoEncoder
+	e.string(1, env.EventID)
+	e.uint(2, uint64(env.SchemaVersion))
+	e.string(3, string(env.Kind))
+	e.int(4, env.ChainID)
+	e.uint(5, env.BlockNumber)
+	e.string(6, env.BlockHash)
+	e.string(7, env.TxHash)
+	e.uint(8, uint64(env.TxIndex))
+	e.uint(9, uint64(env.LogIndex))
+	e.int(10, env.EmittedAt)
+
+	switch {
+	case env.L1 != nil:
+		b, err := encodeChainEvent(chainEventFromL1(env.L1))
+		if err != nil {
+			return nil, err
+		}
+		e.message(20, b)
+	case env.L2 != nil:
+		b, err := encodeChainEvent(chainEventFromL2(env.L2))
+		if err != nil {
+			return nil, err
+		}
+		e.message(21, b)
+	case env.Bridge != nil:
+		e.message(22, encodeBridgeEvent(env.Bridge))
+	}
+	return e.b, nil
+}
+
+func (ProtoCodec) Decode(data []byte) (*Envelope, error) {
+	env := &Envelope{}
+	err := decodeFields(data, func(num protowire.Number, v []byte, x uint64) error {
+		switch num {
+		case 1:
+			env.EventID = string(v)
+		case 2:
+			env.SchemaVersion = uint32(x)
+		case 3:
+			env.Kind = Kind(v)
+		case 4:
+			env.ChainID = int64(x)
+		case 5:
+			env.BlockNumber = x
+		case 6:
+			env.BlockHash = string(v)
+		case 7:
+			env.TxHash = string(v)
+		case 8:
+			env.TxIndex = uint32(x)
+		case 9:
+			env.LogIndex = uint32(x)
+		case 10:
+			env.EmittedAt = int64(x)
+		case 20, 21:
+			ce, err := decodeChainEvent(v)
+			if err != nil {
+				return err
+			}
+			if num == 20 {
+				env.L1 = ce.toL1()
+			} else {
+				env.L2 = ce.toL2()
+			}
+		case 22:
+			be, err := decodeBridgeEvent(v)
+			if err != nil {
+				return err
+			}
+			env.Bridge = be
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// chainEvent is the wire form shared by L1 and L2 events
+type chainEvent struct {
+	UserAddress, Amount, EventType, Token, TxHash string
+	BlockNumber                                   int64
+	BlockHash                                     string
+	TxIndex, LogIndex                             uint
+	Sequence                                      uint64
+	Confirmed                                     bool
+	Timestamp                                     int64
+	ContractAddress, L2TxHash                     string
+	Metadata                                      map[string]interface{}
+	RetractedEventType                            string
+	Finality                                      string
+}
+
+func chainEventFromL1(e *models.L1Event) *chainEvent {
+	return &chainEvent{
+		UserAddress: e.UserAddress, Amount: e.Amount, EventType: e.EventType, Token: e.Token,
+		TxHash: e.TxHash, BlockNumber: e.BlockNumber, BlockHash: e.BlockHash,
+		TxIndex: e.TxIndex, LogIndex: e.LogIndex, Sequence: e.Sequence,
+		Confirmed: e.Confirmed, Timestamp: e.Timestamp, ContractAddress: e.ContractAddress,
+		L2TxHash: e.L2TxHash, Metadata: e.Metadata, RetractedEventType: e.RetractedEventType,
+		Finality: e.Finality,
+	}
+}
+
+func chainEventFromL2(e *models.L2Event) *chainEvent {
+	return &chainEvent{
+		UserAddress: e.UserAddress, Amount: e.Amount, EventType: e.EventType,
+		TxHash: e.TxHash, BlockNumber: e.BlockNumber, BlockHash: e.BlockHash,
+		TxIndex: e.TxIndex, LogIndex: e.LogIndex, Sequence: e.Sequence,
+		Confirmed: e.Confirmed, Timestamp: e.Timestamp, ContractAddress: e.ContractAddress,
+		Metadata: e.Metadata, RetractedEventType: e.RetractedEventType, Finality: e.Finality,
+	}
+}
+
+func (c *chainEvent) toL1() *models.L1Event {
+	return &models.L1Event{
+		UserAddress: c.UserAddress, Amount: c.Amount, EventType: c.EventType, Token: c.Token,
+		TxHash: c.TxHash, BlockNumber: c.BlockNumber, BlockHash: c.BlockHash,
+		TxIndex: c.TxIndex, LogIndex: c.LogIndex, Sequence: c.Sequence,
+		Confirmed: c.Confirmed, Timestamp: c.Timestamp, ContractAddress: c.ContractAddress,
+		L2TxHash: c.L2TxHash, Metadata: c.Metadata, RetractedEventType: c.RetractedEventType,
+		Finality: c.Finality,
+	}
+}
+
+func (c *chainEvent) toL2() *models.L2Event {
+	return &models.L2Event{
+		UserAddress: c.UserAddress, Amount: c.Amount, EventType: c.EventType,
+		TxHash: c.TxHash, BlockNumber: c.BlockNumber, BlockHash: c.BlockHash,
+		TxIndex: c.TxIndex, LogIndex: c.LogIndex, Sequence: c.Sequence,
+		Confirmed: c.Confirmed, Timestamp: c.Timestamp, ContractAddress: c.ContractAddress,
+		Metadata: c.Metadata, RetractedEventType: c.RetractedEventType, Finality: c.Finality,
+	}
+}
+
+func encodeChainEvent(c *chainEvent) ([]byte, error) {
+	var e protoEncoder
+	e.string(1, c.UserAddress)
+	e.string(2, c.Amount)
+	e.string(3, c.EventType)
+	e.string(4, c.Token)
+	e.string(5, c.TxHash)
+	e.int(6, c.BlockNumber)
+	e.string(7, c.BlockHash)
+	e.uint(8, uint64(c.TxIndex))
+	e.uint(9, uint64(c.LogIndex))
+	e.uint(10, c.Sequence)
+	e.bool(11, c.Confirmed)
+	e.int(12, c.Timestamp)
+	e.string(13, c.ContractAddress)
+	e.string(14, c.L2TxHash)
+	for key, val := range c.Metadata {
+		raw, err := json.Marshal(val)
+		if err != nil {
+			return nil, fmt.Errorf("metadata %s: %w", key, err)
+		}
+		var entry protoEncoder
+		entry.string(1, key)
+		entry.string(2, string(raw))
+		e.message(15, entry.b)
+	}
+	e.string(16, c.RetractedEventType)
+	e.string(17, c.Finality)
+	return e.b, nil
+}
+
+func decodeChainEvent(b []byte) (*chainEvent, error) {
+	c := &chainEvent{}
+	err := decodeFields(b, func(num protowire.Number, v []byte, x uint64) error {
+		switch num {
+		case 1:
+			c.UserAddress = string(v)
+		case 2:
+			c.Amount = string(v)
+		case 3:
+			c.EventType = string(v)
+		case 4:
+			c.Token = string(v)
+		case 5:
+			c.TxHash = string(v)
+		case 6:
+			c.BlockNumber = int64(x)
+		case 7:
+			c.BlockHash = string(v)
+		case 8:
+			c.TxIndex = uint(x)
+		case 9:
+			c.LogIndex = uint(x)
+		case 10:
+			c.Sequence = x
+		case 11:
+			c.Confirmed = x != 0
+		case 12:
+			c.Timestamp = int64(x)
+		case 13:
+			c.ContractAddress = string(v)
+		case 14:
+			c.L2TxHash = string(v)
+		case 15:
+			var key, raw string
+			err := decodeFields(v, func(num protowire.Number, v []byte, _ uint64) error {
+				switch num {
+				case 1:
+					key = string(v)
+				case 2:
+					raw = string(v)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			var val interface{}
+			if err := json.Unmarshal([]byte(raw), &val); err != nil {
+				return fmt.Errorf("metadata %s: %w", key, err)
+			}
+			if c.Metadata == nil {
+				c.Metadata = make(map[string]interface{})
+			}
+			c.Metadata[key] = val
+		case 16:
+			c.RetractedEventType = string(v)
+		case 17:
+			c.Finality = string(v)
+		}
+		return nil
+	})
+	return c, err
+}
+
+func encodeBridgeEvent(b *models.BridgeEvent) []byte {
+	var e protoEncoder
+	e.string(1, b.UserAddress)
+	e.string(2, b.Amount)
+	e.string(3, b.Direction)
+	e.string(4, b.Status)
+	e.string(5, b.L1TxHash)
+	e.string(6, b.L2TxHash)
+	e.string(7, b.MessageHash)
+	e.int(8, b.L1BlockNumber)
+	e.int(9, b.L2BlockNumber)
+	e.int(10, b.InitiatedAt)
+	e.int(11, b.ConfirmedAt)
+	e.int(12, int64(b.RetryCount))
+	e.string(13, b.ErrorMsg)
+	return e.b
+}
+
+func decodeBridgeEvent(data []byte) (*models.BridgeEvent, error) {
+	b := &models.BridgeEvent{}
+	err := decodeFields(data, func(num protowire.Number, v []byte, x uint64) error {
+		switch num {
+		case 1:
+			b.UserAddress = string(v)
+		case 2:
+			b.Amount = string(v)
+		case 3:
+			b.Direction = string(v)
+		case 4:
+			b.Status = string(v)
+		case 5:
+			b.L1TxHash = string(v)
+		case 6:
+			b.L2TxHash = string(v)
+		case 7:
+			b.MessageHash = string(v)
+		case 8:
+			b.L1BlockNumber = int64(x)
+		case 9:
+			b.L2BlockNumber = int64(x)
+		case 10:
+			b.InitiatedAt = int64(x)
+		case 11:
+			b.ConfirmedAt = int64(x)
+		case 12:
+			b.RetryCount = int(int64(x))
+		case 13:
+			b.ErrorMsg = string(v)
+		}
+		return nil
+	})
+	return b, err
+}
+
+// protoEncoder appends fields in wire format, omitting zero values like proto3
+type protoEncoder struct {
+	b []byte
+}
+
+func (e *protoEncoder) string(num protowire.Number, v string) {
+	if v == "" {
+		return
+	}
+	e.b = protowire.AppendTag(e.b, num, protowire.BytesType)
+	e.b = protowire.AppendString(e.b, v)
+}
+
+func (e *protoEncoder) uint(num protowire.Number, v uint64) {
+	if v == 0 {
+		return
+	}
+	e.b = protowire.AppendTag(e.b, num, protowire.VarintType)
+	e.b = protowire.AppendVarint(e.b, v)
+}
+
+// int encodes an int64 field (two's complement varint, as protobuf int64)
+func (e *protoEncoder) int(num protowire.Number, v int64) {
+	e.uint(num, uint64(v))
+}
+
+func (e *protoEncoder) bool(num protowire.Number, v bool) {
+	if v {
+		e.uint(num, 1)
+	}
+}
+
+func (e *protoEncoder) message(num protowire.Number, b []byte) {
+	e.b = protowire.AppendTag(e.b, num, protowire.BytesType)
+	e.b = protowire.AppendBytes(e.b, b)
+}
+
+// decodeFields calls fn for every varint and length-delimited field of a
+// message; other wire types are skipped
+func decodeFields(b []byte, fn func(num protowire.Number, v []byte, x uint64) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		switch typ {
+		case protowire.VarintType:
+			x, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			if err := fn(num, nil, x); err != nil {
+				return err
+			}
+			b = b[n:]
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			if err := fn(num, v, 0); err != nil {
+				return err
+			}
+			b = b[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+		}
+	}
+	return nil
+}
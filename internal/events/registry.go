@@ -0,0 +1,135 @@
+package events
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Schema describes one version of an event kind
+type Schema struct {
+	Kind    Kind
+	Version uint32
+	Subject string // registry subject, e.g. "l1_event-v1"
+
+	// Upgrade converts an envelope of this version to Version+1.
+	// Nil for the latest version.
+	Upgrade func(env *Envelope) error
+}
+
+// Registry is an in-process stand-in for a schema registry. It records the
+// known versions of every kind and upgrades older envelopes to the latest one,
+// so producers can roll out a new version before or after the consumers.
+type Registry struct {
+	mu      sync.RWMutex
+	schemas map[Kind]map[uint32]Schema
+}
+
+// NewRegistry creates a registry with the built-in schemas
+func NewRegistry() *Registry {
+	r := &Registry{schemas: make(map[Kind]map[uint32]Schema)}
+	for _, kind := range []Kind{KindL1, KindL2, KindBridge} {
+		r.Register(Schema{Kind: kind, Version: 0, Upgrade: upgradeLegacy})
+		r.Register(Schema{Kind: kind, Version: 1})
+	}
+	return r
+}
+
+// DefaultRegistry is used by Decode when no registry is given
+var DefaultRegistry = NewRegistry()
+
+// Register adds or replaces a schema version
+func (r *Registry) Register(s Schema) {
+	if s.Subject == "" {
+		s.Subject = fmt.Sprintf("%s-v%d", s.Kind, s.Version)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.schemas[s.Kind] == nil {
+		r.schemas[s.Kind] = make(map[uint32]Schema)
+	}
+	r.schemas[s.Kind][s.Version] = s
+}
+
+// Latest returns the newest registered version of a kind
+func (r *Registry) Latest(kind Kind) (uint32, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var latest uint32
+	found := false
+	for v := range r.schemas[kind] {
+		if !found || v > latest {
+			latest, found = v, true
+		}
+	}
+	return latest, found
+}
+
+// Schemas lists the registered versions of a kind
+func (r *Registry) Schemas(kind Kind) []Schema {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]Schema, 0, len(r.schemas[kind]))
+	for _, s := range r.schemas[kind] {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out
+}
+
+// Upgrade brings env to the latest registered version of its kind.
+// Versions newer than the latest, or without an upgrade path, are rejected.
+func (r *Registry) Upgrade(env *Envelope) error {
+	latest, ok := r.Latest(env.Kind)
+	if !ok {
+		return fmt.Errorf("%w: kind %q", ErrUnsupported, env.Kind)
+	}
+	if env.SchemaVersion > latest {
+		return fmt.Errorf("%w: %s version %d is newer than %d", ErrUnsupported, env.Kind, env.SchemaVersion, latest)
+	}
+
+	for env.SchemaVersion < latest {
+		r.mu.RLock()
+		s, ok := r.schemas[env.Kind][env.SchemaVersion]
+		r.mu.RUnlock()
+		if !ok || s.Upgrade == nil {
+			return fmt.Errorf("%w: no upgrade from %s version %d", ErrUnsupported, env.Kind, env.SchemaVersion)
+		}
+		if err := s.Upgrade(env); err != nil {
+			return fmt.Errorf("upgrade %s version %d: %w", env.Kind, env.SchemaVersion, err)
+		}
+		env.SchemaVersion++
+	}
+	return nil
+}
+
+// upgradeLegacy fills the envelope of a bare version 0 payload from the event itself
+func upgradeLegacy(env *Envelope) error {
+	switch {
+	case env.L1 != nil:
+		env.EventID = chainEventID(0, env.L1.TxHash, env.L1.LogIndex, env.L1.EventType)
+		env.BlockNumber = uint64(env.L1.BlockNumber)
+		env.BlockHash = env.L1.BlockHash
+		env.TxHash = env.L1.TxHash
+		env.TxIndex = uint32(env.L1.TxIndex)
+		env.LogIndex = uint32(env.L1.LogIndex)
+		env.EmittedAt = env.L1.Timestamp
+	case env.L2 != nil:
+		env.EventID = chainEventID(0, env.L2.TxHash, env.L2.LogIndex, env.L2.EventType)
+		env.BlockNumber = uint64(env.L2.BlockNumber)
+		env.BlockHash = env.L2.BlockHash
+		env.TxHash = env.L2.TxHash
+		env.TxIndex = uint32(env.L2.TxIndex)
+		env.LogIndex = uint32(env.L2.LogIndex)
+		env.EmittedAt = env.L2.Timestamp
+	case env.Bridge != nil:
+		env.EventID = fmt.Sprintf("bridge:%s:%s", env.Bridge.MessageHash, env.Bridge.Status)
+		env.BlockNumber = uint64(env.Bridge.L1BlockNumber)
+		env.TxHash = env.Bridge.L1TxHash
+		env.EmittedAt = env.Bridge.InitiatedAt
+	}
+	return nil
+}
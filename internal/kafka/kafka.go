@@ -6,6 +6,7 @@ import (
   "github.com/segmentio/kafka-go"
   "net"
   "strconv"
+  "strings"
 )
 
 type Message = kafka.Message
@@ -14,12 +15,18 @@ func NewWriter(brokers []string, topic string) *kafka.Writer {
   return &kafka.Writer{
     Addr:         kafka.TCP(brokers...),
     Topic:        topic,
-    Balancer:     &kafka.LeastBytes{},
+    Balancer:     &kafka.Hash{}, // same key -> same partition, so per-user order holds
     RequiredAcks: kafka.RequireAll,
     BatchTimeout: 50 * time.Millisecond,
   }
 }
 
+// UserKey is the message key for events of a user address; keys are
+// case-normalized so checksummed and lowercase addresses share a partition
+func UserKey(address string) []byte {
+  return []byte(strings.ToLower(address))
+}
+
 func EnsureTopic(ctx context.Context, broker string, topic string, partitions int, replication int) error {
   d := &kafka.Dialer{Timeout: 5 * time.Second, DualStack: true}
   conn, err := d.DialContext(ctx, "tcp", broker)
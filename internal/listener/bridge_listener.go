@@ -2,23 +2,27 @@ package listener
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"log"
 	"math/big"
-	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
 	k "github.com/segmentio/kafka-go"
 
+	"loyalty-points-system/internal/events"
+	"loyalty-points-system/internal/metrics"
 	"loyalty-points-system/internal/models"
 )
 
 // BridgeListenerConfig holds configuration for bridge listener
 type BridgeListenerConfig struct {
+	// Prefix of the "-l1"/"-l2" status and checkpoint names (default "bridge").
+	// Must be unique per L1/L2 pair, as several pairs can share one L1.
+	Name string
+
 	L1RPCURL        string
 	L1WSSURL        string
 	L1ChainID       int64
@@ -30,131 +34,277 @@ type BridgeListenerConfig struct {
 	L2ChainID       int64
 	L2Gateway       string
 	L2Confirmations int
+
+	// Historical catch-up
+	L1StartBlock  uint64          // First L1 block to backfill when no checkpoint exists (0 = head)
+	L2StartBlock  uint64          // First L2 block to backfill when no checkpoint exists (0 = head)
+	BackfillBatch uint64          // Max blocks per FilterLogs call
+	Checkpoints   CheckpointStore // Optional; without it the listener starts from head
+
+	// Durable bridge messages; each observed leg is merged into them by hash
+	Messages BridgeStore
+
+	// Poll mode, used when a WSS URL is empty or unreachable
+	PollInterval time.Duration // eth_blockNumber polling interval (0 = DefaultPollInterval)
+
+	Codec events.Codec // Envelope encoding (nil = JSON)
 }
 
-// BridgeListener listens to bridge events on both L1 and L2
+// BridgeStore merges the legs the bridge listener observes into durable bridge
+// messages (see models.MergeBridgeLeg) and returns the merged message and
+// whether the leg confirmed it
+type BridgeStore interface {
+	RecordBridgeLeg(ctx context.Context, leg *models.BridgeEvent) (*models.BridgeEvent, bool, error)
+}
+
+// BridgeListener listens to bridge events on both L1 and L2. It keeps no
+// message state of its own: every leg is recorded in the BridgeStore, where
+// the L1 and L2 sides meet by message hash, and the merged message is
+// published. Timeouts are left to the bridge monitor.
 type BridgeListener struct {
-	cfg        BridgeListenerConfig
-	l1Client   *ethclient.Client
-	l2Client   *ethclient.Client
-	writer     Writer
-	pending    map[string]*models.BridgeEvent // keyed by message hash
-	pendingMu  sync.Mutex
+	cfg      BridgeListenerConfig
+	l1Client chainClient
+	l2Client chainClient
+	writer   Writer
+
+	// Block cursors for backfill and checkpointing
+	l1Cursor *BlockCursor
+	l2Cursor *BlockCursor
+
+	// Live subscriptions per chain, replaced on every reconnect
+	l1Session *bridgeSession
+	l2Session *bridgeSession
+	l1Head    uint64
+	l2Head    uint64
+	l1Health  *health
+	l2Health  *health
+}
+
+// bridgeSession holds the subscriptions of one chain between reconnects
+type bridgeSession struct {
+	subs         *subscriptionSet
+	headers      chan *types.Header
+	logs         chan types.Log
+	contracts    []common.Address
+	backfilledTo uint64
 }
 
 // NewBridgeListener creates a new bridge listener
 func NewBridgeListener(cfg BridgeListenerConfig, writer Writer) *BridgeListener {
+	name := listenerName(cfg.Name, "bridge")
 	return &BridgeListener{
-		cfg:     cfg,
-		writer:  writer,
-		pending: make(map[string]*models.BridgeEvent),
+		cfg:      cfg,
+		writer:   writer,
+		l1Cursor: NewBlockCursor(cfg.Checkpoints, name+"-l1", cfg.L1ChainID, cfg.L1StartBlock, cfg.BackfillBatch),
+		l2Cursor: NewBlockCursor(cfg.Checkpoints, name+"-l2", cfg.L2ChainID, cfg.L2StartBlock, cfg.BackfillBatch),
+		l1Health: newHealth(name+"-l1", 2*time.Minute),
+		l2Health: newHealth(name+"-l2", time.Minute),
 	}
 }
 
 // Start begins listening to bridge events on both chains
 func (l *BridgeListener) Start(ctx context.Context) error {
-	var err error
+	if l.cfg.Messages == nil {
+		return errors.New("bridge listener needs a message store")
+	}
 
 	// Connect to L1
-	l.l1Client, err = ethclient.DialContext(ctx, l.cfg.L1WSSURL)
-	if err != nil {
+	if err := l.connectL1(ctx); err != nil {
 		return err
 	}
-	log.Printf("🔌 [Bridge] Connected to L1 at %s", l.cfg.L1WSSURL)
 
 	// Connect to L2
-	l.l2Client, err = ethclient.DialContext(ctx, l.cfg.L2WSSURL)
-	if err != nil {
+	if err := l.connectL2(ctx); err != nil {
 		return err
 	}
-	log.Printf("🔌 [Bridge] Connected to L2 at %s", l.cfg.L2WSSURL)
 
 	// Start L1 bridge listener
-	go l.listenL1Bridge(ctx)
+	go l.supervise(ctx, l.l1Health, "Bridge-L1", l.listenL1Bridge, l.connectL1)
 
 	// Start L2 bridge listener
-	go l.listenL2Bridge(ctx)
-
-	// Start status updater (checks pending messages)
-	go l.updateBridgeStatus(ctx)
+	go l.supervise(ctx, l.l2Health, "Bridge-L2", l.listenL2Bridge, l.connectL2)
 
 	return nil
 }
 
-// listenL1Bridge listens to L1 gateway events
-func (l *BridgeListener) listenL1Bridge(ctx context.Context) {
-	headers := make(chan *types.Header, 32)
-	subHead, err := l.l1Client.SubscribeNewHead(ctx, headers)
-	if err != nil {
-		log.Printf("❌ [Bridge-L1] Failed to subscribe to headers: %v", err)
-		return
-	}
+// Status returns the connection state of the L1 and L2 sides
+func (l *BridgeListener) Status() []Status {
+	return []Status{l.l1Health.snapshot(), l.l2Health.snapshot()}
+}
 
-	logsCh := make(chan types.Log, 256)
-	addr := common.HexToAddress(l.cfg.L1Gateway)
+// supervise runs one side's event loop and re-dials whenever a subscription drops
+func (l *BridgeListener) supervise(ctx context.Context, h *health, tag string,
+	listen func(context.Context) error, connect func(context.Context) error) {
+	for {
+		err := listen(ctx)
 
-	query := ethereum.FilterQuery{
-		Addresses: []common.Address{addr},
+		if ctx.Err() != nil {
+			h.set(StateStopped, nil)
+			log.Printf("🛑 [%s] Listener stopped", tag)
+			return
+		}
+
+		h.set(StateReconnecting, err)
+		if !reconnect(ctx, h, connect) {
+			h.set(StateStopped, nil)
+			log.Printf("🛑 [%s] Listener stopped", tag)
+			return
+		}
 	}
+}
 
-	subLogs, err := l.l1Client.SubscribeFilterLogs(ctx, query, logsCh)
+// connectL1 dials L1, subscribes to heads and Gateway logs, and backfills the
+// blocks missed since the checkpoint (first connection) or the last seen head
+func (l *BridgeListener) connectL1(ctx context.Context) error {
+	client, err := dialChain(ctx, "Bridge-L1", l.cfg.L1WSSURL, l.cfg.L1RPCURL, l.cfg.PollInterval)
 	if err != nil {
-		log.Printf("❌ [Bridge-L1] Failed to subscribe to logs: %v", err)
-		return
+		return err
 	}
+	if l.l1Client != nil {
+		l.l1Client.Close()
+	}
+	l.l1Client = client
 
-	log.Printf("👂 [Bridge-L1] Subscribed to Gateway events at %s", addr.Hex())
-
-	conf := l.cfg.L1Confirmations
-	if conf <= 0 {
-		conf = 12
+	addr := common.HexToAddress(l.cfg.L1Gateway)
+	sess, err := l.subscribe(ctx, client, "Bridge-L1", addr)
+	if err != nil {
+		return err
 	}
 
-	for {
-		select {
-		case err := <-subHead.Err():
-			log.Printf("❌ [Bridge-L1] header subscription error: %v", err)
-			return
-		case err := <-subLogs.Err():
-			log.Printf("❌ [Bridge-L1] logs subscription error: %v", err)
-			return
-		case <-headers:
-			// Block confirmation handled separately
-		case lg := <-logsCh:
-			l.onL1BridgeLog(lg)
-		case <-ctx.Done():
-			log.Println("🛑 [Bridge-L1] Listener stopped")
-			return
-		}
+	if err := l.catchUp(ctx, client, l.l1Cursor, sess, l.l1Head, l.onL1BridgeLog); err != nil {
+		sess.subs.close()
+		return err
 	}
+
+	l.l1Session = sess
+	l.l1Head = sess.backfilledTo
+	l.l1Health.head(sess.backfilledTo)
+	l.l1Health.set(StateConnected, nil)
+	return nil
 }
 
-// listenL2Bridge listens to L2 gateway/receiver events
-func (l *BridgeListener) listenL2Bridge(ctx context.Context) {
-	headers := make(chan *types.Header, 32)
-	subHead, err := l.l2Client.SubscribeNewHead(ctx, headers)
+// connectL2 dials L2, subscribes to heads and gateway/receiver logs, and
+// backfills the blocks missed since the checkpoint or the last seen head
+func (l *BridgeListener) connectL2(ctx context.Context) error {
+	client, err := dialChain(ctx, "Bridge-L2", l.cfg.L2WSSURL, l.cfg.L2RPCURL, l.cfg.PollInterval)
 	if err != nil {
-		log.Printf("❌ [Bridge-L2] Failed to subscribe to headers: %v", err)
-		return
+		return err
 	}
-
-	logsCh := make(chan types.Log, 256)
+	if l.l2Client != nil {
+		l.l2Client.Close()
+	}
+	l.l2Client = client
 
 	// L2 might have different contract for receiving messages
 	// For now, use the same gateway address pattern
 	addr := common.HexToAddress(l.cfg.L2Gateway)
+	sess, err := l.subscribe(ctx, client, "Bridge-L2", addr)
+	if err != nil {
+		return err
+	}
+
+	if err := l.catchUp(ctx, client, l.l2Cursor, sess, l.l2Head, l.onL2BridgeLog); err != nil {
+		sess.subs.close()
+		return err
+	}
+
+	l.l2Session = sess
+	l.l2Head = sess.backfilledTo
+	l.l2Health.head(sess.backfilledTo)
+	l.l2Health.set(StateConnected, nil)
+	return nil
+}
+
+// subscribe opens the header and gateway log subscriptions of one side
+func (l *BridgeListener) subscribe(ctx context.Context, client chainClient, tag string, addr common.Address) (*bridgeSession, error) {
+	sess := &bridgeSession{
+		subs:      newSubscriptionSet(),
+		headers:   make(chan *types.Header, 32),
+		logs:      make(chan types.Log, 256),
+		contracts: []common.Address{addr},
+	}
+
+	subHead, err := client.SubscribeNewHead(ctx, sess.headers)
+	if err != nil {
+		return nil, err
+	}
+	sess.subs.watch(tag, "header", subHead)
 
 	query := ethereum.FilterQuery{
-		Addresses: []common.Address{addr},
+		Addresses: sess.contracts,
 	}
 
-	subLogs, err := l.l2Client.SubscribeFilterLogs(ctx, query, logsCh)
+	subLogs, err := client.SubscribeFilterLogs(ctx, query, sess.logs)
 	if err != nil {
-		log.Printf("❌ [Bridge-L2] Failed to subscribe to logs: %v", err)
-		return
+		sess.subs.close()
+		return nil, err
+	}
+	sess.subs.watch(tag, "logs", subLogs)
+
+	log.Printf("👂 [%s] Subscribed to Gateway events at %s", tag, addr.Hex())
+	return sess, nil
+}
+
+// catchUp replays the logs missed before the session's subscriptions started.
+// The first connection resumes from the stored checkpoint; a reconnection
+// replays only the blocks after the last seen head. Replayed legs merge into
+// the stored messages without changing them.
+func (l *BridgeListener) catchUp(ctx context.Context, client chainClient, cursor *BlockCursor,
+	sess *bridgeSession, lastHead uint64, handle func(types.Log)) error {
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return err
 	}
+	if lastHead == 0 {
+		err = cursor.CatchUp(ctx, client, sess.contracts, head, handle)
+	} else if head > lastHead {
+		err = cursor.BackfillAll(ctx, client, sess.contracts, lastHead+1, head, handle)
+	}
+	if err != nil {
+		return err
+	}
+	sess.backfilledTo = head
+	return nil
+}
+
+// listenL1Bridge processes L1 gateway events until a subscription fails
+func (l *BridgeListener) listenL1Bridge(ctx context.Context) error {
+	sess := l.l1Session
+	defer sess.subs.close()
+
+	conf := l.cfg.L1Confirmations
+	if conf <= 0 {
+		conf = 12
+	}
+
+	for {
+		select {
+		case err := <-sess.subs.Err():
+			return err
+		case h := <-sess.headers:
+			// Message confirmation handled separately; only the cursor moves here
+			if h != nil {
+				l.l1Head = h.Number.Uint64()
+				l.l1Health.head(l.l1Head)
+				if l.l1Head >= uint64(conf) {
+					l.l1Cursor.Commit(ctx, sess.contracts, l.l1Head-uint64(conf))
+				}
+			}
+		case lg := <-sess.logs:
+			if lg.BlockNumber <= sess.backfilledTo {
+				continue // Already replayed by backfill
+			}
+			l.onL1BridgeLog(lg)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
 
-	log.Printf("👂 [Bridge-L2] Subscribed to Gateway events at %s", addr.Hex())
+// listenL2Bridge processes L2 gateway/receiver events until a subscription fails
+func (l *BridgeListener) listenL2Bridge(ctx context.Context) error {
+	sess := l.l2Session
+	defer sess.subs.close()
 
 	conf := l.cfg.L2Confirmations
 	if conf <= 0 {
@@ -163,183 +313,102 @@ func (l *BridgeListener) listenL2Bridge(ctx context.Context) {
 
 	for {
 		select {
-		case err := <-subHead.Err():
-			log.Printf("❌ [Bridge-L2] header subscription error: %v", err)
-			return
-		case err := <-subLogs.Err():
-			log.Printf("❌ [Bridge-L2] logs subscription error: %v", err)
-			return
-		case <-headers:
-			// Block confirmation handled separately
-		case lg := <-logsCh:
+		case err := <-sess.subs.Err():
+			return err
+		case h := <-sess.headers:
+			// Message confirmation handled separately; only the cursor moves here
+			if h != nil {
+				l.l2Head = h.Number.Uint64()
+				l.l2Health.head(l.l2Head)
+				if l.l2Head >= uint64(conf) {
+					l.l2Cursor.Commit(ctx, sess.contracts, l.l2Head-uint64(conf))
+				}
+			}
+		case lg := <-sess.logs:
+			if lg.BlockNumber <= sess.backfilledTo {
+				continue // Already replayed by backfill
+			}
 			l.onL2BridgeLog(lg)
 		case <-ctx.Done():
-			log.Println("🛑 [Bridge-L2] Listener stopped")
-			return
+			return ctx.Err()
 		}
 	}
 }
 
-// onL1BridgeLog processes L1 bridge events
+// onL1BridgeLog records an L1 gateway event: a deposit starting, or a
+// withdrawal arriving
 func (l *BridgeListener) onL1BridgeLog(lg types.Log) {
-	if len(lg.Topics) < 2 {
+	leg := parseBridgeLeg(lg)
+	if leg == nil {
 		return
 	}
+	leg.L1TxHash = lg.TxHash.Hex()
+	leg.L1BlockNumber = int64(lg.BlockNumber)
+	l.recordLeg(leg)
+}
 
-	// Parse MessageSent event from L1 Gateway
-	// MessageSent(bytes32 indexed messageHash, address indexed sender, uint256 amount, bytes data)
+// onL2BridgeLog records an L2 gateway/receiver event: a deposit arriving, or a
+// withdrawal starting
+func (l *BridgeListener) onL2BridgeLog(lg types.Log) {
+	leg := parseBridgeLeg(lg)
+	if leg == nil {
+		return
+	}
+	leg.L2TxHash = lg.TxHash.Hex()
+	leg.L2BlockNumber = int64(lg.BlockNumber)
+	l.recordLeg(leg)
+}
 
-	messageHash := lg.Topics[1].Hex()
-	var userAddress string
+// parseBridgeLeg reads the message hash, user and amount shared by the gateway
+// events on both sides, e.g.
+// MessageSent(bytes32 indexed messageHash, address indexed sender, uint256 amount, bytes data)
+// MessageReceived(bytes32 indexed messageHash, address indexed recipient, uint256 amount)
+func parseBridgeLeg(lg types.Log) *models.BridgeEvent {
+	if len(lg.Topics) < 2 {
+		return nil
+	}
 
+	var userAddress string
 	if len(lg.Topics) >= 3 {
 		userAddress = common.BytesToAddress(lg.Topics[2].Bytes()).Hex()
 	}
 
-	var amount string
+	amount := "0"
 	if len(lg.Data) >= 32 {
-		amt := new(big.Int).SetBytes(lg.Data[:32])
-		amount = amt.String()
-	} else {
-		amount = "0"
+		amount = new(big.Int).SetBytes(lg.Data[:32]).String()
 	}
 
-	l.pendingMu.Lock()
-	defer l.pendingMu.Unlock()
-
-	// Create or update bridge event
-	if existingEvent, exists := l.pending[messageHash]; exists {
-		// Update existing event with L1 confirmation
-		existingEvent.L1TxHash = lg.TxHash.Hex()
-		existingEvent.L1BlockNumber = int64(lg.BlockNumber)
-		existingEvent.Status = "pending"
-		log.Printf("🔄 [Bridge] Updated L1→L2 message %s", messageHash)
-	} else {
-		// Create new bridge event
-		event := &models.BridgeEvent{
-			UserAddress:   userAddress,
-			Amount:        amount,
-			Direction:     "L1_TO_L2",
-			Status:        "initiated",
-			L1TxHash:      lg.TxHash.Hex(),
-			L2TxHash:      "",
-			MessageHash:   messageHash,
-			L1BlockNumber: int64(lg.BlockNumber),
-			L2BlockNumber: 0,
-			InitiatedAt:   time.Now().Unix(),
-			ConfirmedAt:   0,
-			RetryCount:    0,
-			ErrorMsg:      "",
-		}
-		l.pending[messageHash] = event
-		log.Printf("🚀 [Bridge] L1→L2 message initiated %s user=%s amt=%s", messageHash, userAddress, amount)
+	return &models.BridgeEvent{
+		UserAddress: userAddress,
+		Amount:      amount,
+		MessageHash: lg.Topics[1].Hex(),
+		InitiatedAt: time.Now().Unix(),
 	}
 }
 
-// onL2BridgeLog processes L2 bridge events
-func (l *BridgeListener) onL2BridgeLog(lg types.Log) {
-	if len(lg.Topics) < 2 {
+// recordLeg merges a leg into the stored message and publishes the result
+func (l *BridgeListener) recordLeg(leg *models.BridgeEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	msg, confirmed, err := l.cfg.Messages.RecordBridgeLeg(ctx, leg)
+	if err != nil {
+		log.Printf("❌ [Bridge] Failed to record message %s: %v", leg.MessageHash, err)
 		return
 	}
 
-	// Parse MessageReceived event from L2
-	// MessageReceived(bytes32 indexed messageHash, address indexed recipient, uint256 amount)
-
-	messageHash := lg.Topics[1].Hex()
-
-	l.pendingMu.Lock()
-	defer l.pendingMu.Unlock()
-
-	// Update existing bridge event
-	if existingEvent, exists := l.pending[messageHash]; exists {
-		existingEvent.L2TxHash = lg.TxHash.Hex()
-		existingEvent.L2BlockNumber = int64(lg.BlockNumber)
-		existingEvent.Status = "confirmed"
-		existingEvent.ConfirmedAt = time.Now().Unix()
-
-		// Publish to Kafka
-		go l.publishBridgeEvent(existingEvent)
-
-		// Remove from pending
-		delete(l.pending, messageHash)
-
-		log.Printf("✅ [Bridge] L1→L2 message confirmed %s", messageHash)
-	} else {
-		// Received L2 event without L1 event (might be L2→L1)
-		// Handle reverse direction
-		var userAddress string
-		if len(lg.Topics) >= 3 {
-			userAddress = common.BytesToAddress(lg.Topics[2].Bytes()).Hex()
-		}
-
-		var amount string
-		if len(lg.Data) >= 32 {
-			amt := new(big.Int).SetBytes(lg.Data[:32])
-			amount = amt.String()
-		} else {
-			amount = "0"
-		}
-
-		event := &models.BridgeEvent{
-			UserAddress:   userAddress,
-			Amount:        amount,
-			Direction:     "L2_TO_L1",
-			Status:        "initiated",
-			L1TxHash:      "",
-			L2TxHash:      lg.TxHash.Hex(),
-			MessageHash:   messageHash,
-			L1BlockNumber: 0,
-			L2BlockNumber: int64(lg.BlockNumber),
-			InitiatedAt:   time.Now().Unix(),
-			ConfirmedAt:   0,
-			RetryCount:    0,
-			ErrorMsg:      "",
-		}
-		l.pending[messageHash] = event
-		log.Printf("🚀 [Bridge] L2→L1 message initiated %s user=%s amt=%s", messageHash, userAddress, amount)
+	if confirmed {
+		metrics.BridgeMessagesTotal.WithLabelValues(msg.Direction, msg.Status).Inc()
+		metrics.BridgeConfirmationTime.WithLabelValues(msg.Direction).Observe(float64(msg.ConfirmedAt - msg.InitiatedAt))
 	}
-}
-
-// updateBridgeStatus periodically checks and updates pending bridge messages
-func (l *BridgeListener) updateBridgeStatus(ctx context.Context) {
-	ticker := time.NewTicker(30 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			l.checkPendingMessages()
-		case <-ctx.Done():
-			return
-		}
+	if msg.Status == models.BridgeStatusConfirmed {
+		log.Printf("✅ [Bridge] %s message confirmed %s", msg.Direction, msg.MessageHash)
+	} else {
+		log.Printf("🚀 [Bridge] %s message %s %s user=%s amt=%s",
+			msg.Direction, msg.MessageHash, msg.Status, msg.UserAddress, msg.Amount)
 	}
-}
 
-// checkPendingMessages checks status of pending messages and retries if needed
-func (l *BridgeListener) checkPendingMessages() {
-	l.pendingMu.Lock()
-	defer l.pendingMu.Unlock()
-
-	now := time.Now().Unix()
-
-	for messageHash, event := range l.pending {
-		// Check if message is stuck (initiated >5 minutes ago)
-		if now-event.InitiatedAt > 300 {
-			event.RetryCount++
-
-			if event.RetryCount > 10 {
-				// Mark as failed after 10 retries
-				event.Status = "failed"
-				event.ErrorMsg = "timeout after 10 retries"
-				go l.publishBridgeEvent(event)
-				delete(l.pending, messageHash)
-				log.Printf("❌ [Bridge] Message %s failed after timeout", messageHash)
-			} else {
-				log.Printf("⚠️  [Bridge] Message %s pending for %d seconds (retry %d)",
-					messageHash, now-event.InitiatedAt, event.RetryCount)
-			}
-		}
-	}
+	l.publishBridgeEvent(msg)
 }
 
 // publishBridgeEvent publishes a bridge event to Kafka
@@ -347,15 +416,17 @@ func (l *BridgeListener) publishBridgeEvent(event *models.BridgeEvent) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	data, _ := json.Marshal(event)
-
-	msg := k.Message{
-		Key:   []byte(event.MessageHash),
-		Value: data,
-		Headers: []k.Header{
-			{Key: "direction", Value: []byte(event.Direction)},
-			{Key: "status", Value: []byte(event.Status)},
-		},
+	chainID := l.cfg.L1ChainID
+	if event.Direction == models.BridgeL2ToL1 {
+		chainID = l.cfg.L2ChainID
+	}
+	msg, err := events.Message(l.cfg.Codec, events.NewBridgeEnvelope(chainID, event), []byte(event.MessageHash),
+		k.Header{Key: "direction", Value: []byte(event.Direction)},
+		k.Header{Key: "status", Value: []byte(event.Status)},
+	)
+	if err != nil {
+		log.Printf("❌ [Bridge] Encode error for %s: %v", event.MessageHash, err)
+		return
 	}
 
 	if err := l.writer.WriteMessages(ctx, msg); err != nil {
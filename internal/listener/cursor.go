@@ -0,0 +1,147 @@
+package listener
+
+import (
+	"context"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// DefaultBackfillBatch is the block range requested per FilterLogs call during catch-up
+const DefaultBackfillBatch = 2000
+
+// checkpointInterval bounds how often checkpoints are written to the store
+const checkpointInterval = 5 * time.Second
+
+// CheckpointStore persists the last fully-confirmed block per listener, chain and contract
+type CheckpointStore interface {
+	LoadCheckpoint(ctx context.Context, listener string, chainID int64, contract string) (uint64, bool, error)
+	SaveCheckpoint(ctx context.Context, listener string, chainID int64, contract string, block uint64) error
+}
+
+// LogFilterer is the subset of ethclient.Client used for historical log queries
+type LogFilterer interface {
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error)
+}
+
+// BlockCursor tracks how far each watched contract has been processed and
+// replays the gap between the stored checkpoint and the chain head on startup.
+type BlockCursor struct {
+	store      CheckpointStore
+	listener   string
+	chainID    int64
+	startBlock uint64
+	batch      uint64
+	lastCommit time.Time
+}
+
+// NewBlockCursor creates a cursor for one listener on one chain.
+// startBlock is used for contracts without a checkpoint; 0 means start from the current head.
+func NewBlockCursor(store CheckpointStore, listener string, chainID int64, startBlock, batch uint64) *BlockCursor {
+	if batch == 0 {
+		batch = DefaultBackfillBatch
+	}
+	return &BlockCursor{
+		store:      store,
+		listener:   listener,
+		chainID:    chainID,
+		startBlock: startBlock,
+		batch:      batch,
+	}
+}
+
+// ResumeFrom returns the first block that still needs to be fetched for a contract.
+// ok is false when there is nothing to backfill.
+func (c *BlockCursor) ResumeFrom(ctx context.Context, contract common.Address, head uint64) (uint64, bool) {
+	from := c.startBlock
+
+	if c.store != nil {
+		last, found, err := c.store.LoadCheckpoint(ctx, c.listener, c.chainID, contract.Hex())
+		if err != nil {
+			log.Printf("⚠️  [%s] Failed to load checkpoint for %s: %v", c.listener, contract.Hex(), err)
+		} else if found {
+			from = last + 1
+		}
+	}
+
+	if from == 0 {
+		from = head
+	}
+	if from > head {
+		return 0, false
+	}
+	return from, true
+}
+
+// Backfill replays the logs of a contract between from and to (inclusive)
+// through handle, in ranges of at most the configured batch size.
+func (c *BlockCursor) Backfill(ctx context.Context, client LogFilterer, contract common.Address, from, to uint64, handle func(types.Log)) error {
+	for start := from; start <= to; start += c.batch {
+		end := start + c.batch - 1
+		if end > to {
+			end = to
+		}
+
+		logs, err := client.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(start),
+			ToBlock:   new(big.Int).SetUint64(end),
+			Addresses: []common.Address{contract},
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, lg := range logs {
+			handle(lg)
+		}
+
+		log.Printf("⏪ [%s] Backfilled %s blocks %d-%d (%d logs)", c.listener, contract.Hex(), start, end, len(logs))
+	}
+	return nil
+}
+
+// CatchUp backfills every contract from its checkpoint up to head
+func (c *BlockCursor) CatchUp(ctx context.Context, client LogFilterer, contracts []common.Address, head uint64, handle func(types.Log)) error {
+	for _, contract := range contracts {
+		from, ok := c.ResumeFrom(ctx, contract, head)
+		if !ok {
+			continue
+		}
+		if err := c.Backfill(ctx, client, contract, from, head, handle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BackfillAll replays every contract from a fixed block, used to close the
+// gap after a reconnection
+func (c *BlockCursor) BackfillAll(ctx context.Context, client LogFilterer, contracts []common.Address, from, to uint64, handle func(types.Log)) error {
+	if from > to {
+		return nil
+	}
+	for _, contract := range contracts {
+		if err := c.Backfill(ctx, client, contract, from, to, handle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Commit records block as the last fully-confirmed block for the given contracts.
+// Writes are throttled; a crash between commits only replays already-published blocks.
+func (c *BlockCursor) Commit(ctx context.Context, contracts []common.Address, block uint64) {
+	if c.store == nil || time.Since(c.lastCommit) < checkpointInterval {
+		return
+	}
+	c.lastCommit = time.Now()
+	for _, contract := range contracts {
+		if err := c.store.SaveCheckpoint(ctx, c.listener, c.chainID, contract.Hex(), block); err != nil {
+			log.Printf("⚠️  [%s] Failed to save checkpoint for %s: %v", c.listener, contract.Hex(), err)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package listener
+
+import (
+	"log"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// systemAddress is used as the user for contract-level events (pauses, state roots, ...)
+var systemAddress = common.Address{}.Hex()
+
+// eventName resolves the ABI event name of a log from its first topic.
+// Returns an empty string for anonymous logs or events not in the ABI.
+func eventName(meta *bind.MetaData, lg types.Log) string {
+	if len(lg.Topics) == 0 {
+		return ""
+	}
+	parsed, err := meta.GetAbi()
+	if err != nil {
+		return ""
+	}
+	ev, err := parsed.EventByID(lg.Topics[0])
+	if err != nil {
+		return ""
+	}
+	return ev.Name
+}
+
+// logDecodeError reports a log that matched a known event signature but failed to unpack
+func logDecodeError(layer, name string, lg types.Log, err error) {
+	log.Printf("⚠️  [%s] Failed to decode %s tx=%s index=%d: %v", layer, name, lg.TxHash.Hex(), lg.Index, err)
+}
+
+// bigString renders a uint256 value as a decimal string (nil-safe)
+func bigString(v *big.Int) string {
+	if v == nil {
+		return "0"
+	}
+	return v.String()
+}
+
+// hashString renders a bytes32 value as 0x-prefixed hex
+func hashString(v [32]byte) string {
+	return common.Hash(v).Hex()
+}
@@ -0,0 +1,207 @@
+package listener
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"loyalty-points-system/internal/models"
+)
+
+// FinalityMode selects when a block is final enough for its events to be published
+type FinalityMode string
+
+const (
+	FinalityDepth     FinalityMode = "depth"     // a fixed number of confirmations
+	FinalitySafe      FinalityMode = "safe"      // at or below the node's "safe" block
+	FinalityFinalized FinalityMode = "finalized" // at or below the node's "finalized" block
+	FinalityL1Batch   FinalityMode = "l1_batch"  // Arbitrum: the batch holding the block is finalized on the parent chain
+)
+
+// finalityRefresh bounds how often tag and batch finality are queried; heads
+// arrive far more often than the finalized block moves
+const finalityRefresh = 12 * time.Second
+
+// nodeInterface is Arbitrum's virtual NodeInterface contract
+var nodeInterface = common.HexToAddress("0x00000000000000000000000000000000000000C8")
+
+// getL1ConfirmationsSelector is NodeInterface.getL1Confirmations(bytes32 blockHash)
+var getL1ConfirmationsSelector = crypto.Keccak256([]byte("getL1Confirmations(bytes32)"))[:4]
+
+// finalizer tracks the highest block whose events may be released under a policy.
+// It is only used from the listener's own goroutine.
+type finalizer struct {
+	layer     string
+	mode      FinalityMode
+	depth     uint64
+	parentURL string
+	parent    *ethclient.Client
+
+	final     uint64 // highest final block seen so far; never decreases
+	known     bool
+	checkedAt time.Time
+}
+
+// confirmations returns the configured depth, or def when none is set
+func confirmations(configured, def int) int {
+	if configured <= 0 {
+		return def
+	}
+	return configured
+}
+
+func newFinalizer(layer string, mode FinalityMode, depth int, parentURL string) *finalizer {
+	if mode == "" {
+		mode = FinalityDepth
+	}
+	return &finalizer{layer: layer, mode: mode, depth: uint64(depth), parentURL: parentURL}
+}
+
+// check validates the policy before the listener starts
+func (f *finalizer) check() error {
+	switch f.mode {
+	case FinalityDepth, FinalitySafe, FinalityFinalized:
+		return nil
+	case FinalityL1Batch:
+		if f.parentURL == "" {
+			return fmt.Errorf("[%s] finality %q needs the parent chain RPC URL", f.layer, f.mode)
+		}
+		return nil
+	}
+	return fmt.Errorf("[%s] unknown finality %q", f.layer, f.mode)
+}
+
+// level is the finality carried by events released under the policy
+func (f *finalizer) level() string {
+	switch f.mode {
+	case FinalitySafe:
+		return models.FinalitySafe
+	case FinalityFinalized, FinalityL1Batch:
+		return models.FinalityFinal
+	}
+	return models.FinalitySoft
+}
+
+// height returns the highest block whose events can be released at head n.
+// It reports false until a final block is known.
+func (f *finalizer) height(ctx context.Context, client chainClient, n uint64) (uint64, bool) {
+	if f.mode == FinalityDepth {
+		if n < f.depth {
+			return 0, false
+		}
+		f.final, f.known = n-f.depth, true
+		return f.final, true
+	}
+
+	if f.known && time.Since(f.checkedAt) < finalityRefresh {
+		return min(f.final, n), true
+	}
+	f.checkedAt = time.Now()
+
+	var final uint64
+	var err error
+	if f.mode == FinalityL1Batch {
+		final, err = f.batchFinalized(ctx, client, n)
+	} else {
+		final, err = taggedBlock(ctx, client, f.mode)
+	}
+	if err != nil {
+		log.Printf("⚠️  [%s] Failed to read %s block: %v", f.layer, f.mode, err)
+	} else if !f.known || final > f.final {
+		f.final, f.known = final, true
+	}
+	return min(f.final, n), f.known
+}
+
+// replayFrom is the first block to replay after a reconnect: everything above
+// the last final block may still have unreleased events
+func (f *finalizer) replayFrom(head uint64) uint64 {
+	if f.known {
+		return min(f.final, head)
+	}
+	return head - min(head, f.depth)
+}
+
+// taggedBlock returns the number of the "safe" or "finalized" block
+func taggedBlock(ctx context.Context, client chainClient, mode FinalityMode) (uint64, error) {
+	tag := rpc.SafeBlockNumber
+	if mode == FinalityFinalized {
+		tag = rpc.FinalizedBlockNumber
+	}
+	h, err := client.HeaderByNumber(ctx, big.NewInt(int64(tag)))
+	if err != nil {
+		return 0, err
+	}
+	return h.Number.Uint64(), nil
+}
+
+// batchFinalized returns the highest block at or below head whose batch was
+// posted in a finalized parent chain block. A batch posted in parent block p
+// has head-p+1 confirmations, so it is final once it has at least
+// parentHead-parentFinalized+1. Confirmations only shrink with the block
+// number, so the boundary is found by binary search above the last final block.
+func (f *finalizer) batchFinalized(ctx context.Context, client chainClient, head uint64) (uint64, error) {
+	if f.parent == nil {
+		parent, err := ethclient.DialContext(ctx, f.parentURL)
+		if err != nil {
+			return 0, fmt.Errorf("dial parent chain: %w", err)
+		}
+		f.parent = parent
+	}
+
+	parentHead, err := f.parent.BlockNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+	parentFinal, err := taggedBlock(ctx, f.parent, FinalityFinalized)
+	if err != nil {
+		return 0, err
+	}
+	need := parentHead - min(parentHead, parentFinal) + 1
+
+	lo, hi := min(f.final, head), head
+	for lo < hi {
+		mid := lo + (hi-lo+1)/2
+		confs, err := l1Confirmations(ctx, client, mid)
+		if err != nil {
+			return 0, err
+		}
+		if confs >= need {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return lo, nil
+}
+
+// l1Confirmations asks the Arbitrum node how many parent chain confirmations
+// the batch holding block n has (0 while it is not posted)
+func l1Confirmations(ctx context.Context, client chainClient, n uint64) (uint64, error) {
+	h, err := client.HeaderByNumber(ctx, new(big.Int).SetUint64(n))
+	if err != nil {
+		return 0, err
+	}
+	data := append(append([]byte{}, getL1ConfirmationsSelector...), h.Hash().Bytes()...)
+	out, err := client.CallContract(ctx, ethereum.CallMsg{To: &nodeInterface, Data: data}, nil)
+	if err != nil {
+		return 0, fmt.Errorf("getL1Confirmations(%d): %w", n, err)
+	}
+	return new(big.Int).SetBytes(out).Uint64(), nil
+}
+
+// close releases the parent chain connection
+func (f *finalizer) close() {
+	if f.parent != nil {
+		f.parent.Close()
+		f.parent = nil
+	}
+}
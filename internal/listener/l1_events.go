@@ -0,0 +1,372 @@
+package listener
+
+import (
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"loyalty-points-system/internal/blockchain/l1"
+	"loyalty-points-system/internal/models"
+)
+
+// parseCollateralVaultEvent decodes CollateralVaultL1 events
+func (l *L1Listener) parseCollateralVaultEvent(lg types.Log, event *models.L1Event) string {
+	name := eventName(l1.CollateralVaultL1MetaData, lg)
+
+	switch name {
+	case "CollateralLocked":
+		ev, err := l.collateralVault.ParseCollateralLocked(lg)
+		if err != nil {
+			logDecodeError("L1", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = bigString(ev.Amount)
+		event.Token = l.collateralToken
+		event.L2TxHash = hashString(ev.L2TxHash)
+		event.Metadata = map[string]interface{}{
+			"total_user_locked": bigString(ev.TotalUserLocked),
+		}
+		return "collateral_deposit"
+
+	case "CollateralUnlocked":
+		ev, err := l.collateralVault.ParseCollateralUnlocked(lg)
+		if err != nil {
+			logDecodeError("L1", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = bigString(ev.Amount)
+		event.Token = l.collateralToken
+		event.L2TxHash = hashString(ev.L2TxHash)
+		event.Metadata = map[string]interface{}{
+			"remaining": bigString(ev.Remaining),
+		}
+		return "collateral_withdraw"
+
+	case "EmergencyWithdrawalRequested":
+		ev, err := l.collateralVault.ParseEmergencyWithdrawalRequested(lg)
+		if err != nil {
+			logDecodeError("L1", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = bigString(ev.Amount)
+		event.Token = l.collateralToken
+		event.Metadata = map[string]interface{}{
+			"unlock_time": bigString(ev.UnlockTime),
+		}
+		return "collateral_emergency_withdraw_requested"
+
+	case "EmergencyWithdrawalExecuted":
+		ev, err := l.collateralVault.ParseEmergencyWithdrawalExecuted(lg)
+		if err != nil {
+			logDecodeError("L1", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = bigString(ev.Amount)
+		event.Token = l.collateralToken
+		return "collateral_emergency_withdraw"
+
+	case "EmergencyPauseTriggered":
+		ev, err := l.collateralVault.ParseEmergencyPauseTriggered(lg)
+		if err != nil {
+			logDecodeError("L1", name, lg, err)
+			return ""
+		}
+		event.UserAddress = systemAddress
+		event.Amount = "0"
+		event.Metadata = map[string]interface{}{
+			"triggered_by": ev.TriggeredBy.Hex(),
+		}
+		return "collateral_emergency_paused"
+
+	case "EmergencyResumed":
+		ev, err := l.collateralVault.ParseEmergencyResumed(lg)
+		if err != nil {
+			logDecodeError("L1", name, lg, err)
+			return ""
+		}
+		event.UserAddress = systemAddress
+		event.Amount = "0"
+		event.Metadata = map[string]interface{}{
+			"resumed_by": ev.ResumedBy.Hex(),
+		}
+		return "collateral_emergency_resumed"
+	}
+
+	// Admin events (ownership, bridge/registry updates) are not tracked
+	return ""
+}
+
+// parseStateRegistryEvent decodes L1StateRegistry events
+func (l *L1Listener) parseStateRegistryEvent(lg types.Log, event *models.L1Event) string {
+	name := eventName(l1.L1StateRegistryMetaData, lg)
+
+	switch name {
+	case "StateRootReceived":
+		ev, err := l.stateRegistry.ParseStateRootReceived(lg)
+		if err != nil {
+			logDecodeError("L1", name, lg, err)
+			return ""
+		}
+		event.UserAddress = systemAddress
+		event.Amount = "0"
+		event.Metadata = map[string]interface{}{
+			"state_root":   hashString(ev.StateRoot),
+			"l2_block":     bigString(ev.L2Block),
+			"l2_timestamp": bigString(ev.Timestamp),
+		}
+		return "state_update"
+
+	case "CriticalConditionDetected":
+		ev, err := l.stateRegistry.ParseCriticalConditionDetected(lg)
+		if err != nil {
+			logDecodeError("L1", name, lg, err)
+			return ""
+		}
+		event.UserAddress = systemAddress
+		event.Amount = "0"
+		event.Metadata = map[string]interface{}{
+			"state_root":       hashString(ev.StateRoot),
+			"total_collateral": bigString(ev.TotalCollateral),
+			"total_debt":       bigString(ev.TotalDebt),
+			"ratio":            bigString(ev.Ratio),
+		}
+		return "state_critical_condition"
+
+	case "EmergencyExitInitiated":
+		ev, err := l.stateRegistry.ParseEmergencyExitInitiated(lg)
+		if err != nil {
+			logDecodeError("L1", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = bigString(ev.Amount)
+		event.Metadata = map[string]interface{}{
+			"l2_block": bigString(ev.BlockNumber),
+		}
+		return "state_emergency_exit"
+
+	case "EmergencyPauseTriggered":
+		ev, err := l.stateRegistry.ParseEmergencyPauseTriggered(lg)
+		if err != nil {
+			logDecodeError("L1", name, lg, err)
+			return ""
+		}
+		event.UserAddress = systemAddress
+		event.Amount = "0"
+		event.Metadata = map[string]interface{}{
+			"triggered_by": ev.TriggeredBy.Hex(),
+			"reason":       ev.Reason,
+		}
+		return "state_emergency_paused"
+
+	case "ThresholdsUpdated":
+		ev, err := l.stateRegistry.ParseThresholdsUpdated(lg)
+		if err != nil {
+			logDecodeError("L1", name, lg, err)
+			return ""
+		}
+		event.UserAddress = systemAddress
+		event.Amount = "0"
+		event.Metadata = map[string]interface{}{
+			"min_collateral_ratio":  bigString(ev.MinCollateralRatio),
+			"liquidation_threshold": bigString(ev.LiquidationThreshold),
+			"max_debt_ceiling":      bigString(ev.MaxDebtCeiling),
+		}
+		return "state_thresholds_updated"
+	}
+
+	return ""
+}
+
+// parseLoyaltyUSDEvent decodes LoyaltyUSDL1 events
+func (l *L1Listener) parseLoyaltyUSDEvent(lg types.Log, event *models.L1Event) string {
+	name := eventName(l1.LoyaltyUSDL1MetaData, lg)
+	event.Token = "LOYALTY_USD"
+
+	switch name {
+	case "Transfer":
+		ev, err := l.loyaltyUSD.ParseTransfer(lg)
+		if err != nil {
+			logDecodeError("L1", name, lg, err)
+			return ""
+		}
+		// Tracked from the recipient's side; the sender is kept in metadata
+		event.UserAddress = ev.To.Hex()
+		event.Amount = bigString(ev.Value)
+		event.Metadata = map[string]interface{}{
+			"from": ev.From.Hex(),
+			"to":   ev.To.Hex(),
+		}
+		return "loyaltyusd_transfer"
+
+	case "Minted":
+		ev, err := l.loyaltyUSD.ParseMinted(lg)
+		if err != nil {
+			logDecodeError("L1", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.To.Hex()
+		event.Amount = bigString(ev.Amount)
+		event.Metadata = map[string]interface{}{
+			"minter": ev.Minter.Hex(),
+		}
+		return "loyaltyusd_mint"
+
+	case "Burned":
+		ev, err := l.loyaltyUSD.ParseBurned(lg)
+		if err != nil {
+			logDecodeError("L1", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.From.Hex()
+		event.Amount = bigString(ev.Amount)
+		event.Metadata = map[string]interface{}{
+			"burner": ev.Burner.Hex(),
+		}
+		return "loyaltyusd_burn"
+
+	case "BridgeMint":
+		ev, err := l.loyaltyUSD.ParseBridgeMint(lg)
+		if err != nil {
+			logDecodeError("L1", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.To.Hex()
+		event.Amount = bigString(ev.Amount)
+		event.L2TxHash = hashString(ev.L2TxHash)
+		return "loyaltyusd_bridge_mint"
+
+	case "BridgeBurn":
+		ev, err := l.loyaltyUSD.ParseBridgeBurn(lg)
+		if err != nil {
+			logDecodeError("L1", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.From.Hex()
+		event.Amount = bigString(ev.Amount)
+		event.L2TxHash = hashString(ev.L2TxHash)
+		return "loyaltyusd_bridge_burn"
+
+	case "DailyMintLimitUpdated":
+		ev, err := l.loyaltyUSD.ParseDailyMintLimitUpdated(lg)
+		if err != nil {
+			logDecodeError("L1", name, lg, err)
+			return ""
+		}
+		event.UserAddress = systemAddress
+		event.Amount = bigString(ev.NewLimit)
+		event.Metadata = map[string]interface{}{
+			"old_limit": bigString(ev.OldLimit),
+			"new_limit": bigString(ev.NewLimit),
+		}
+		return "loyaltyusd_mint_limit_updated"
+
+	case "EmergencyPaused":
+		ev, err := l.loyaltyUSD.ParseEmergencyPaused(lg)
+		if err != nil {
+			logDecodeError("L1", name, lg, err)
+			return ""
+		}
+		event.UserAddress = systemAddress
+		event.Amount = "0"
+		event.Metadata = map[string]interface{}{
+			"pauser": ev.Pauser.Hex(),
+		}
+		return "loyaltyusd_emergency_paused"
+
+	case "EmergencyUnpaused":
+		ev, err := l.loyaltyUSD.ParseEmergencyUnpaused(lg)
+		if err != nil {
+			logDecodeError("L1", name, lg, err)
+			return ""
+		}
+		event.UserAddress = systemAddress
+		event.Amount = "0"
+		event.Metadata = map[string]interface{}{
+			"unpauser": ev.Unpauser.Hex(),
+		}
+		return "loyaltyusd_emergency_unpaused"
+	}
+
+	// Approvals and role changes are not tracked
+	return ""
+}
+
+// parseGatewayEvent decodes L1Gateway events
+func (l *L1Listener) parseGatewayEvent(lg types.Log, event *models.L1Event) string {
+	name := eventName(l1.L1GatewayMetaData, lg)
+
+	switch name {
+	case "DepositInitiated":
+		ev, err := l.gateway.ParseDepositInitiated(lg)
+		if err != nil {
+			logDecodeError("L1", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = bigString(ev.Amount)
+		event.Metadata = map[string]interface{}{
+			"deposit_id": bigString(ev.DepositId),
+			"ticket_id":  bigString(ev.TicketId),
+		}
+		return "gateway_deposit_initiated"
+
+	case "DepositFinalized":
+		ev, err := l.gateway.ParseDepositFinalized(lg)
+		if err != nil {
+			logDecodeError("L1", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = bigString(ev.Amount)
+		event.Metadata = map[string]interface{}{
+			"deposit_id": bigString(ev.DepositId),
+		}
+		return "gateway_deposit_finalized"
+
+	case "WithdrawalInitiated":
+		ev, err := l.gateway.ParseWithdrawalInitiated(lg)
+		if err != nil {
+			logDecodeError("L1", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = bigString(ev.Amount)
+		event.Metadata = map[string]interface{}{
+			"withdrawal_id": hashString(ev.WithdrawalId),
+		}
+		return "gateway_withdrawal_initiated"
+
+	case "WithdrawalExecuted":
+		ev, err := l.gateway.ParseWithdrawalExecuted(lg)
+		if err != nil {
+			logDecodeError("L1", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = bigString(ev.Amount)
+		event.Metadata = map[string]interface{}{
+			"withdrawal_id": hashString(ev.WithdrawalId),
+		}
+		return "gateway_withdrawal_executed"
+
+	case "GasParametersUpdated":
+		ev, err := l.gateway.ParseGasParametersUpdated(lg)
+		if err != nil {
+			logDecodeError("L1", name, lg, err)
+			return ""
+		}
+		event.UserAddress = systemAddress
+		event.Amount = "0"
+		event.Metadata = map[string]interface{}{
+			"max_submission_cost": bigString(ev.MaxSubmissionCost),
+			"max_gas":             bigString(ev.MaxGas),
+			"gas_price_bid":       bigString(ev.GasPriceBid),
+		}
+		return "gateway_gas_parameters_updated"
+	}
+
+	return ""
+}
@@ -2,42 +2,82 @@ package listener
 
 import (
 	"context"
-	"encoding/json"
 	"log"
-	"math/big"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
 	k "github.com/segmentio/kafka-go"
 
 	"loyalty-points-system/internal/blockchain/l1"
+	"loyalty-points-system/internal/events"
+	"loyalty-points-system/internal/kafka"
 	"loyalty-points-system/internal/models"
 )
 
 // L1ListenerConfig holds configuration for L1 listener
 type L1ListenerConfig struct {
+	Name                string // Listener name on /status and in metrics (default "l1")
 	RPCURL              string
 	WSSURL              string
 	ChainID             int64
 	Confirmations       int
+	Finality            FinalityMode // Release policy (empty = FinalityDepth, using Confirmations)
 	CollateralVault     string
 	StateRegistry       string
 	LoyaltyUSD          string
 	Gateway             string
+
+	// Historical catch-up
+	StartBlock    uint64          // First block to backfill when no checkpoint exists (0 = head)
+	BackfillBatch uint64          // Max blocks per FilterLogs call
+	Checkpoints   CheckpointStore // Optional; without it the listener starts from head
+
+	// Poll mode, used when WSSURL is empty or unreachable
+	PollInterval time.Duration // eth_blockNumber polling interval (0 = DefaultPollInterval)
+
+	Codec events.Codec // Envelope encoding (nil = JSON)
 }
 
 // L1Listener listens to L1 events and publishes to Kafka
 type L1Listener struct {
 	cfg        L1ListenerConfig
-	client     *ethclient.Client
+	client     chainClient
 	writer     Writer
 	pending    map[uint64][]models.L1Event
 	pendingMu  sync.Mutex
 	head       uint64
+
+	// Release policy and block cursor for backfill and checkpointing
+	finality     *finalizer
+	cursor       *BlockCursor
+	contracts    []common.Address
+	backfilledTo uint64
+
+	// Reorg handling: canonical hashes and recently published events. The
+	// published window lives in memory only: events released before a restart
+	// cannot be retracted if their blocks are reorged afterwards, so a
+	// finality policy short of "final" accepts that gap.
+	blocks      *blockTracker
+	published   map[uint64][]models.L1Event
+	unretracted []models.L1Event // retractions that could not be written, retried on the next head
+
+	// Current connection session, replaced on every reconnect
+	subs    *subscriptionSet
+	headers chan *types.Header
+	logsCh  chan types.Log
+	health  *health
+
+	// Contract bindings used to decode logs
+	collateralVault *l1.CollateralVaultL1
+	stateRegistry   *l1.L1StateRegistry
+	loyaltyUSD      *l1.LoyaltyUSDL1
+	gateway         *l1.L1Gateway
+	collateralToken string
 }
 
 // Writer interface for Kafka
@@ -48,20 +88,49 @@ type Writer interface {
 // NewL1Listener creates a new L1 listener
 func NewL1Listener(cfg L1ListenerConfig, writer Writer) *L1Listener {
 	return &L1Listener{
-		cfg:     cfg,
-		writer:  writer,
-		pending: make(map[uint64][]models.L1Event),
+		cfg:       cfg,
+		writer:    writer,
+		pending:   make(map[uint64][]models.L1Event),
+		published: make(map[uint64][]models.L1Event),
+		blocks:    newBlockTracker("L1"),
+		finality:  newFinalizer("L1", cfg.Finality, confirmations(cfg.Confirmations, 12), ""),
+		cursor:    NewBlockCursor(cfg.Checkpoints, "l1", cfg.ChainID, cfg.StartBlock, cfg.BackfillBatch),
+		health:    newHealth(listenerName(cfg.Name, "l1"), 2*time.Minute),
 	}
 }
 
-// Start begins listening to L1 events
+// Start connects to L1 and begins listening to events. Dropped subscriptions
+// are re-dialed in the background with exponential backoff.
 func (l *L1Listener) Start(ctx context.Context) error {
-	var err error
-	l.client, err = ethclient.DialContext(ctx, l.cfg.WSSURL)
+	if err := l.finality.check(); err != nil {
+		return err
+	}
+	if err := l.connect(ctx); err != nil {
+		return err
+	}
+	go l.supervise(ctx)
+	return nil
+}
+
+// Status reports the current connection state of the listener
+func (l *L1Listener) Status() Status {
+	return l.health.snapshot()
+}
+
+// connect dials L1 (websocket, or HTTP polling as a fallback), subscribes to
+// heads and every configured contract, and backfills the logs missed since the
+// last checkpoint or the last seen head
+func (l *L1Listener) connect(ctx context.Context) error {
+	client, err := dialChain(ctx, "L1", l.cfg.WSSURL, l.cfg.RPCURL, l.cfg.PollInterval)
 	if err != nil {
 		return err
 	}
-	log.Printf("🔌 [L1] Connected to %s (chain ID: %d)", l.cfg.WSSURL, l.cfg.ChainID)
+	if l.client != nil {
+		l.client.Close()
+	}
+	l.client = client
+
+	subs := newSubscriptionSet()
 
 	// Subscribe to new block headers
 	headers := make(chan *types.Header, 32)
@@ -69,75 +138,135 @@ func (l *L1Listener) Start(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	subs.watch("L1", "head", subHead)
 
 	// Start listening to each contract
 	logsCh := make(chan types.Log, 256)
+	l.contracts = nil
 
 	// Subscribe to CollateralVault events
 	if l.cfg.CollateralVault != "" {
-		if err := l.subscribeCollateralVault(ctx, logsCh); err != nil {
+		if err := l.subscribeCollateralVault(ctx, logsCh, subs); err != nil {
+			subs.close()
 			return err
 		}
 	}
 
 	// Subscribe to StateRegistry events
 	if l.cfg.StateRegistry != "" {
-		if err := l.subscribeStateRegistry(ctx, logsCh); err != nil {
+		if err := l.subscribeStateRegistry(ctx, logsCh, subs); err != nil {
+			subs.close()
 			return err
 		}
 	}
 
 	// Subscribe to LoyaltyUSD events
 	if l.cfg.LoyaltyUSD != "" {
-		if err := l.subscribeLoyaltyUSD(ctx, logsCh); err != nil {
+		if err := l.subscribeLoyaltyUSD(ctx, logsCh, subs); err != nil {
+			subs.close()
 			return err
 		}
 	}
 
 	// Subscribe to Gateway events
 	if l.cfg.Gateway != "" {
-		if err := l.subscribeGateway(ctx, logsCh); err != nil {
+		if err := l.subscribeGateway(ctx, logsCh, subs); err != nil {
+			subs.close()
 			return err
 		}
 	}
 
-	conf := l.cfg.Confirmations
-	if conf <= 0 {
-		conf = 12 // Default for L1
+	// Catch up before processing live logs. The head is read after
+	// subscribing so no block falls between the two.
+	head, err := l.client.BlockNumber(ctx)
+	if err != nil {
+		subs.close()
+		return err
+	}
+	if l.head == 0 {
+		// First connection: resume from the stored checkpoints
+		err = l.cursor.CatchUp(ctx, l.client, l.contracts, head, l.onLog)
+	} else {
+		// Reconnection: replay the unconfirmed window before the last seen head;
+		// logs already pending or published are skipped by onLog
+		from := l.finality.replayFrom(l.head)
+		err = l.cursor.BackfillAll(ctx, l.client, l.contracts, from, head, l.onLog)
+	}
+	if err != nil {
+		subs.close()
+		return err
 	}
+	l.backfilledTo = head
+	l.onHead(ctx, head)
 
-	// Main event loop
-	go func() {
-		for {
-			select {
-			case err := <-subHead.Err():
-				log.Printf("❌ [L1] head subscription error: %v", err)
-				return
-			case h := <-headers:
-				if h != nil {
-					l.onHead(h.Number.Uint64(), conf)
-				}
-			case lg := <-logsCh:
-				l.onLog(lg)
-			case <-ctx.Done():
-				log.Println("🛑 [L1] Listener stopped")
-				return
-			}
+	l.subs, l.headers, l.logsCh = subs, headers, logsCh
+	l.health.head(head)
+	l.health.set(StateConnected, nil)
+	return nil
+}
+
+// supervise runs the event loop and re-dials whenever a subscription drops
+func (l *L1Listener) supervise(ctx context.Context) {
+	for {
+		err := l.loop(ctx)
+		l.subs.close()
+
+		if ctx.Err() != nil {
+			l.health.set(StateStopped, nil)
+			log.Println("🛑 [L1] Listener stopped")
+			return
 		}
-	}()
 
-	return nil
+		l.health.set(StateReconnecting, err)
+		if !reconnect(ctx, l.health, l.connect) {
+			l.health.set(StateStopped, nil)
+			log.Println("🛑 [L1] Listener stopped")
+			return
+		}
+	}
+}
+
+// loop processes heads and logs until a subscription fails or ctx is done
+func (l *L1Listener) loop(ctx context.Context) error {
+	for {
+		select {
+		case err := <-l.subs.Err():
+			return err
+		case h := <-l.headers:
+			if h != nil {
+				l.health.head(h.Number.Uint64())
+				l.onReorg(l.blocks.observe(ctx, l.client, h))
+				l.onHead(ctx, h.Number.Uint64())
+			}
+		case lg := <-l.logsCh:
+			if !lg.Removed && lg.BlockNumber <= l.backfilledTo {
+				continue // Already replayed by backfill
+			}
+			l.onLog(lg)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 }
 
 // subscribeCollateralVault subscribes to CollateralVault events
-func (l *L1Listener) subscribeCollateralVault(ctx context.Context, logsCh chan types.Log) error {
+func (l *L1Listener) subscribeCollateralVault(ctx context.Context, logsCh chan types.Log, subs *subscriptionSet) error {
 	addr := common.HexToAddress(l.cfg.CollateralVault)
+	l.contracts = append(l.contracts, addr)
 
-	// Get contract to access event signatures
+	// Bind contract for event decoding
 	contract, err := l1.NewCollateralVaultL1(addr, l.client)
 	if err != nil {
 		return err
 	}
+	l.collateralVault = contract
+
+	// Resolve collateral token so deposits carry the token they lock
+	if token, err := contract.CollateralToken(&bind.CallOpts{Context: ctx}); err != nil {
+		log.Printf("⚠️  [L1] Failed to resolve collateral token: %v", err)
+	} else {
+		l.collateralToken = token.Hex()
+	}
 
 	// Subscribe to CollateralLocked and CollateralUnlocked events
 	query := ethereum.FilterQuery{
@@ -151,19 +280,22 @@ func (l *L1Listener) subscribeCollateralVault(ctx context.Context, logsCh chan t
 
 	log.Printf("👂 [L1] Subscribed to CollateralVault events at %s", addr.Hex())
 
-	go func() {
-		<-sub.Err()
-		log.Println("❌ [L1] CollateralVault subscription error")
-	}()
-
-	_ = contract // Use contract to avoid unused warning
+	subs.watch("L1", "CollateralVault", sub)
 
 	return nil
 }
 
 // subscribeStateRegistry subscribes to StateRegistry events
-func (l *L1Listener) subscribeStateRegistry(ctx context.Context, logsCh chan types.Log) error {
+func (l *L1Listener) subscribeStateRegistry(ctx context.Context, logsCh chan types.Log, subs *subscriptionSet) error {
 	addr := common.HexToAddress(l.cfg.StateRegistry)
+	l.contracts = append(l.contracts, addr)
+
+	// Bind contract for event decoding
+	contract, err := l1.NewL1StateRegistry(addr, l.client)
+	if err != nil {
+		return err
+	}
+	l.stateRegistry = contract
 
 	query := ethereum.FilterQuery{
 		Addresses: []common.Address{addr},
@@ -176,17 +308,22 @@ func (l *L1Listener) subscribeStateRegistry(ctx context.Context, logsCh chan typ
 
 	log.Printf("👂 [L1] Subscribed to StateRegistry events at %s", addr.Hex())
 
-	go func() {
-		<-sub.Err()
-		log.Println("❌ [L1] StateRegistry subscription error")
-	}()
+	subs.watch("L1", "StateRegistry", sub)
 
 	return nil
 }
 
 // subscribeLoyaltyUSD subscribes to LoyaltyUSD events
-func (l *L1Listener) subscribeLoyaltyUSD(ctx context.Context, logsCh chan types.Log) error {
+func (l *L1Listener) subscribeLoyaltyUSD(ctx context.Context, logsCh chan types.Log, subs *subscriptionSet) error {
 	addr := common.HexToAddress(l.cfg.LoyaltyUSD)
+	l.contracts = append(l.contracts, addr)
+
+	// Bind contract for event decoding
+	contract, err := l1.NewLoyaltyUSDL1(addr, l.client)
+	if err != nil {
+		return err
+	}
+	l.loyaltyUSD = contract
 
 	query := ethereum.FilterQuery{
 		Addresses: []common.Address{addr},
@@ -199,17 +336,22 @@ func (l *L1Listener) subscribeLoyaltyUSD(ctx context.Context, logsCh chan types.
 
 	log.Printf("👂 [L1] Subscribed to LoyaltyUSD events at %s", addr.Hex())
 
-	go func() {
-		<-sub.Err()
-		log.Println("❌ [L1] LoyaltyUSD subscription error")
-	}()
+	subs.watch("L1", "LoyaltyUSD", sub)
 
 	return nil
 }
 
 // subscribeGateway subscribes to Gateway events
-func (l *L1Listener) subscribeGateway(ctx context.Context, logsCh chan types.Log) error {
+func (l *L1Listener) subscribeGateway(ctx context.Context, logsCh chan types.Log, subs *subscriptionSet) error {
 	addr := common.HexToAddress(l.cfg.Gateway)
+	l.contracts = append(l.contracts, addr)
+
+	// Bind contract for event decoding
+	contract, err := l1.NewL1Gateway(addr, l.client)
+	if err != nil {
+		return err
+	}
+	l.gateway = contract
 
 	query := ethereum.FilterQuery{
 		Addresses: []common.Address{addr},
@@ -222,16 +364,23 @@ func (l *L1Listener) subscribeGateway(ctx context.Context, logsCh chan types.Log
 
 	log.Printf("👂 [L1] Subscribed to Gateway events at %s", addr.Hex())
 
-	go func() {
-		<-sub.Err()
-		log.Println("❌ [L1] Gateway subscription error")
-	}()
+	subs.watch("L1", "Gateway", sub)
 
 	return nil
 }
 
 // onLog processes incoming log events
 func (l *L1Listener) onLog(lg types.Log) {
+	if lg.Removed {
+		l.onRemovedLog(lg)
+		return
+	}
+
+	// Skip logs already handled, e.g. replayed by a backfill after reconnecting
+	if l.seen(lg) {
+		return
+	}
+
 	var event models.L1Event
 	var eventType string
 
@@ -258,6 +407,10 @@ func (l *L1Listener) onLog(lg types.Log) {
 	event.EventType = eventType
 	event.TxHash = lg.TxHash.Hex()
 	event.BlockNumber = int64(lg.BlockNumber)
+	event.BlockHash = lg.BlockHash.Hex()
+	event.TxIndex = lg.TxIndex
+	event.LogIndex = lg.Index
+	event.Sequence = models.EventSequence(lg.BlockNumber, lg.Index)
 	event.Confirmed = false
 	event.Timestamp = time.Now().Unix()
 	event.ContractAddress = lg.Address.Hex()
@@ -270,138 +423,215 @@ func (l *L1Listener) onLog(lg types.Log) {
 	log.Printf("🕒 [L1] Pending %s block=%d tx=%s", eventType, lg.BlockNumber, lg.TxHash.Hex())
 }
 
-// parseCollateralVaultEvent parses CollateralVault events
-func (l *L1Listener) parseCollateralVaultEvent(lg types.Log, event *models.L1Event) string {
-	if len(lg.Topics) < 2 {
-		return ""
+// seen reports whether a log is already pending or was recently published
+func (l *L1Listener) seen(lg types.Log) bool {
+	txHash := lg.TxHash.Hex()
+	blockHash := lg.BlockHash.Hex()
+
+	l.pendingMu.Lock()
+	defer l.pendingMu.Unlock()
+
+	for _, list := range [][]models.L1Event{l.pending[lg.BlockNumber], l.published[lg.BlockNumber]} {
+		for _, evt := range list {
+			if evt.TxHash == txHash && evt.LogIndex == lg.Index && evt.BlockHash == blockHash {
+				return true
+			}
+		}
 	}
+	return false
+}
 
-	// Get event signature
-	eventSig := lg.Topics[0].Hex()
+// onHead releases the events of blocks that became final under the finality policy
+func (l *L1Listener) onHead(ctx context.Context, n uint64) {
+	l.head = n
 
-	// CollateralLocked(address indexed user, address indexed token, uint256 amount, bytes32 indexed l2TxHash)
-	// CollateralUnlocked(address indexed user, address indexed token, uint256 amount, bytes32 indexed l2TxHash)
+	l.pendingMu.Lock()
+	unretracted := l.unretracted
+	l.unretracted = nil
+	l.pendingMu.Unlock()
+	l.retract(unretracted)
 
-	if len(lg.Topics) >= 2 {
-		event.UserAddress = common.BytesToAddress(lg.Topics[1].Bytes()).Hex()
-	}
+	final, ok := l.finality.height(ctx, l.client, n)
 
-	if len(lg.Data) >= 32 {
-		amount := new(big.Int).SetBytes(lg.Data[:32])
-		event.Amount = amount.String()
-	}
+	var confirmed []models.L1Event
+	keep := make(map[uint64][]models.L1Event)
 
-	if len(lg.Topics) >= 3 {
-		event.Token = common.BytesToAddress(lg.Topics[2].Bytes()).Hex()
+	l.pendingMu.Lock()
+	for bn, list := range l.pending {
+		if ok && bn <= final {
+			for _, evt := range list {
+				if !l.blocks.canonical(bn, evt.BlockHash) {
+					log.Printf("🔀 [L1] Dropping %s tx=%s from reorged block %d", evt.EventType, evt.TxHash, bn)
+					continue
+				}
+				confirmed = append(confirmed, evt)
+			}
+		} else {
+			keep[bn] = list
+		}
 	}
+	l.pending = keep
+	l.pendingMu.Unlock()
 
-	if len(lg.Topics) >= 4 {
-		event.L2TxHash = lg.Topics[3].Hex()
+	if !ok {
+		return
 	}
 
-	// Determine event type based on signature
-	// Note: These are placeholder signatures - should be updated with actual event signatures from ABI
-	// Use: crypto.Keccak256Hash([]byte("EventName(...)")).Hex()
-	_ = eventSig // Placeholder until real signatures are added
+	// Publish confirmed events to Kafka
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
 
-	// For now, return generic operation type
-	// TODO: Update with actual event signatures after contract deployment
-	return "collateral_operation"
-}
+	// Events that could not be written go back to pending and are retried on
+	// the next head; the checkpoint stops below the lowest of their blocks
+	commit, hold := final, false
+	for _, evt := range confirmed {
+		retry := evt
+		evt.Confirmed = true
+		evt.Finality = l.finality.level()
+		if err := l.publish(ctx, evt); err != nil {
+			log.Printf("❌ [L1] Kafka write error: %v", err)
+			bn := uint64(evt.BlockNumber)
+			l.pendingMu.Lock()
+			l.pending[bn] = append(l.pending[bn], retry)
+			l.pendingMu.Unlock()
+			if bn == 0 {
+				hold = true
+			} else if bn-1 < commit {
+				commit = bn - 1
+			}
+			continue
+		}
+		log.Printf("✅ [L1] Confirmed (%s) %s tx=%s block=%d user=%s", evt.Finality, evt.EventType, evt.TxHash, evt.BlockNumber, evt.UserAddress)
 
-// parseStateRegistryEvent parses StateRegistry events
-func (l *L1Listener) parseStateRegistryEvent(lg types.Log, event *models.L1Event) string {
-	if len(lg.Topics) < 1 {
-		return ""
+		// Remember released events so a deeper reorg can still retract them
+		l.pendingMu.Lock()
+		bn := uint64(evt.BlockNumber)
+		l.published[bn] = append(l.published[bn], evt)
+		l.pendingMu.Unlock()
 	}
 
-	// StateRootReceived(bytes32 indexed stateRoot, uint256 l2Block, uint256 timestamp)
-	event.Amount = "0" // State updates don't have amounts
-	event.UserAddress = "0x0000000000000000000000000000000000000000" // System event
+	// Forget released events older than the reorg window
+	l.pendingMu.Lock()
+	for bn := range l.published {
+		if n > reorgWindow && bn < n-reorgWindow {
+			delete(l.published, bn)
+		}
+	}
+	l.pendingMu.Unlock()
 
-	return "state_update"
+	// Only advance the checkpoint to the highest block whose events were all written (to the outbox or Kafka)
+	if !hold {
+		l.cursor.Commit(ctx, l.contracts, commit)
+	}
 }
 
-// parseLoyaltyUSDEvent parses LoyaltyUSD events
-func (l *L1Listener) parseLoyaltyUSDEvent(lg types.Log, event *models.L1Event) string {
-	if len(lg.Topics) < 3 {
-		return ""
+// publish writes a single event to Kafka
+func (l *L1Listener) publish(ctx context.Context, evt models.L1Event) error {
+	msg, err := events.Message(l.cfg.Codec, events.NewL1Envelope(l.cfg.ChainID, &evt), kafka.UserKey(evt.UserAddress),
+		k.Header{Key: "event_type", Value: []byte(evt.EventType)},
+		k.Header{Key: "contract", Value: []byte(evt.ContractAddress)},
+		k.Header{Key: "sequence", Value: []byte(strconv.FormatUint(evt.Sequence, 10))},
+	)
+	if err != nil {
+		return err
 	}
+	return l.writer.WriteMessages(ctx, msg)
+}
 
-	// Transfer(address indexed from, address indexed to, uint256 value)
-	to := common.BytesToAddress(lg.Topics[2].Bytes()).Hex()
-
-	if len(lg.Data) >= 32 {
-		amount := new(big.Int).SetBytes(lg.Data)
-		event.Amount = amount.String()
+// onReorg drops pending events and retracts released events from blocks that
+// are no longer canonical
+func (l *L1Listener) onReorg(blocks []uint64) {
+	if len(blocks) == 0 {
+		return
 	}
 
-	// Create event for recipient (for tracking)
-	event.UserAddress = to
-	event.Token = "LOYALTY_USD"
-
-	// Could emit two events (from/to) like the old listener
-	// For now, just track incoming transfers
+	var retract []models.L1Event
 
-	return "loyaltyusd_transfer"
-}
-
-// parseGatewayEvent parses Gateway events
-func (l *L1Listener) parseGatewayEvent(lg types.Log, event *models.L1Event) string {
-	if len(lg.Topics) < 1 {
-		return ""
+	l.pendingMu.Lock()
+	for _, bn := range blocks {
+		var keep []models.L1Event
+		for _, evt := range l.pending[bn] {
+			if l.blocks.canonical(bn, evt.BlockHash) {
+				keep = append(keep, evt)
+			} else {
+				log.Printf("🔀 [L1] Dropping pending %s tx=%s from reorged block %d", evt.EventType, evt.TxHash, bn)
+			}
+		}
+		l.pending[bn] = keep
+
+		var kept []models.L1Event
+		for _, evt := range l.published[bn] {
+			if l.blocks.canonical(bn, evt.BlockHash) {
+				kept = append(kept, evt)
+			} else {
+				retract = append(retract, evt)
+			}
+		}
+		l.published[bn] = kept
 	}
+	l.pendingMu.Unlock()
 
-	// MessageSent, MessageReceived, etc.
-	event.Amount = "0" // Bridge messages don't have amounts in L1 events
-	event.UserAddress = "0x0000000000000000000000000000000000000000"
-
-	return "gateway_message"
+	l.retract(retract)
 }
 
-// onHead confirms events after N blocks
-func (l *L1Listener) onHead(n uint64, conf int) {
-	l.head = n
+// onRemovedLog handles a log the node flagged as removed by a reorg
+func (l *L1Listener) onRemovedLog(lg types.Log) {
+	txHash := lg.TxHash.Hex()
+	blockHash := lg.BlockHash.Hex()
+	matches := func(evt models.L1Event) bool {
+		return evt.TxHash == txHash && evt.LogIndex == lg.Index && evt.BlockHash == blockHash
+	}
 
-	var confirmed []models.L1Event
-	keep := make(map[uint64][]models.L1Event)
+	var retract []models.L1Event
 
 	l.pendingMu.Lock()
-	for bn, list := range l.pending {
-		if n >= uint64(conf) && bn <= n-uint64(conf) {
-			confirmed = append(confirmed, list...)
+	var keep []models.L1Event
+	for _, evt := range l.pending[lg.BlockNumber] {
+		if matches(evt) {
+			log.Printf("🔀 [L1] Dropping removed %s tx=%s block=%d", evt.EventType, txHash, lg.BlockNumber)
 		} else {
-			keep[bn] = list
+			keep = append(keep, evt)
 		}
 	}
-	l.pending = keep
+	l.pending[lg.BlockNumber] = keep
+
+	var kept []models.L1Event
+	for _, evt := range l.published[lg.BlockNumber] {
+		if matches(evt) {
+			retract = append(retract, evt)
+		} else {
+			kept = append(kept, evt)
+		}
+	}
+	l.published[lg.BlockNumber] = kept
 	l.pendingMu.Unlock()
 
-	if len(confirmed) == 0 {
+	l.retract(retract)
+}
+
+// retract publishes reorg_retraction messages for events already sent to
+// Kafka. Retractions that cannot be written are kept and retried on the next head.
+func (l *L1Listener) retract(events []models.L1Event) {
+	if len(events) == 0 {
 		return
 	}
 
-	// Publish confirmed events to Kafka
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	for _, evt := range confirmed {
-		evt.Confirmed = true
-		data, _ := json.Marshal(evt)
-
-		msg := k.Message{
-			Key:   []byte(evt.UserAddress),
-			Value: data,
-			Headers: []k.Header{
-				{Key: "event_type", Value: []byte(evt.EventType)},
-				{Key: "contract", Value: []byte(evt.ContractAddress)},
-			},
-		}
-
-		if err := l.writer.WriteMessages(ctx, msg); err != nil {
-			log.Printf("❌ [L1] Kafka write error: %v", err)
+	for _, evt := range events {
+		retry := evt
+		evt.RetractedEventType = evt.EventType
+		evt.EventType = EventTypeReorgRetraction
+		evt.Timestamp = time.Now().Unix()
+
+		if err := l.publish(ctx, evt); err != nil {
+			log.Printf("❌ [L1] Kafka write error for retraction tx=%s: %v", evt.TxHash, err)
+			l.pendingMu.Lock()
+			l.unretracted = append(l.unretracted, retry)
+			l.pendingMu.Unlock()
 		} else {
-			log.Printf("✅ [L1] Confirmed %s tx=%s block=%d user=%s", evt.EventType, evt.TxHash, evt.BlockNumber, evt.UserAddress)
+			log.Printf("↩️  [L1] Retracted %s tx=%s block=%d user=%s", evt.RetractedEventType, evt.TxHash, evt.BlockNumber, evt.UserAddress)
 		}
 	}
 }
@@ -411,5 +641,6 @@ func (l *L1Listener) Close() error {
 	if l.client != nil {
 		l.client.Close()
 	}
+	l.finality.close()
 	return nil
 }
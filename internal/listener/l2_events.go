@@ -0,0 +1,1054 @@
+package listener
+
+import (
+	"math/big"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"loyalty-points-system/internal/blockchain/l2"
+	"loyalty-points-system/internal/models"
+)
+
+// Solidity enum names, indexed by their on-chain uint8 value
+var (
+	rwaAssetTypes    = []string{"RealEstate", "Bonds", "Equity", "Commodities", "ArtCollectible", "Invoice"}
+	rwaAssetStatuses = []string{"Pending", "Active", "Suspended", "Matured", "Defaulted"}
+	rwaOrderTypes    = []string{"FixedPrice", "Auction", "DutchAuction", "Offer"}
+	rwaProposalTypes = []string{"ParameterChange", "AssetSale", "YieldStrategy", "ValuationUpdate", "EmergencyAction"}
+)
+
+// enumName maps a Solidity enum value to its name, falling back to the numeric value
+func enumName(names []string, v uint8) string {
+	if int(v) < len(names) {
+		return names[v]
+	}
+	return strconv.Itoa(int(v))
+}
+
+// parseIntegratedVaultEvent decodes IntegratedVault events
+func (l *L2Listener) parseIntegratedVaultEvent(lg types.Log, event *models.L2Event) string {
+	name := eventName(l2.IntegratedVaultMetaData, lg)
+
+	switch name {
+	case "Deposited":
+		ev, err := l.integratedVault.ParseDeposited(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = bigString(ev.Amount)
+		return "vault_deposit"
+
+	case "Withdrawn":
+		ev, err := l.integratedVault.ParseWithdrawn(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = bigString(ev.Amount)
+		return "vault_withdraw"
+
+	case "Borrowed":
+		ev, err := l.integratedVault.ParseBorrowed(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = bigString(ev.Amount)
+		return "vault_borrow"
+
+	case "Repaid":
+		ev, err := l.integratedVault.ParseRepaid(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = bigString(ev.Amount)
+		return "vault_repay"
+
+	case "InterestAccrued":
+		ev, err := l.integratedVault.ParseInterestAccrued(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = bigString(ev.InterestAmount)
+		return "vault_interest_accrued"
+
+	case "Liquidated":
+		ev, err := l.integratedVault.ParseLiquidated(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = bigString(ev.CollateralSeized)
+		event.Metadata = map[string]interface{}{
+			"liquidator":        ev.Liquidator.Hex(),
+			"debt_repaid":       bigString(ev.DebtRepaid),
+			"collateral_seized": bigString(ev.CollateralSeized),
+		}
+		return "vault_liquidated"
+	}
+
+	// Ownership and pause events are not tracked
+	return ""
+}
+
+// parseStateAggregatorEvent decodes L2StateAggregator events
+func (l *L2Listener) parseStateAggregatorEvent(lg types.Log, event *models.L2Event) string {
+	name := eventName(l2.L2StateAggregatorMetaData, lg)
+	event.UserAddress = systemAddress
+	event.Amount = "0"
+
+	switch name {
+	case "StateRootComputed":
+		ev, err := l.stateAggregator.ParseStateRootComputed(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.Metadata = map[string]interface{}{
+			"state_root":   hashString(ev.StateRoot),
+			"l2_block":     bigString(ev.BlockNumber),
+			"l2_timestamp": bigString(ev.Timestamp),
+		}
+		return "state_root_computed"
+
+	case "StateSubmittedToL1":
+		ev, err := l.stateAggregator.ParseStateSubmittedToL1(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.Metadata = map[string]interface{}{
+			"state_root":       hashString(ev.StateRoot),
+			"l2_block":         bigString(ev.L2Block),
+			"l1_tx_id":         bigString(ev.L1TxId),
+			"total_collateral": bigString(ev.TotalCollateral),
+			"total_debt":       bigString(ev.TotalDebt),
+		}
+		return "state_submitted_to_l1"
+
+	case "ModuleStateUpdated":
+		ev, err := l.stateAggregator.ParseModuleStateUpdated(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.Metadata = map[string]interface{}{
+			"module_id":  hashString(ev.ModuleId),
+			"state_hash": hashString(ev.StateHash),
+			"updated_by": ev.UpdatedBy.Hex(),
+		}
+		return "state_module_updated"
+	}
+
+	return ""
+}
+
+// parseDeFiAdapterEvent decodes Aave, Compound and Uniswap adapter events
+func (l *L2Listener) parseDeFiAdapterEvent(lg types.Log, event *models.L2Event, protocol string) string {
+	var action string
+	switch protocol {
+	case "aave":
+		action = l.parseAaveEvent(lg, event)
+	case "compound":
+		action = l.parseCompoundEvent(lg, event)
+	case "uniswap":
+		action = l.parseUniswapEvent(lg, event)
+	}
+
+	if action == "" {
+		return ""
+	}
+
+	if event.Metadata == nil {
+		event.Metadata = make(map[string]interface{})
+	}
+	event.Metadata["protocol"] = protocol
+
+	return "defi_" + protocol + "_" + action
+}
+
+// parseAaveEvent decodes AaveV3Adapter events and returns the action name
+func (l *L2Listener) parseAaveEvent(lg types.Log, event *models.L2Event) string {
+	name := eventName(l2.AaveV3AdapterMetaData, lg)
+
+	switch name {
+	case "Supplied":
+		ev, err := l.aaveAdapter.ParseSupplied(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = bigString(ev.Amount)
+		event.Metadata = map[string]interface{}{"asset": ev.Asset.Hex()}
+		return "supply"
+
+	case "Withdrawn":
+		ev, err := l.aaveAdapter.ParseWithdrawn(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = bigString(ev.Amount)
+		event.Metadata = map[string]interface{}{"asset": ev.Asset.Hex()}
+		return "withdraw"
+
+	case "Borrowed":
+		ev, err := l.aaveAdapter.ParseBorrowed(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = bigString(ev.Amount)
+		event.Metadata = map[string]interface{}{
+			"asset":              ev.Asset.Hex(),
+			"interest_rate_mode": bigString(ev.InterestRateMode),
+		}
+		return "borrow"
+
+	case "Repaid":
+		ev, err := l.aaveAdapter.ParseRepaid(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = bigString(ev.Amount)
+		event.Metadata = map[string]interface{}{
+			"asset":              ev.Asset.Hex(),
+			"interest_rate_mode": bigString(ev.InterestRateMode),
+		}
+		return "repay"
+
+	case "CollateralStatusChanged":
+		ev, err := l.aaveAdapter.ParseCollateralStatusChanged(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = "0"
+		event.Metadata = map[string]interface{}{
+			"asset":             ev.Asset.Hex(),
+			"use_as_collateral": ev.UseAsCollateral,
+		}
+		return "collateral_status"
+
+	case "InterestRateModeSwapped":
+		ev, err := l.aaveAdapter.ParseInterestRateModeSwapped(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = "0"
+		event.Metadata = map[string]interface{}{
+			"asset":    ev.Asset.Hex(),
+			"new_mode": bigString(ev.NewMode),
+		}
+		return "rate_mode_swap"
+
+	case "FlashLoanExecuted":
+		ev, err := l.aaveAdapter.ParseFlashLoanExecuted(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.Initiator.Hex()
+		event.Amount = bigString(ev.Amount)
+		event.Metadata = map[string]interface{}{
+			"asset":   ev.Asset.Hex(),
+			"premium": bigString(ev.Premium),
+		}
+		return "flash_loan"
+	}
+
+	return ""
+}
+
+// parseCompoundEvent decodes CompoundV3Adapter events and returns the action name
+func (l *L2Listener) parseCompoundEvent(lg types.Log, event *models.L2Event) string {
+	name := eventName(l2.CompoundV3AdapterMetaData, lg)
+
+	switch name {
+	case "BaseSupplied":
+		ev, err := l.compoundAdapter.ParseBaseSupplied(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = bigString(ev.Amount)
+		return "supply"
+
+	case "BaseWithdrawn":
+		ev, err := l.compoundAdapter.ParseBaseWithdrawn(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = bigString(ev.Amount)
+		return "withdraw"
+
+	case "Borrowed":
+		ev, err := l.compoundAdapter.ParseBorrowed(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = bigString(ev.Amount)
+		return "borrow"
+
+	case "Repaid":
+		ev, err := l.compoundAdapter.ParseRepaid(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = bigString(ev.Amount)
+		return "repay"
+
+	case "CollateralSupplied":
+		ev, err := l.compoundAdapter.ParseCollateralSupplied(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = bigString(ev.Amount)
+		event.Metadata = map[string]interface{}{"asset": ev.Asset.Hex()}
+		return "collateral_supply"
+
+	case "CollateralWithdrawn":
+		ev, err := l.compoundAdapter.ParseCollateralWithdrawn(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = bigString(ev.Amount)
+		event.Metadata = map[string]interface{}{"asset": ev.Asset.Hex()}
+		return "collateral_withdraw"
+
+	case "RewardsClaimed":
+		ev, err := l.compoundAdapter.ParseRewardsClaimed(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = bigString(ev.Amount)
+		return "rewards_claimed"
+	}
+
+	return ""
+}
+
+// parseUniswapEvent decodes UniswapV3Adapter events and returns the action name
+func (l *L2Listener) parseUniswapEvent(lg types.Log, event *models.L2Event) string {
+	name := eventName(l2.UniswapV3AdapterMetaData, lg)
+
+	switch name {
+	case "Swapped":
+		ev, err := l.uniswapAdapter.ParseSwapped(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = bigString(ev.AmountIn)
+		event.Metadata = map[string]interface{}{
+			"token_in":   ev.TokenIn.Hex(),
+			"token_out":  ev.TokenOut.Hex(),
+			"amount_out": bigString(ev.AmountOut),
+			"fee":        bigString(ev.Fee),
+		}
+		return "swap"
+
+	case "MultiHopSwap":
+		ev, err := l.uniswapAdapter.ParseMultiHopSwap(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = bigString(ev.AmountIn)
+		event.Metadata = map[string]interface{}{
+			"amount_out": bigString(ev.AmountOut),
+		}
+		return "multihop_swap"
+	}
+
+	return ""
+}
+
+// parseRWAEvent decodes RWA contract events
+func (l *L2Listener) parseRWAEvent(lg types.Log, event *models.L2Event, contractType string) string {
+	var eventType string
+	switch contractType {
+	case "factory":
+		eventType = l.parseRWAFactoryEvent(lg, event)
+	case "marketplace":
+		eventType = l.parseRWAMarketplaceEvent(lg, event)
+	case "yield":
+		eventType = l.parseRWAYieldEvent(lg, event)
+	case "compliance":
+		eventType = l.parseRWAComplianceEvent(lg, event)
+	case "valuation":
+		eventType = l.parseRWAValuationEvent(lg, event)
+	case "governance":
+		eventType = l.parseRWAGovernanceEvent(lg, event)
+	}
+
+	if eventType == "" {
+		return ""
+	}
+
+	if event.Metadata == nil {
+		event.Metadata = make(map[string]interface{})
+	}
+	event.Metadata["rwa_contract"] = contractType
+
+	return eventType
+}
+
+// parseRWAFactoryEvent decodes RWAAssetFactory events
+func (l *L2Listener) parseRWAFactoryEvent(lg types.Log, event *models.L2Event) string {
+	name := eventName(l2.RWAAssetFactoryMetaData, lg)
+
+	switch name {
+	case "AssetCreated":
+		ev, err := l.rwaFactory.ParseAssetCreated(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.Issuer.Hex()
+		event.Amount = bigString(ev.TotalValue)
+		event.Metadata = map[string]interface{}{
+			"asset_id":    bigString(ev.AssetId),
+			"asset_type":  enumName(rwaAssetTypes, ev.AssetType),
+			"total_value": bigString(ev.TotalValue),
+		}
+		return "rwa_asset_created"
+
+	case "AssetFractionalized":
+		ev, err := l.rwaFactory.ParseAssetFractionalized(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = systemAddress
+		event.Amount = bigString(ev.TotalSupply)
+		event.Metadata = map[string]interface{}{
+			"asset_id":      bigString(ev.AssetId),
+			"token_address": ev.FractionalToken.Hex(),
+		}
+		return "rwa_asset_fractionalized"
+
+	case "AssetStatusChanged":
+		ev, err := l.rwaFactory.ParseAssetStatusChanged(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = systemAddress
+		event.Amount = "0"
+		event.Metadata = map[string]interface{}{
+			"asset_id":   bigString(ev.AssetId),
+			"old_status": enumName(rwaAssetStatuses, ev.OldStatus),
+			"new_status": enumName(rwaAssetStatuses, ev.NewStatus),
+		}
+		return "rwa_asset_status_changed"
+
+	case "AssetValuationUpdated":
+		ev, err := l.rwaFactory.ParseAssetValuationUpdated(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = systemAddress
+		event.Amount = bigString(ev.NewValue)
+		event.Metadata = map[string]interface{}{
+			"asset_id":  bigString(ev.AssetId),
+			"old_value": bigString(ev.OldValue),
+			"new_value": bigString(ev.NewValue),
+		}
+		return "rwa_asset_valuation_updated"
+
+	case "YieldDistributed":
+		ev, err := l.rwaFactory.ParseYieldDistributed(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = systemAddress
+		event.Amount = bigString(ev.Amount)
+		event.Metadata = map[string]interface{}{
+			"asset_id": bigString(ev.AssetId),
+		}
+		return "rwa_asset_yield_distributed"
+	}
+
+	return ""
+}
+
+// parseRWAMarketplaceEvent decodes RWAMarketplace events
+func (l *L2Listener) parseRWAMarketplaceEvent(lg types.Log, event *models.L2Event) string {
+	name := eventName(l2.RWAMarketplaceMetaData, lg)
+
+	switch name {
+	case "ListingCreated":
+		ev, err := l.rwaMarketplace.ParseListingCreated(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.Seller.Hex()
+		event.Amount = bigString(ev.Amount)
+		event.Metadata = map[string]interface{}{
+			"listing_id": bigString(ev.ListingId),
+			"asset_id":   bigString(ev.AssetId),
+			"price":      bigString(ev.Price),
+			"order_type": enumName(rwaOrderTypes, ev.OrderType),
+		}
+		return "rwa_listing_created"
+
+	case "ListingCancelled":
+		ev, err := l.rwaMarketplace.ParseListingCancelled(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.Seller.Hex()
+		event.Amount = "0"
+		event.Metadata = map[string]interface{}{
+			"listing_id": bigString(ev.ListingId),
+		}
+		return "rwa_listing_cancelled"
+
+	case "TradExecuted":
+		ev, err := l.rwaMarketplace.ParseTradExecuted(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.Buyer.Hex()
+		event.Amount = bigString(ev.Amount)
+		event.Metadata = map[string]interface{}{
+			"trade_id":    bigString(ev.TradeId),
+			"listing_id":  bigString(ev.ListingId),
+			"buyer":       ev.Buyer.Hex(),
+			"seller":      ev.Seller.Hex(),
+			"price":       bigString(ev.Price),
+			"total_value": bigString(ev.TotalValue),
+		}
+		return "rwa_trade_executed"
+
+	case "BidPlaced":
+		ev, err := l.rwaMarketplace.ParseBidPlaced(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.Bidder.Hex()
+		event.Amount = bigString(ev.BidAmount)
+		event.Metadata = map[string]interface{}{
+			"listing_id": bigString(ev.ListingId),
+		}
+		return "rwa_bid_placed"
+
+	case "AuctionFinalized":
+		ev, err := l.rwaMarketplace.ParseAuctionFinalized(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.Winner.Hex()
+		event.Amount = bigString(ev.FinalPrice)
+		event.Metadata = map[string]interface{}{
+			"listing_id":  bigString(ev.ListingId),
+			"final_price": bigString(ev.FinalPrice),
+		}
+		return "rwa_auction_finalized"
+	}
+
+	return ""
+}
+
+// parseRWAYieldEvent decodes RWAYieldDistributor events
+func (l *L2Listener) parseRWAYieldEvent(lg types.Log, event *models.L2Event) string {
+	name := eventName(l2.RWAYieldDistributorMetaData, lg)
+
+	switch name {
+	case "YieldDeposited":
+		ev, err := l.rwaYield.ParseYieldDeposited(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = systemAddress
+		event.Amount = bigString(ev.Amount)
+		event.Metadata = map[string]interface{}{
+			"distribution_id": bigString(ev.DistributionId),
+			"asset_id":        bigString(ev.AssetId),
+			"payment_token":   ev.PaymentToken.Hex(),
+			"claim_deadline":  bigString(ev.ClaimDeadline),
+		}
+		return "rwa_yield_deposited"
+
+	case "YieldClaimed":
+		ev, err := l.rwaYield.ParseYieldClaimed(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.User.Hex()
+		event.Amount = bigString(ev.Amount)
+		event.Metadata = map[string]interface{}{
+			"distribution_id": bigString(ev.DistributionId),
+		}
+		return "rwa_yield_claimed"
+
+	case "DistributionFinalized":
+		ev, err := l.rwaYield.ParseDistributionFinalized(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = systemAddress
+		event.Amount = bigString(ev.TotalClaimed)
+		event.Metadata = map[string]interface{}{
+			"distribution_id": bigString(ev.DistributionId),
+			"total_claimed":   bigString(ev.TotalClaimed),
+			"unclaimed":       bigString(ev.Unclaimed),
+		}
+		return "rwa_distribution_finalized"
+
+	case "UnclaimedYieldReclaimed":
+		ev, err := l.rwaYield.ParseUnclaimedYieldReclaimed(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.Recipient.Hex()
+		event.Amount = bigString(ev.Amount)
+		event.Metadata = map[string]interface{}{
+			"distribution_id": bigString(ev.DistributionId),
+		}
+		return "rwa_yield_reclaimed"
+	}
+
+	return ""
+}
+
+// parseRWAComplianceEvent decodes RWACompliance events
+func (l *L2Listener) parseRWAComplianceEvent(lg types.Log, event *models.L2Event) string {
+	name := eventName(l2.RWAComplianceMetaData, lg)
+	event.Amount = "0"
+
+	switch name {
+	case "InvestorVerified":
+		ev, err := l.rwaCompliance.ParseInvestorVerified(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.Investor.Hex()
+		event.Metadata = map[string]interface{}{
+			"status":     ev.Status,
+			"tier":       ev.Tier,
+			"expires_at": bigString(ev.ExpiresAt),
+		}
+		return "rwa_investor_verified"
+
+	case "InvestorStatusChanged":
+		ev, err := l.rwaCompliance.ParseInvestorStatusChanged(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.Investor.Hex()
+		event.Metadata = map[string]interface{}{
+			"old_status": ev.OldStatus,
+			"new_status": ev.NewStatus,
+		}
+		return "rwa_investor_status_changed"
+
+	case "ComplianceViolation":
+		ev, err := l.rwaCompliance.ParseComplianceViolation(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.Investor.Hex()
+		event.Metadata = map[string]interface{}{
+			"asset_id": bigString(ev.AssetId),
+			"reason":   ev.Reason,
+		}
+		return "rwa_compliance_violation"
+
+	case "AssetComplianceSet":
+		ev, err := l.rwaCompliance.ParseAssetComplianceSet(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = systemAddress
+		event.Metadata = map[string]interface{}{
+			"asset_id":     bigString(ev.AssetId),
+			"min_tier":     ev.MinTier,
+			"requires_kyc": ev.RequiresKYC,
+		}
+		return "rwa_asset_compliance_set"
+
+	case "JurisdictionUpdated":
+		ev, err := l.rwaCompliance.ParseJurisdictionUpdated(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = systemAddress
+		event.Metadata = map[string]interface{}{
+			"asset_id":     bigString(ev.AssetId),
+			"jurisdiction": ev.Jurisdiction,
+			"allowed":      ev.Allowed,
+		}
+		return "rwa_jurisdiction_updated"
+	}
+
+	return ""
+}
+
+// parseRWAValuationEvent decodes RWAValuation events
+func (l *L2Listener) parseRWAValuationEvent(lg types.Log, event *models.L2Event) string {
+	name := eventName(l2.RWAValuationMetaData, lg)
+
+	switch name {
+	case "ValuationUpdated":
+		ev, err := l.rwaValuation.ParseValuationUpdated(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.Valuator.Hex()
+		event.Amount = bigString(ev.NewValue)
+		event.Metadata = map[string]interface{}{
+			"asset_id":  bigString(ev.AssetId),
+			"old_value": bigString(ev.OldValue),
+			"new_value": bigString(ev.NewValue),
+			"method":    ev.Method,
+		}
+		return "rwa_valuation_updated"
+
+	case "ValuationDisputed":
+		ev, err := l.rwaValuation.ParseValuationDisputed(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.Disputer.Hex()
+		event.Amount = bigString(ev.DisputedValue)
+		event.Metadata = map[string]interface{}{
+			"asset_id": bigString(ev.AssetId),
+			"reason":   ev.Reason,
+		}
+		return "rwa_valuation_disputed"
+
+	case "OracleConfigured":
+		ev, err := l.rwaValuation.ParseOracleConfigured(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = systemAddress
+		event.Amount = "0"
+		event.Metadata = map[string]interface{}{
+			"asset_id":  bigString(ev.AssetId),
+			"oracle":    ev.Oracle.Hex(),
+			"heartbeat": bigString(ev.Heartbeat),
+		}
+		return "rwa_oracle_configured"
+
+	case "ValuatorAuthorized":
+		ev, err := l.rwaValuation.ParseValuatorAuthorized(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.Valuator.Hex()
+		event.Amount = "0"
+		event.Metadata = map[string]interface{}{
+			"authorized": ev.Authorized,
+		}
+		return "rwa_valuator_authorized"
+	}
+
+	return ""
+}
+
+// parseRWAGovernanceEvent decodes RWAGovernance events
+func (l *L2Listener) parseRWAGovernanceEvent(lg types.Log, event *models.L2Event) string {
+	name := eventName(l2.RWAGovernanceMetaData, lg)
+	event.Amount = "0"
+
+	switch name {
+	case "ProposalCreated":
+		ev, err := l.rwaGovernance.ParseProposalCreated(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.Proposer.Hex()
+		event.Metadata = map[string]interface{}{
+			"proposal_id":   bigString(ev.ProposalId),
+			"asset_id":      bigString(ev.AssetId),
+			"proposal_type": enumName(rwaProposalTypes, ev.ProposalType),
+			"description":   ev.Description,
+		}
+		return "rwa_proposal_created"
+
+	case "VoteCast":
+		ev, err := l.rwaGovernance.ParseVoteCast(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.Voter.Hex()
+		event.Amount = bigString(ev.Votes)
+		event.Metadata = map[string]interface{}{
+			"proposal_id": bigString(ev.ProposalId),
+			"choice":      ev.Choice,
+		}
+		return "rwa_vote_cast"
+
+	case "VoteDelegated":
+		ev, err := l.rwaGovernance.ParseVoteDelegated(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.Delegator.Hex()
+		event.Metadata = map[string]interface{}{
+			"delegate": ev.Delegate.Hex(),
+			"asset_id": bigString(ev.AssetId),
+		}
+		return "rwa_vote_delegated"
+
+	case "ProposalExecuted":
+		ev, err := l.rwaGovernance.ParseProposalExecuted(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = systemAddress
+		event.Metadata = map[string]interface{}{
+			"proposal_id": bigString(ev.ProposalId),
+			"success":     ev.Success,
+		}
+		return "rwa_proposal_executed"
+
+	case "ProposalCancelled":
+		ev, err := l.rwaGovernance.ParseProposalCancelled(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = systemAddress
+		event.Metadata = map[string]interface{}{
+			"proposal_id": bigString(ev.ProposalId),
+		}
+		return "rwa_proposal_cancelled"
+
+	case "ProposalVetoed":
+		ev, err := l.rwaGovernance.ParseProposalVetoed(lg)
+		if err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+		event.UserAddress = ev.Vetoer.Hex()
+		event.Metadata = map[string]interface{}{
+			"proposal_id": bigString(ev.ProposalId),
+		}
+		return "rwa_proposal_vetoed"
+	}
+
+	return ""
+}
+
+// treasuryEventsABI covers the Treasury contract events; these contracts ship without abigen bindings
+const treasuryEventsABI = `[
+	{"type":"event","name":"TreasuryAssetCreated","inputs":[
+		{"name":"assetId","type":"uint256","indexed":true},
+		{"name":"treasuryType","type":"uint8","indexed":false},
+		{"name":"cusip","type":"string","indexed":false},
+		{"name":"tokenAddress","type":"address","indexed":true},
+		{"name":"faceValue","type":"uint256","indexed":false}]},
+	{"type":"event","name":"OrderCreated","inputs":[
+		{"name":"orderId","type":"uint256","indexed":true},
+		{"name":"assetId","type":"uint256","indexed":true},
+		{"name":"user","type":"address","indexed":true},
+		{"name":"orderType","type":"uint8","indexed":false},
+		{"name":"tokenAmount","type":"uint256","indexed":false},
+		{"name":"pricePerToken","type":"uint256","indexed":false}]},
+	{"type":"event","name":"OrderMatched","inputs":[
+		{"name":"tradeId","type":"uint256","indexed":true},
+		{"name":"buyOrderId","type":"uint256","indexed":true},
+		{"name":"sellOrderId","type":"uint256","indexed":true},
+		{"name":"buyer","type":"address","indexed":false},
+		{"name":"seller","type":"address","indexed":false},
+		{"name":"amount","type":"uint256","indexed":false},
+		{"name":"price","type":"uint256","indexed":false}]},
+	{"type":"event","name":"OrderCancelled","inputs":[
+		{"name":"orderId","type":"uint256","indexed":true},
+		{"name":"user","type":"address","indexed":true}]},
+	{"type":"event","name":"YieldDeposited","inputs":[
+		{"name":"distributionId","type":"uint256","indexed":true},
+		{"name":"assetId","type":"uint256","indexed":true},
+		{"name":"totalYield","type":"uint256","indexed":false},
+		{"name":"yieldPerToken","type":"uint256","indexed":false},
+		{"name":"distributionType","type":"string","indexed":false}]},
+	{"type":"event","name":"YieldClaimed","inputs":[
+		{"name":"user","type":"address","indexed":true},
+		{"name":"assetId","type":"uint256","indexed":true},
+		{"name":"amount","type":"uint256","indexed":false},
+		{"name":"distributionId","type":"uint256","indexed":false}]},
+	{"type":"event","name":"PriceUpdated","inputs":[
+		{"name":"assetId","type":"uint256","indexed":true},
+		{"name":"price","type":"uint256","indexed":false},
+		{"name":"yield","type":"uint256","indexed":false},
+		{"name":"timestamp","type":"uint256","indexed":false},
+		{"name":"source","type":"string","indexed":false}]}
+]`
+
+var treasuryTypes = []string{"T_BILL", "T_NOTE", "T_BOND"}
+
+var treasuryMetaData = &bind.MetaData{ABI: treasuryEventsABI}
+
+// parseTreasuryEvent decodes Treasury contract events
+func (l *L2Listener) parseTreasuryEvent(lg types.Log, event *models.L2Event, contractType string) string {
+	name := eventName(treasuryMetaData, lg)
+	if name == "" {
+		return ""
+	}
+
+	parsed, err := treasuryMetaData.GetAbi()
+	if err != nil {
+		return ""
+	}
+
+	// Decode indexed and non-indexed arguments into a single map
+	fields := make(map[string]interface{})
+	ev := parsed.Events[name]
+	if len(lg.Data) > 0 {
+		if err := parsed.UnpackIntoMap(fields, name, lg.Data); err != nil {
+			logDecodeError("L2", name, lg, err)
+			return ""
+		}
+	}
+	var indexed abi.Arguments
+	for _, arg := range ev.Inputs {
+		if arg.Indexed {
+			indexed = append(indexed, arg)
+		}
+	}
+	if err := abi.ParseTopicsIntoMap(fields, indexed, lg.Topics[1:]); err != nil {
+		logDecodeError("L2", name, lg, err)
+		return ""
+	}
+
+	metadata := map[string]interface{}{
+		"contract_type": contractType,
+		"log_index":     lg.Index,
+	}
+	event.Metadata = metadata
+	event.UserAddress = systemAddress
+	event.Amount = "0"
+
+	switch name {
+	case "TreasuryAssetCreated":
+		metadata["asset_id"] = bigString(fields["assetId"].(*big.Int))
+		metadata["treasury_type"] = enumName(treasuryTypes, fields["treasuryType"].(uint8))
+		metadata["cusip"] = fields["cusip"].(string)
+		metadata["token_address"] = fields["tokenAddress"].(common.Address).Hex()
+		metadata["face_value"] = bigString(fields["faceValue"].(*big.Int))
+		event.Amount = bigString(fields["faceValue"].(*big.Int))
+		return "treasury_token_created"
+
+	case "OrderCreated":
+		user := fields["user"].(common.Address).Hex()
+		metadata["order_id"] = bigString(fields["orderId"].(*big.Int))
+		metadata["asset_id"] = bigString(fields["assetId"].(*big.Int))
+		metadata["user_address"] = user
+		metadata["order_type"] = fields["orderType"].(uint8)
+		metadata["price_per_token"] = bigString(fields["pricePerToken"].(*big.Int))
+		event.UserAddress = user
+		event.Amount = bigString(fields["tokenAmount"].(*big.Int))
+		return "treasury_order_created"
+
+	case "OrderMatched":
+		buyer := fields["buyer"].(common.Address).Hex()
+		metadata["trade_id"] = bigString(fields["tradeId"].(*big.Int))
+		metadata["buy_order_id"] = bigString(fields["buyOrderId"].(*big.Int))
+		metadata["sell_order_id"] = bigString(fields["sellOrderId"].(*big.Int))
+		metadata["buyer"] = buyer
+		metadata["seller"] = fields["seller"].(common.Address).Hex()
+		metadata["price"] = bigString(fields["price"].(*big.Int))
+		event.UserAddress = buyer
+		event.Amount = bigString(fields["amount"].(*big.Int))
+		return "treasury_order_matched"
+
+	case "OrderCancelled":
+		user := fields["user"].(common.Address).Hex()
+		metadata["order_id"] = bigString(fields["orderId"].(*big.Int))
+		metadata["user_address"] = user
+		event.UserAddress = user
+		return "treasury_order_cancelled"
+
+	case "YieldDeposited":
+		metadata["distribution_id"] = bigString(fields["distributionId"].(*big.Int))
+		metadata["asset_id"] = bigString(fields["assetId"].(*big.Int))
+		metadata["yield_per_token"] = bigString(fields["yieldPerToken"].(*big.Int))
+		metadata["distribution_type"] = fields["distributionType"].(string)
+		event.Amount = bigString(fields["totalYield"].(*big.Int))
+		return "treasury_yield_deposited"
+
+	case "YieldClaimed":
+		user := fields["user"].(common.Address).Hex()
+		metadata["user_address"] = user
+		metadata["asset_id"] = bigString(fields["assetId"].(*big.Int))
+		metadata["distribution_id"] = bigString(fields["distributionId"].(*big.Int))
+		event.UserAddress = user
+		event.Amount = bigString(fields["amount"].(*big.Int))
+		return "treasury_yield_claimed"
+
+	case "PriceUpdated":
+		metadata["asset_id"] = bigString(fields["assetId"].(*big.Int))
+		metadata["price"] = bigString(fields["price"].(*big.Int))
+		metadata["yield"] = bigString(fields["yield"].(*big.Int))
+		metadata["price_timestamp"] = bigString(fields["timestamp"].(*big.Int))
+		metadata["source"] = fields["source"].(string)
+		event.Amount = bigString(fields["price"].(*big.Int))
+		return "treasury_price_updated"
+	}
+
+	return ""
+}
@@ -2,27 +2,31 @@ package listener
 
 import (
 	"context"
-	"encoding/json"
 	"log"
-	"math/big"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
 	k "github.com/segmentio/kafka-go"
 
+	"loyalty-points-system/internal/blockchain/l2"
+	"loyalty-points-system/internal/events"
+	"loyalty-points-system/internal/kafka"
 	"loyalty-points-system/internal/models"
 )
 
 // L2ListenerConfig holds configuration for L2 listener
 type L2ListenerConfig struct {
+	Name                string // Listener name on /status and in metrics (default "l2")
 	RPCURL              string
 	WSSURL              string
 	ChainID             int64
 	Confirmations       int
+	Finality            FinalityMode // Release policy (empty = FinalityDepth, using Confirmations)
+	ParentRPCURL        string       // Parent chain RPC, used by FinalityL1Batch
 	IntegratedVault     string
 	StateAggregator     string
 	AaveAdapter         string
@@ -39,35 +43,109 @@ type L2ListenerConfig struct {
 	TreasuryMarketplace     string
 	TreasuryYieldDistributor string
 	TreasuryPriceOracle     string
+
+	// Historical catch-up
+	StartBlock    uint64          // First block to backfill when no checkpoint exists (0 = head)
+	BackfillBatch uint64          // Max blocks per FilterLogs call
+	Checkpoints   CheckpointStore // Optional; without it the listener starts from head
+
+	// Poll mode, used when WSSURL is empty or unreachable
+	PollInterval time.Duration // eth_blockNumber polling interval (0 = DefaultPollInterval)
+
+	Codec events.Codec // Envelope encoding (nil = JSON)
 }
 
 // L2Listener listens to L2 events and publishes to Kafka
 type L2Listener struct {
 	cfg       L2ListenerConfig
-	client    *ethclient.Client
+	client    chainClient
 	writer    Writer
 	pending   map[uint64][]models.L2Event
 	pendingMu sync.Mutex
 	head      uint64
+
+	// Release policy and block cursor for backfill and checkpointing
+	finality     *finalizer
+	cursor       *BlockCursor
+	contracts    []common.Address
+	backfilledTo uint64
+
+	// Reorg handling: canonical hashes and recently published events. The
+	// published window lives in memory only: events released before a restart
+	// cannot be retracted if their blocks are reorged afterwards, so a
+	// finality policy short of "final" accepts that gap.
+	blocks      *blockTracker
+	published   map[uint64][]models.L2Event
+	unretracted []models.L2Event // retractions that could not be written, retried on the next head
+
+	// Current connection session, replaced on every reconnect
+	subs    *subscriptionSet
+	headers chan *types.Header
+	logsCh  chan types.Log
+	health  *health
+
+	// Contract bindings used to decode logs
+	integratedVault *l2.IntegratedVault
+	stateAggregator *l2.L2StateAggregator
+	aaveAdapter     *l2.AaveV3Adapter
+	compoundAdapter *l2.CompoundV3Adapter
+	uniswapAdapter  *l2.UniswapV3Adapter
+	rwaFactory      *l2.RWAAssetFactory
+	rwaMarketplace  *l2.RWAMarketplace
+	rwaYield        *l2.RWAYieldDistributor
+	rwaCompliance   *l2.RWACompliance
+	rwaValuation    *l2.RWAValuation
+	rwaGovernance   *l2.RWAGovernance
 }
 
 // NewL2Listener creates a new L2 listener
 func NewL2Listener(cfg L2ListenerConfig, writer Writer) *L2Listener {
 	return &L2Listener{
-		cfg:     cfg,
-		writer:  writer,
-		pending: make(map[uint64][]models.L2Event),
+		cfg:       cfg,
+		writer:    writer,
+		pending:   make(map[uint64][]models.L2Event),
+		published: make(map[uint64][]models.L2Event),
+		blocks:    newBlockTracker("L2"),
+		finality:  newFinalizer("L2", cfg.Finality, confirmations(cfg.Confirmations, 1), cfg.ParentRPCURL),
+		cursor:    NewBlockCursor(cfg.Checkpoints, "l2", cfg.ChainID, cfg.StartBlock, cfg.BackfillBatch),
+		health:    newHealth(listenerName(cfg.Name, "l2"), time.Minute),
 	}
 }
 
-// Start begins listening to L2 events
+// Start connects to L2 and begins listening to events. Dropped subscriptions
+// are re-dialed in the background with exponential backoff.
 func (l *L2Listener) Start(ctx context.Context) error {
-	var err error
-	l.client, err = ethclient.DialContext(ctx, l.cfg.WSSURL)
+	if err := l.finality.check(); err != nil {
+		return err
+	}
+	if err := l.connect(ctx); err != nil {
+		return err
+	}
+	go l.supervise(ctx)
+	return nil
+}
+
+// Status reports the current connection state of the listener
+func (l *L2Listener) Status() Status {
+	return l.health.snapshot()
+}
+
+// connect dials L2 (websocket, or HTTP polling as a fallback), subscribes to
+// heads and every configured contract, and backfills the logs missed since the
+// last checkpoint or the last seen head
+func (l *L2Listener) connect(ctx context.Context) error {
+	client, err := dialChain(ctx, "L2", l.cfg.WSSURL, l.cfg.RPCURL, l.cfg.PollInterval)
 	if err != nil {
 		return err
 	}
-	log.Printf("🔌 [L2] Connected to %s (chain ID: %d)", l.cfg.WSSURL, l.cfg.ChainID)
+	if l.client != nil {
+		l.client.Close()
+	}
+	l.client = client
+
+	if err := l.bindContracts(); err != nil {
+		return err
+	}
 
 	// Subscribe to new block headers
 	headers := make(chan *types.Header, 32)
@@ -75,37 +153,45 @@ func (l *L2Listener) Start(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	subs := newSubscriptionSet()
+	subs.watch("L2", "head", subHead)
 
 	// Start listening to each contract
 	logsCh := make(chan types.Log, 256)
+	l.contracts = nil
 
 	// Subscribe to IntegratedVault events
 	if l.cfg.IntegratedVault != "" {
-		if err := l.subscribeIntegratedVault(ctx, logsCh); err != nil {
+		if err := l.subscribeIntegratedVault(ctx, logsCh, subs); err != nil {
+			subs.close()
 			return err
 		}
 	}
 
 	// Subscribe to StateAggregator events
 	if l.cfg.StateAggregator != "" {
-		if err := l.subscribeStateAggregator(ctx, logsCh); err != nil {
+		if err := l.subscribeStateAggregator(ctx, logsCh, subs); err != nil {
+			subs.close()
 			return err
 		}
 	}
 
 	// Subscribe to DeFi adapter events
 	if l.cfg.AaveAdapter != "" {
-		if err := l.subscribeDeFiAdapter(ctx, logsCh, l.cfg.AaveAdapter, "Aave"); err != nil {
+		if err := l.subscribeDeFiAdapter(ctx, logsCh, l.cfg.AaveAdapter, "Aave", subs); err != nil {
+			subs.close()
 			return err
 		}
 	}
 	if l.cfg.CompoundAdapter != "" {
-		if err := l.subscribeDeFiAdapter(ctx, logsCh, l.cfg.CompoundAdapter, "Compound"); err != nil {
+		if err := l.subscribeDeFiAdapter(ctx, logsCh, l.cfg.CompoundAdapter, "Compound", subs); err != nil {
+			subs.close()
 			return err
 		}
 	}
 	if l.cfg.UniswapAdapter != "" {
-		if err := l.subscribeDeFiAdapter(ctx, logsCh, l.cfg.UniswapAdapter, "Uniswap"); err != nil {
+		if err := l.subscribeDeFiAdapter(ctx, logsCh, l.cfg.UniswapAdapter, "Uniswap", subs); err != nil {
+			subs.close()
 			return err
 		}
 	}
@@ -122,7 +208,8 @@ func (l *L2Listener) Start(ctx context.Context) error {
 
 	for name, addr := range rwaContracts {
 		if addr != "" {
-			if err := l.subscribeRWAContract(ctx, logsCh, addr, name); err != nil {
+			if err := l.subscribeRWAContract(ctx, logsCh, addr, name, subs); err != nil {
+				subs.close()
 				return err
 			}
 		}
@@ -138,43 +225,152 @@ func (l *L2Listener) Start(ctx context.Context) error {
 
 	for name, addr := range treasuryContracts {
 		if addr != "" {
-			if err := l.subscribeTreasuryContract(ctx, logsCh, addr, name); err != nil {
+			if err := l.subscribeTreasuryContract(ctx, logsCh, addr, name, subs); err != nil {
+				subs.close()
 				return err
 			}
 		}
 	}
 
-	conf := l.cfg.Confirmations
-	if conf <= 0 {
-		conf = 1 // Default for L2 (faster finality)
+	// Catch up before processing live logs. The head is read after
+	// subscribing so no block falls between the two.
+	head, err := l.client.BlockNumber(ctx)
+	if err != nil {
+		subs.close()
+		return err
 	}
+	if l.head == 0 {
+		// First connection: resume from the stored checkpoints
+		err = l.cursor.CatchUp(ctx, l.client, l.contracts, head, l.onLog)
+	} else {
+		// Reconnection: replay the unconfirmed window before the last seen head;
+		// logs already pending or published are skipped by onLog
+		from := l.finality.replayFrom(l.head)
+		err = l.cursor.BackfillAll(ctx, l.client, l.contracts, from, head, l.onLog)
+	}
+	if err != nil {
+		subs.close()
+		return err
+	}
+	l.backfilledTo = head
+	l.onHead(ctx, head)
 
-	// Main event loop
-	go func() {
-		for {
-			select {
-			case err := <-subHead.Err():
-				log.Printf("❌ [L2] head subscription error: %v", err)
-				return
-			case h := <-headers:
-				if h != nil {
-					l.onHead(h.Number.Uint64(), conf)
-				}
-			case lg := <-logsCh:
-				l.onLog(lg)
-			case <-ctx.Done():
-				log.Println("🛑 [L2] Listener stopped")
-				return
+	l.subs, l.headers, l.logsCh = subs, headers, logsCh
+	l.health.head(head)
+	l.health.set(StateConnected, nil)
+	return nil
+}
+
+// supervise runs the event loop and re-dials whenever a subscription drops
+func (l *L2Listener) supervise(ctx context.Context) {
+	for {
+		err := l.loop(ctx)
+		l.subs.close()
+
+		if ctx.Err() != nil {
+			l.health.set(StateStopped, nil)
+			log.Println("🛑 [L2] Listener stopped")
+			return
+		}
+
+		l.health.set(StateReconnecting, err)
+		if !reconnect(ctx, l.health, l.connect) {
+			l.health.set(StateStopped, nil)
+			log.Println("🛑 [L2] Listener stopped")
+			return
+		}
+	}
+}
+
+// loop processes heads and logs until a subscription fails or ctx is done
+func (l *L2Listener) loop(ctx context.Context) error {
+	for {
+		select {
+		case err := <-l.subs.Err():
+			return err
+		case h := <-l.headers:
+			if h != nil {
+				l.health.head(h.Number.Uint64())
+				l.onReorg(l.blocks.observe(ctx, l.client, h))
+				l.onHead(ctx, h.Number.Uint64())
+			}
+		case lg := <-l.logsCh:
+			if !lg.Removed && lg.BlockNumber <= l.backfilledTo {
+				continue // Already replayed by backfill
 			}
+			l.onLog(lg)
+		case <-ctx.Done():
+			return ctx.Err()
 		}
-	}()
+	}
+}
+
 
+// bindContracts binds every configured contract for event decoding
+func (l *L2Listener) bindContracts() error {
+	var err error
+	if l.cfg.IntegratedVault != "" {
+		if l.integratedVault, err = l2.NewIntegratedVault(common.HexToAddress(l.cfg.IntegratedVault), l.client); err != nil {
+			return err
+		}
+	}
+	if l.cfg.StateAggregator != "" {
+		if l.stateAggregator, err = l2.NewL2StateAggregator(common.HexToAddress(l.cfg.StateAggregator), l.client); err != nil {
+			return err
+		}
+	}
+	if l.cfg.AaveAdapter != "" {
+		if l.aaveAdapter, err = l2.NewAaveV3Adapter(common.HexToAddress(l.cfg.AaveAdapter), l.client); err != nil {
+			return err
+		}
+	}
+	if l.cfg.CompoundAdapter != "" {
+		if l.compoundAdapter, err = l2.NewCompoundV3Adapter(common.HexToAddress(l.cfg.CompoundAdapter), l.client); err != nil {
+			return err
+		}
+	}
+	if l.cfg.UniswapAdapter != "" {
+		if l.uniswapAdapter, err = l2.NewUniswapV3Adapter(common.HexToAddress(l.cfg.UniswapAdapter), l.client); err != nil {
+			return err
+		}
+	}
+	if l.cfg.RWAFactory != "" {
+		if l.rwaFactory, err = l2.NewRWAAssetFactory(common.HexToAddress(l.cfg.RWAFactory), l.client); err != nil {
+			return err
+		}
+	}
+	if l.cfg.RWAMarketplace != "" {
+		if l.rwaMarketplace, err = l2.NewRWAMarketplace(common.HexToAddress(l.cfg.RWAMarketplace), l.client); err != nil {
+			return err
+		}
+	}
+	if l.cfg.RWAYield != "" {
+		if l.rwaYield, err = l2.NewRWAYieldDistributor(common.HexToAddress(l.cfg.RWAYield), l.client); err != nil {
+			return err
+		}
+	}
+	if l.cfg.RWACompliance != "" {
+		if l.rwaCompliance, err = l2.NewRWACompliance(common.HexToAddress(l.cfg.RWACompliance), l.client); err != nil {
+			return err
+		}
+	}
+	if l.cfg.RWAValuation != "" {
+		if l.rwaValuation, err = l2.NewRWAValuation(common.HexToAddress(l.cfg.RWAValuation), l.client); err != nil {
+			return err
+		}
+	}
+	if l.cfg.RWAGovernance != "" {
+		if l.rwaGovernance, err = l2.NewRWAGovernance(common.HexToAddress(l.cfg.RWAGovernance), l.client); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 // subscribeIntegratedVault subscribes to IntegratedVault events
-func (l *L2Listener) subscribeIntegratedVault(ctx context.Context, logsCh chan types.Log) error {
+func (l *L2Listener) subscribeIntegratedVault(ctx context.Context, logsCh chan types.Log, subs *subscriptionSet) error {
 	addr := common.HexToAddress(l.cfg.IntegratedVault)
+	l.contracts = append(l.contracts, addr)
 
 	query := ethereum.FilterQuery{
 		Addresses: []common.Address{addr},
@@ -187,17 +383,15 @@ func (l *L2Listener) subscribeIntegratedVault(ctx context.Context, logsCh chan t
 
 	log.Printf("👂 [L2] Subscribed to IntegratedVault events at %s", addr.Hex())
 
-	go func() {
-		<-sub.Err()
-		log.Println("❌ [L2] IntegratedVault subscription error")
-	}()
+	subs.watch("L2", "IntegratedVault", sub)
 
 	return nil
 }
 
 // subscribeStateAggregator subscribes to StateAggregator events
-func (l *L2Listener) subscribeStateAggregator(ctx context.Context, logsCh chan types.Log) error {
+func (l *L2Listener) subscribeStateAggregator(ctx context.Context, logsCh chan types.Log, subs *subscriptionSet) error {
 	addr := common.HexToAddress(l.cfg.StateAggregator)
+	l.contracts = append(l.contracts, addr)
 
 	query := ethereum.FilterQuery{
 		Addresses: []common.Address{addr},
@@ -210,17 +404,15 @@ func (l *L2Listener) subscribeStateAggregator(ctx context.Context, logsCh chan t
 
 	log.Printf("👂 [L2] Subscribed to StateAggregator events at %s", addr.Hex())
 
-	go func() {
-		<-sub.Err()
-		log.Println("❌ [L2] StateAggregator subscription error")
-	}()
+	subs.watch("L2", "StateAggregator", sub)
 
 	return nil
 }
 
 // subscribeDeFiAdapter subscribes to DeFi adapter events
-func (l *L2Listener) subscribeDeFiAdapter(ctx context.Context, logsCh chan types.Log, address string, name string) error {
+func (l *L2Listener) subscribeDeFiAdapter(ctx context.Context, logsCh chan types.Log, address string, name string, subs *subscriptionSet) error {
 	addr := common.HexToAddress(address)
+	l.contracts = append(l.contracts, addr)
 
 	query := ethereum.FilterQuery{
 		Addresses: []common.Address{addr},
@@ -233,17 +425,15 @@ func (l *L2Listener) subscribeDeFiAdapter(ctx context.Context, logsCh chan types
 
 	log.Printf("👂 [L2] Subscribed to %s adapter events at %s", name, addr.Hex())
 
-	go func() {
-		<-sub.Err()
-		log.Printf("❌ [L2] %s adapter subscription error", name)
-	}()
+	subs.watch("L2", name+" adapter", sub)
 
 	return nil
 }
 
 // subscribeRWAContract subscribes to RWA contract events
-func (l *L2Listener) subscribeRWAContract(ctx context.Context, logsCh chan types.Log, address string, name string) error {
+func (l *L2Listener) subscribeRWAContract(ctx context.Context, logsCh chan types.Log, address string, name string, subs *subscriptionSet) error {
 	addr := common.HexToAddress(address)
+	l.contracts = append(l.contracts, addr)
 
 	query := ethereum.FilterQuery{
 		Addresses: []common.Address{addr},
@@ -256,16 +446,23 @@ func (l *L2Listener) subscribeRWAContract(ctx context.Context, logsCh chan types
 
 	log.Printf("👂 [L2] Subscribed to %s events at %s", name, addr.Hex())
 
-	go func() {
-		<-sub.Err()
-		log.Printf("❌ [L2] %s subscription error", name)
-	}()
+	subs.watch("L2", name, sub)
 
 	return nil
 }
 
 // onLog processes incoming log events
 func (l *L2Listener) onLog(lg types.Log) {
+	if lg.Removed {
+		l.onRemovedLog(lg)
+		return
+	}
+
+	// Skip logs already handled, e.g. replayed by a backfill after reconnecting
+	if l.seen(lg) {
+		return
+	}
+
 	var event models.L2Event
 	var eventType string
 
@@ -316,6 +513,10 @@ func (l *L2Listener) onLog(lg types.Log) {
 	event.EventType = eventType
 	event.TxHash = lg.TxHash.Hex()
 	event.BlockNumber = int64(lg.BlockNumber)
+	event.BlockHash = lg.BlockHash.Hex()
+	event.TxIndex = lg.TxIndex
+	event.LogIndex = lg.Index
+	event.Sequence = models.EventSequence(lg.BlockNumber, lg.Index)
 	event.Confirmed = false
 	event.Timestamp = time.Now().Unix()
 	event.ContractAddress = lg.Address.Hex()
@@ -328,139 +529,223 @@ func (l *L2Listener) onLog(lg types.Log) {
 	log.Printf("🕒 [L2] Pending %s block=%d tx=%s", eventType, lg.BlockNumber, lg.TxHash.Hex())
 }
 
-// parseIntegratedVaultEvent parses IntegratedVault events
-func (l *L2Listener) parseIntegratedVaultEvent(lg types.Log, event *models.L2Event) string {
-	if len(lg.Topics) < 2 {
-		return ""
-	}
+// seen reports whether a log is already pending or was recently published
+func (l *L2Listener) seen(lg types.Log) bool {
+	txHash := lg.TxHash.Hex()
+	blockHash := lg.BlockHash.Hex()
 
-	// Common pattern: user in topics[1], amount in data
-	event.UserAddress = common.BytesToAddress(lg.Topics[1].Bytes()).Hex()
+	l.pendingMu.Lock()
+	defer l.pendingMu.Unlock()
 
-	if len(lg.Data) >= 32 {
-		amount := new(big.Int).SetBytes(lg.Data[:32])
-		event.Amount = amount.String()
+	for _, list := range [][]models.L2Event{l.pending[lg.BlockNumber], l.published[lg.BlockNumber]} {
+		for _, evt := range list {
+			if evt.TxHash == txHash && evt.LogIndex == lg.Index && evt.BlockHash == blockHash {
+				return true
+			}
+		}
 	}
+	return false
+}
 
-	// Determine specific event type based on signature
-	eventSig := lg.Topics[0].Hex()
-	_ = eventSig // Placeholder until real signatures are added
+// onHead releases the events of blocks that became final under the finality policy
+func (l *L2Listener) onHead(ctx context.Context, n uint64) {
+	l.head = n
 
-	// For now, return generic operation type
-	// TODO: Update with actual event signatures after contract deployment
-	return "vault_operation"
-}
+	l.pendingMu.Lock()
+	unretracted := l.unretracted
+	l.unretracted = nil
+	l.pendingMu.Unlock()
+	l.retract(unretracted)
 
-// parseStateAggregatorEvent parses StateAggregator events
-func (l *L2Listener) parseStateAggregatorEvent(lg types.Log, event *models.L2Event) string {
-	event.Amount = "0"
-	event.UserAddress = "0x0000000000000000000000000000000000000000"
+	final, ok := l.finality.height(ctx, l.client, n)
 
-	return "state_aggregated"
-}
+	var confirmed []models.L2Event
+	keep := make(map[uint64][]models.L2Event)
 
-// parseDeFiAdapterEvent parses DeFi adapter events
-func (l *L2Listener) parseDeFiAdapterEvent(lg types.Log, event *models.L2Event, protocol string) string {
-	if len(lg.Topics) < 2 {
-		return ""
+	l.pendingMu.Lock()
+	for bn, list := range l.pending {
+		if ok && bn <= final {
+			for _, evt := range list {
+				if !l.blocks.canonical(bn, evt.BlockHash) {
+					log.Printf("🔀 [L2] Dropping %s tx=%s from reorged block %d", evt.EventType, evt.TxHash, bn)
+					continue
+				}
+				confirmed = append(confirmed, evt)
+			}
+		} else {
+			keep[bn] = list
+		}
 	}
+	l.pending = keep
+	l.pendingMu.Unlock()
 
-	event.UserAddress = common.BytesToAddress(lg.Topics[1].Bytes()).Hex()
+	if !ok {
+		return
+	}
 
-	if len(lg.Data) >= 32 {
-		amount := new(big.Int).SetBytes(lg.Data[:32])
-		event.Amount = amount.String()
+	// Publish confirmed events to Kafka
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// Events that could not be written go back to pending and are retried on
+	// the next head; the checkpoint stops below the lowest of their blocks
+	commit, hold := final, false
+	for _, evt := range confirmed {
+		retry := evt
+		evt.Confirmed = true
+		evt.Finality = l.finality.level()
+		if err := l.publish(ctx, evt); err != nil {
+			log.Printf("❌ [L2] Kafka write error: %v", err)
+			bn := uint64(evt.BlockNumber)
+			l.pendingMu.Lock()
+			l.pending[bn] = append(l.pending[bn], retry)
+			l.pendingMu.Unlock()
+			if bn == 0 {
+				hold = true
+			} else if bn-1 < commit {
+				commit = bn - 1
+			}
+			continue
+		}
+		log.Printf("✅ [L2] Confirmed (%s) %s tx=%s block=%d user=%s", evt.Finality, evt.EventType, evt.TxHash, evt.BlockNumber, evt.UserAddress)
+
+		// Remember released events so a deeper reorg can still retract them
+		l.pendingMu.Lock()
+		bn := uint64(evt.BlockNumber)
+		l.published[bn] = append(l.published[bn], evt)
+		l.pendingMu.Unlock()
 	}
 
-	if event.Metadata == nil {
-		event.Metadata = make(map[string]interface{})
+	// Forget released events older than the reorg window
+	l.pendingMu.Lock()
+	for bn := range l.published {
+		if n > reorgWindow && bn < n-reorgWindow {
+			delete(l.published, bn)
+		}
 	}
-	event.Metadata["protocol"] = protocol
+	l.pendingMu.Unlock()
 
-	return "defi_" + protocol + "_operation"
+	// Only advance the checkpoint to the highest block whose events were all written (to the outbox or Kafka)
+	if !hold {
+		l.cursor.Commit(ctx, l.contracts, commit)
+	}
 }
 
-// parseRWAEvent parses RWA contract events
-func (l *L2Listener) parseRWAEvent(lg types.Log, event *models.L2Event, contractType string) string {
-	if len(lg.Topics) < 2 {
-		// Some RWA events might not have indexed user
-		event.UserAddress = "0x0000000000000000000000000000000000000000"
-	} else {
-		event.UserAddress = common.BytesToAddress(lg.Topics[1].Bytes()).Hex()
+// publish writes a single event to Kafka
+func (l *L2Listener) publish(ctx context.Context, evt models.L2Event) error {
+	msg, err := events.Message(l.cfg.Codec, events.NewL2Envelope(l.cfg.ChainID, &evt), kafka.UserKey(evt.UserAddress),
+		k.Header{Key: "event_type", Value: []byte(evt.EventType)},
+		k.Header{Key: "contract", Value: []byte(evt.ContractAddress)},
+		k.Header{Key: "sequence", Value: []byte(strconv.FormatUint(evt.Sequence, 10))},
+	)
+	if err != nil {
+		return err
 	}
+	return l.writer.WriteMessages(ctx, msg)
+}
 
-	// RWA events often have asset IDs or proposal IDs
-	if len(lg.Data) >= 32 {
-		amount := new(big.Int).SetBytes(lg.Data[:32])
-		event.Amount = amount.String()
-	} else {
-		event.Amount = "0"
+// onReorg drops pending events and retracts released events from blocks that
+// are no longer canonical
+func (l *L2Listener) onReorg(blocks []uint64) {
+	if len(blocks) == 0 {
+		return
 	}
 
-	if event.Metadata == nil {
-		event.Metadata = make(map[string]interface{})
-	}
-	event.Metadata["rwa_contract"] = contractType
+	var retract []models.L2Event
 
-	// Extract asset ID if present in topics
-	if len(lg.Topics) >= 3 {
-		assetID := new(big.Int).SetBytes(lg.Topics[2].Bytes())
-		event.Metadata["asset_id"] = assetID.String()
+	l.pendingMu.Lock()
+	for _, bn := range blocks {
+		var keep []models.L2Event
+		for _, evt := range l.pending[bn] {
+			if l.blocks.canonical(bn, evt.BlockHash) {
+				keep = append(keep, evt)
+			} else {
+				log.Printf("🔀 [L2] Dropping pending %s tx=%s from reorged block %d", evt.EventType, evt.TxHash, bn)
+			}
+		}
+		l.pending[bn] = keep
+
+		var kept []models.L2Event
+		for _, evt := range l.published[bn] {
+			if l.blocks.canonical(bn, evt.BlockHash) {
+				kept = append(kept, evt)
+			} else {
+				retract = append(retract, evt)
+			}
+		}
+		l.published[bn] = kept
 	}
+	l.pendingMu.Unlock()
 
-	return "rwa_" + contractType
+	l.retract(retract)
 }
 
-// onHead confirms events after N blocks
-func (l *L2Listener) onHead(n uint64, conf int) {
-	l.head = n
+// onRemovedLog handles a log the node flagged as removed by a reorg
+func (l *L2Listener) onRemovedLog(lg types.Log) {
+	txHash := lg.TxHash.Hex()
+	blockHash := lg.BlockHash.Hex()
+	matches := func(evt models.L2Event) bool {
+		return evt.TxHash == txHash && evt.LogIndex == lg.Index && evt.BlockHash == blockHash
+	}
 
-	var confirmed []models.L2Event
-	keep := make(map[uint64][]models.L2Event)
+	var retract []models.L2Event
 
 	l.pendingMu.Lock()
-	for bn, list := range l.pending {
-		if n >= uint64(conf) && bn <= n-uint64(conf) {
-			confirmed = append(confirmed, list...)
+	var keep []models.L2Event
+	for _, evt := range l.pending[lg.BlockNumber] {
+		if matches(evt) {
+			log.Printf("🔀 [L2] Dropping removed %s tx=%s block=%d", evt.EventType, txHash, lg.BlockNumber)
 		} else {
-			keep[bn] = list
+			keep = append(keep, evt)
 		}
 	}
-	l.pending = keep
+	l.pending[lg.BlockNumber] = keep
+
+	var kept []models.L2Event
+	for _, evt := range l.published[lg.BlockNumber] {
+		if matches(evt) {
+			retract = append(retract, evt)
+		} else {
+			kept = append(kept, evt)
+		}
+	}
+	l.published[lg.BlockNumber] = kept
 	l.pendingMu.Unlock()
 
-	if len(confirmed) == 0 {
+	l.retract(retract)
+}
+
+// retract publishes reorg_retraction messages for events already sent to
+// Kafka. Retractions that cannot be written are kept and retried on the next head.
+func (l *L2Listener) retract(events []models.L2Event) {
+	if len(events) == 0 {
 		return
 	}
 
-	// Publish confirmed events to Kafka
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	for _, evt := range confirmed {
-		evt.Confirmed = true
-		data, _ := json.Marshal(evt)
-
-		msg := k.Message{
-			Key:   []byte(evt.UserAddress),
-			Value: data,
-			Headers: []k.Header{
-				{Key: "event_type", Value: []byte(evt.EventType)},
-				{Key: "contract", Value: []byte(evt.ContractAddress)},
-			},
-		}
-
-		if err := l.writer.WriteMessages(ctx, msg); err != nil {
-			log.Printf("❌ [L2] Kafka write error: %v", err)
+	for _, evt := range events {
+		retry := evt
+		evt.RetractedEventType = evt.EventType
+		evt.EventType = EventTypeReorgRetraction
+		evt.Timestamp = time.Now().Unix()
+
+		if err := l.publish(ctx, evt); err != nil {
+			log.Printf("❌ [L2] Kafka write error for retraction tx=%s: %v", evt.TxHash, err)
+			l.pendingMu.Lock()
+			l.unretracted = append(l.unretracted, retry)
+			l.pendingMu.Unlock()
 		} else {
-			log.Printf("✅ [L2] Confirmed %s tx=%s block=%d user=%s", evt.EventType, evt.TxHash, evt.BlockNumber, evt.UserAddress)
+			log.Printf("↩️  [L2] Retracted %s tx=%s block=%d user=%s", evt.RetractedEventType, evt.TxHash, evt.BlockNumber, evt.UserAddress)
 		}
 	}
 }
 
 // subscribeTreasuryContract subscribes to Treasury contract events
-func (l *L2Listener) subscribeTreasuryContract(ctx context.Context, logsCh chan types.Log, address string, name string) error {
+func (l *L2Listener) subscribeTreasuryContract(ctx context.Context, logsCh chan types.Log, address string, name string, subs *subscriptionSet) error {
 	addr := common.HexToAddress(address)
+	l.contracts = append(l.contracts, addr)
 
 	query := ethereum.FilterQuery{
 		Addresses: []common.Address{addr},
@@ -473,118 +758,16 @@ func (l *L2Listener) subscribeTreasuryContract(ctx context.Context, logsCh chan
 
 	log.Printf("👂 [L2] Subscribed to %s events at %s", name, addr.Hex())
 
-	go func() {
-		<-sub.Err()
-		log.Printf("❌ [L2] %s subscription error", name)
-	}()
+	subs.watch("L2", name, sub)
 
 	return nil
 }
 
-// parseTreasuryEvent parses Treasury contract events
-func (l *L2Listener) parseTreasuryEvent(lg types.Log, event *models.L2Event, contractType string) string {
-	event.ContractAddress = lg.Address.Hex()
-	event.TxHash = lg.TxHash.Hex()
-	event.BlockNumber = lg.BlockNumber
-
-	// Extract basic metadata
-	metadata := map[string]interface{}{
-		"contract_type": contractType,
-		"log_index":     lg.Index,
-	}
-
-	// Parse based on contract type and event signature
-	switch contractType {
-	case "factory":
-		// TreasuryTokenCreated event
-		if len(lg.Topics) > 0 {
-			eventSig := lg.Topics[0].Hex()
-			switch eventSig {
-			case "0x...": // TreasuryTokenCreated signature (to be filled with actual signature)
-				// Parse asset creation event
-				if len(lg.Topics) >= 3 {
-					metadata["asset_id"] = new(big.Int).SetBytes(lg.Topics[1][:]).String()
-					metadata["token_address"] = common.BytesToAddress(lg.Topics[2][:]).Hex()
-				}
-				event.Metadata = metadata
-				return "treasury_token_created"
-			}
-		}
-
-	case "marketplace":
-		// OrderCreated, OrderMatched, OrderCancelled events
-		if len(lg.Topics) > 0 {
-			eventSig := lg.Topics[0].Hex()
-			switch eventSig {
-			case "0x...": // OrderCreated signature
-				if len(lg.Topics) >= 3 {
-					metadata["order_id"] = new(big.Int).SetBytes(lg.Topics[1][:]).String()
-					metadata["user_address"] = common.BytesToAddress(lg.Topics[2][:]).Hex()
-					event.UserAddress = metadata["user_address"].(string)
-				}
-				event.Metadata = metadata
-				return "treasury_order_created"
-			case "0x...": // OrderMatched signature
-				if len(lg.Topics) >= 2 {
-					metadata["order_id"] = new(big.Int).SetBytes(lg.Topics[1][:]).String()
-				}
-				event.Metadata = metadata
-				return "treasury_order_matched"
-			case "0x...": // OrderCancelled signature
-				if len(lg.Topics) >= 2 {
-					metadata["order_id"] = new(big.Int).SetBytes(lg.Topics[1][:]).String()
-				}
-				event.Metadata = metadata
-				return "treasury_order_cancelled"
-			}
-		}
-
-	case "yield_distributor":
-		// YieldDeposited, YieldClaimed events
-		if len(lg.Topics) > 0 {
-			eventSig := lg.Topics[0].Hex()
-			switch eventSig {
-			case "0x...": // YieldDeposited signature
-				if len(lg.Topics) >= 2 {
-					metadata["asset_id"] = new(big.Int).SetBytes(lg.Topics[1][:]).String()
-				}
-				event.Metadata = metadata
-				return "treasury_yield_deposited"
-			case "0x...": // YieldClaimed signature
-				if len(lg.Topics) >= 3 {
-					metadata["user_address"] = common.BytesToAddress(lg.Topics[1][:]).Hex()
-					metadata["asset_id"] = new(big.Int).SetBytes(lg.Topics[2][:]).String()
-					event.UserAddress = metadata["user_address"].(string)
-				}
-				event.Metadata = metadata
-				return "treasury_yield_claimed"
-			}
-		}
-
-	case "price_oracle":
-		// PriceUpdated event
-		if len(lg.Topics) > 0 {
-			eventSig := lg.Topics[0].Hex()
-			switch eventSig {
-			case "0x...": // PriceUpdated signature
-				if len(lg.Topics) >= 2 {
-					metadata["asset_id"] = new(big.Int).SetBytes(lg.Topics[1][:]).String()
-				}
-				event.Metadata = metadata
-				return "treasury_price_updated"
-			}
-		}
-	}
-
-	// Default fallback
-	event.Metadata = metadata
-	return "treasury_unknown_event"
-}
-
 // Close closes the listener
 func (l *L2Listener) Close() error {
 	if l.client != nil {
 		l.client.Close()
 	}
+	l.finality.close()
 	return nil
 }
@@ -0,0 +1,126 @@
+package listener
+
+import (
+	"context"
+	"log"
+	"time"
+
+	k "github.com/segmentio/kafka-go"
+
+	"loyalty-points-system/internal/db"
+	"loyalty-points-system/internal/metrics"
+)
+
+const (
+	// outboxPollInterval is how often the relay looks for undelivered messages
+	outboxPollInterval = 500 * time.Millisecond
+	// outboxBatch is the maximum number of messages sent per Kafka write
+	outboxBatch = 100
+	// outboxRetention is how long delivered messages are kept before purging
+	outboxRetention = 7 * 24 * time.Hour
+)
+
+// OutboxStore is the durable queue drained by the relay
+type OutboxStore interface {
+	PendingOutbox(ctx context.Context, topic string, limit int) ([]db.OutboxMessage, error)
+	MarkOutboxDelivered(ctx context.Context, ids []int64) error
+	MarkOutboxFailed(ctx context.Context, ids []int64, cause error) error
+	PurgeOutbox(ctx context.Context, before time.Time) (int64, error)
+}
+
+// OutboxRelay publishes outbox messages to Kafka in order, per topic, and
+// marks them delivered only once the broker has acknowledged the write.
+// Delivery is at-least-once: a crash between the ack and the update resends.
+type OutboxRelay struct {
+	store    OutboxStore
+	writers  map[string]Writer // keyed by topic
+	failures map[string]int
+	retryAt  map[string]time.Time
+}
+
+// NewOutboxRelay creates a relay delivering each topic through its writer
+func NewOutboxRelay(store OutboxStore, writers map[string]Writer) *OutboxRelay {
+	return &OutboxRelay{
+		store:    store,
+		writers:  writers,
+		failures: make(map[string]int),
+		retryAt:  make(map[string]time.Time),
+	}
+}
+
+// Run drains the outbox until ctx is done
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+	purge := time.NewTicker(time.Hour)
+	defer purge.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for topic, w := range r.writers {
+				r.drain(ctx, topic, w)
+			}
+		case <-purge.C:
+			if n, err := r.store.PurgeOutbox(ctx, time.Now().Add(-outboxRetention)); err != nil {
+				log.Printf("⚠️  [Outbox] Purge failed: %v", err)
+			} else if n > 0 {
+				log.Printf("🧹 [Outbox] Purged %d delivered messages", n)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// drain delivers the pending messages of one topic until it is empty or a write fails
+func (r *OutboxRelay) drain(ctx context.Context, topic string, w Writer) {
+	for {
+		if time.Now().Before(r.retryAt[topic]) {
+			return // Backing off after a failed write
+		}
+
+		pending, err := r.store.PendingOutbox(ctx, topic, outboxBatch)
+		if err != nil {
+			log.Printf("⚠️  [Outbox] Failed to load pending %s messages: %v", topic, err)
+			return
+		}
+		if len(pending) == 0 {
+			return
+		}
+
+		ids := make([]int64, len(pending))
+		msgs := make([]k.Message, len(pending))
+		for i, m := range pending {
+			ids[i] = m.ID
+			msgs[i] = k.Message{Key: m.Key, Value: m.Value, Headers: m.Headers}
+		}
+
+		wctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		err = w.WriteMessages(wctx, msgs...)
+		cancel()
+		if err != nil {
+			delay := reconnectDelay(r.failures[topic])
+			r.failures[topic]++
+			r.retryAt[topic] = time.Now().Add(delay)
+			metrics.OutboxPublishErrors.WithLabelValues(topic).Inc()
+			log.Printf("❌ [Outbox] Kafka write to %s failed, retrying %d messages in %s: %v", topic, len(msgs), delay, err)
+
+			if err := r.store.MarkOutboxFailed(ctx, ids, err); err != nil {
+				log.Printf("⚠️  [Outbox] Failed to record attempt: %v", err)
+			}
+			return
+		}
+		r.failures[topic] = 0
+
+		if err := r.store.MarkOutboxDelivered(ctx, ids); err != nil {
+			log.Printf("⚠️  [Outbox] Failed to mark %d %s messages delivered: %v", len(ids), topic, err)
+			return
+		}
+		metrics.OutboxPublished.WithLabelValues(topic).Add(float64(len(ids)))
+
+		if len(pending) < outboxBatch {
+			return
+		}
+	}
+}
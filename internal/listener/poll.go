@@ -0,0 +1,169 @@
+package listener
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// DefaultPollInterval is how often eth_blockNumber is polled in poll mode
+const DefaultPollInterval = 4 * time.Second
+
+// chainClient is the chain access used by the listeners: an *ethclient.Client
+// over websocket, or a pollingClient over plain HTTP
+type chainClient interface {
+	bind.ContractBackend
+	BlockNumber(ctx context.Context) (uint64, error)
+	HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error)
+	SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error)
+	Close()
+}
+
+// dialChain connects to wssURL, falling back to polling rpcURL when the
+// websocket URL is empty or cannot be dialed
+func dialChain(ctx context.Context, layer, wssURL, rpcURL string, interval time.Duration) (chainClient, error) {
+	if wssURL != "" {
+		client, err := ethclient.DialContext(ctx, wssURL)
+		if err == nil {
+			log.Printf("🔌 [%s] Connected to %s", layer, wssURL)
+			return client, nil
+		}
+		if rpcURL == "" {
+			return nil, err
+		}
+		log.Printf("⚠️  [%s] Websocket dial failed, falling back to polling: %v", layer, err)
+	}
+	if rpcURL == "" {
+		return nil, errors.New("neither WSS nor RPC URL configured")
+	}
+
+	client, err := ethclient.DialContext(ctx, rpcURL)
+	if err != nil {
+		return nil, err
+	}
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	log.Printf("🔌 [%s] Polling %s every %s", layer, rpcURL, interval)
+	return &pollingClient{Client: client, interval: interval}, nil
+}
+
+// pollingClient emulates head and log subscriptions with eth_blockNumber and
+// eth_getLogs, so HTTP-only endpoints drive the same onLog/onHead pipeline.
+// Removed logs are never delivered; reorgs are caught by the block tracker.
+type pollingClient struct {
+	*ethclient.Client
+	interval time.Duration
+}
+
+// SubscribeNewHead delivers the latest header whenever the head advances
+func (c *pollingClient) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	last, err := c.BlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.poll(func(ctx context.Context) error {
+		head, err := c.BlockNumber(ctx)
+		if err != nil || head <= last {
+			return err
+		}
+		h, err := c.HeaderByNumber(ctx, new(big.Int).SetUint64(head))
+		if err != nil {
+			return err
+		}
+		last = head
+
+		select {
+		case ch <- h:
+		case <-ctx.Done():
+		}
+		return nil
+	}), nil
+}
+
+// SubscribeFilterLogs delivers the logs matching q of every block mined after the call
+func (c *pollingClient) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	last, err := c.BlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.poll(func(ctx context.Context) error {
+		head, err := c.BlockNumber(ctx)
+		if err != nil || head <= last {
+			return err
+		}
+
+		query := q
+		query.FromBlock = new(big.Int).SetUint64(last + 1)
+		query.ToBlock = new(big.Int).SetUint64(head)
+		logs, err := c.FilterLogs(ctx, query)
+		if err != nil {
+			return err
+		}
+		last = head
+
+		for _, lg := range logs {
+			select {
+			case ch <- lg:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		return nil
+	}), nil
+}
+
+// poll runs tick every interval until it fails or the subscription is cancelled
+func (c *pollingClient) poll(tick func(context.Context) error) *pollSubscription {
+	ctx, cancel := context.WithCancel(context.Background())
+	sub := &pollSubscription{cancel: cancel, err: make(chan error, 1)}
+
+	go func() {
+		defer close(sub.err)
+
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := tick(ctx); err != nil {
+					if ctx.Err() == nil {
+						sub.err <- err
+					}
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return sub
+}
+
+// pollSubscription is the ethereum.Subscription of a polling loop
+type pollSubscription struct {
+	once   sync.Once
+	cancel context.CancelFunc
+	err    chan error
+}
+
+func (s *pollSubscription) Err() <-chan error {
+	return s.err
+}
+
+func (s *pollSubscription) Unsubscribe() {
+	s.once.Do(s.cancel)
+}
@@ -0,0 +1,86 @@
+package listener
+
+import (
+	"context"
+	"log"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// EventTypeReorgRetraction is published for events whose block was reorged out after release
+const EventTypeReorgRetraction = "reorg_retraction"
+
+// reorgWindow is how many blocks of hashes and published events are kept for reorg handling
+const reorgWindow = 128
+
+// HeaderReader is the subset of ethclient.Client used to walk back a reorged chain
+type HeaderReader interface {
+	HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error)
+}
+
+// blockTracker records the canonical block hash of recent heights and detects
+// when a new head does not extend the chain seen so far.
+type blockTracker struct {
+	layer  string
+	hashes map[uint64]common.Hash
+}
+
+func newBlockTracker(layer string) *blockTracker {
+	return &blockTracker{
+		layer:  layer,
+		hashes: make(map[uint64]common.Hash),
+	}
+}
+
+// observe records a new head and returns the heights whose previously seen
+// hash is no longer canonical. The new canonical hashes are walked back via
+// the parent chain until it rejoins the recorded one.
+func (t *blockTracker) observe(ctx context.Context, client HeaderReader, h *types.Header) []uint64 {
+	n := h.Number.Uint64()
+	var reorged []uint64
+
+	if old, ok := t.hashes[n]; ok && old != h.Hash() {
+		reorged = append(reorged, n)
+	}
+	t.hashes[n] = h.Hash()
+
+	parent := h.ParentHash
+	for bn := n - 1; bn > 0 && n-bn <= reorgWindow; bn-- {
+		old, ok := t.hashes[bn]
+		if !ok || old == parent {
+			break
+		}
+		reorged = append(reorged, bn)
+		t.hashes[bn] = parent
+
+		hdr, err := client.HeaderByHash(ctx, parent)
+		if err != nil {
+			log.Printf("⚠️  [%s] Failed to walk back reorg at block %d: %v", t.layer, bn, err)
+			break
+		}
+		parent = hdr.ParentHash
+	}
+
+	// Forget heights that can no longer be reorged within the window
+	for bn := range t.hashes {
+		if n > reorgWindow && bn < n-reorgWindow {
+			delete(t.hashes, bn)
+		}
+	}
+
+	if len(reorged) > 0 {
+		log.Printf("🔀 [%s] Reorg detected at head %d: %d block(s) replaced", t.layer, n, len(reorged))
+	}
+	return reorged
+}
+
+// canonical reports whether blockHash is the recorded canonical hash at height bn.
+// Heights that were never observed are assumed canonical.
+func (t *blockTracker) canonical(bn uint64, blockHash string) bool {
+	h, ok := t.hashes[bn]
+	if !ok || blockHash == "" {
+		return true
+	}
+	return h.Hex() == blockHash
+}
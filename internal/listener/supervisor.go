@@ -0,0 +1,187 @@
+package listener
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+
+	"loyalty-points-system/internal/metrics"
+)
+
+// State is the connection state of a listener
+type State string
+
+const (
+	StateConnecting   State = "connecting"
+	StateConnected    State = "connected"
+	StateLagging      State = "lagging"
+	StateReconnecting State = "reconnecting"
+	StateStopped      State = "stopped"
+)
+
+var allStates = []State{StateConnecting, StateConnected, StateLagging, StateReconnecting, StateStopped}
+
+const (
+	// reconnectBaseDelay and reconnectMaxDelay bound the exponential backoff between re-dials
+	reconnectBaseDelay = time.Second
+	reconnectMaxDelay  = time.Minute
+)
+
+// Status is a point-in-time view of a listener, served on /status
+type Status struct {
+	Name       string    `json:"name"`
+	State      State     `json:"state"`
+	HeadBlock  uint64    `json:"head_block"`
+	LastHeadAt time.Time `json:"last_head_at,omitempty"`
+	Reconnects int       `json:"reconnects"`
+	LastError  string    `json:"last_error,omitempty"`
+}
+
+// health tracks the state of one listener connection and mirrors it to Prometheus
+type health struct {
+	mu       sync.Mutex
+	status   Status
+	lagAfter time.Duration
+}
+
+// newHealth creates a tracker; a connected listener without a new head for lagAfter is reported as lagging
+func newHealth(name string, lagAfter time.Duration) *health {
+	h := &health{
+		status:   Status{Name: name},
+		lagAfter: lagAfter,
+	}
+	h.set(StateConnecting, nil)
+	return h
+}
+
+// set moves the listener to a new state, recording err if any
+func (h *health) set(state State, err error) {
+	h.mu.Lock()
+	h.status.State = state
+	if err != nil {
+		h.status.LastError = err.Error()
+	}
+	if state == StateReconnecting {
+		h.status.Reconnects++
+		metrics.ListenerReconnects.WithLabelValues(h.status.Name).Inc()
+	}
+	h.mu.Unlock()
+
+	h.export(state)
+}
+
+// head records a new block header
+func (h *health) head(n uint64) {
+	h.mu.Lock()
+	h.status.HeadBlock = n
+	h.status.LastHeadAt = time.Now()
+	h.mu.Unlock()
+
+	metrics.ListenerHeadBlock.WithLabelValues(h.status.Name).Set(float64(n))
+}
+
+// snapshot returns the current status, deriving "lagging" from the last head time
+func (h *health) snapshot() Status {
+	h.mu.Lock()
+	st := h.status
+	h.mu.Unlock()
+
+	if st.State == StateConnected && !st.LastHeadAt.IsZero() && time.Since(st.LastHeadAt) > h.lagAfter {
+		st.State = StateLagging
+	}
+	h.export(st.State)
+	return st
+}
+
+// export sets the state gauge so exactly one state reads 1
+func (h *health) export(state State) {
+	for _, s := range allStates {
+		v := 0.0
+		if s == state {
+			v = 1
+		}
+		metrics.ListenerState.WithLabelValues(h.status.Name, string(s)).Set(v)
+	}
+}
+
+// listenerName returns the configured name, or def when none is set
+func listenerName(name, def string) string {
+	if name == "" {
+		return def
+	}
+	return name
+}
+
+// reconnectDelay returns the backoff before the given (0-based) reconnection attempt
+func reconnectDelay(attempt int) time.Duration {
+	d := reconnectBaseDelay
+	for i := 0; i < attempt && d < reconnectMaxDelay; i++ {
+		d *= 2
+	}
+	if d > reconnectMaxDelay {
+		d = reconnectMaxDelay
+	}
+	return d
+}
+
+// reconnect retries connect with exponential backoff until it succeeds or ctx is done
+func reconnect(ctx context.Context, h *health, connect func(context.Context) error) bool {
+	for attempt := 0; ; attempt++ {
+		delay := reconnectDelay(attempt)
+		log.Printf("🔁 [%s] Reconnecting in %s (attempt %d)", h.status.Name, delay, attempt+1)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return false
+		}
+
+		if err := connect(ctx); err != nil {
+			log.Printf("❌ [%s] Reconnect failed: %v", h.status.Name, err)
+			h.set(StateReconnecting, err)
+			continue
+		}
+		return true
+	}
+}
+
+// subscriptionSet forwards the first error of any subscription in a session
+type subscriptionSet struct {
+	subs []ethereum.Subscription
+	errc chan error
+}
+
+func newSubscriptionSet() *subscriptionSet {
+	return &subscriptionSet{errc: make(chan error, 1)}
+}
+
+// watch adds a subscription whose failure ends the session
+func (s *subscriptionSet) watch(layer, name string, sub ethereum.Subscription) {
+	s.subs = append(s.subs, sub)
+	go func() {
+		err, ok := <-sub.Err()
+		if !ok {
+			return // Unsubscribed
+		}
+		log.Printf("❌ [%s] %s subscription error: %v", layer, name, err)
+		select {
+		case s.errc <- err:
+		default:
+		}
+	}()
+}
+
+// Err fires when any watched subscription fails
+func (s *subscriptionSet) Err() <-chan error {
+	return s.errc
+}
+
+// close unsubscribes everything in the set
+func (s *subscriptionSet) close() {
+	for _, sub := range s.subs {
+		sub.Unsubscribe()
+	}
+}
@@ -41,6 +41,55 @@ var (
 		[]string{"topic"},
 	)
 
+	KafkaDeadLetters = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "loyalty_kafka_dead_letters_total",
+			Help: "Total number of messages routed to a dead-letter topic",
+		},
+		[]string{"topic"},
+	)
+
+	// Listener metrics
+	ListenerState = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "loyalty_listener_state",
+			Help: "Current listener connection state (1 for the active state)",
+		},
+		[]string{"listener", "state"},
+	)
+
+	ListenerReconnects = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "loyalty_listener_reconnects_total",
+			Help: "Total number of listener reconnection attempts",
+		},
+		[]string{"listener"},
+	)
+
+	ListenerHeadBlock = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "loyalty_listener_head_block",
+			Help: "Latest block header seen by the listener",
+		},
+		[]string{"listener"},
+	)
+
+	OutboxPublished = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "loyalty_outbox_published_total",
+			Help: "Total number of outbox messages delivered to Kafka",
+		},
+		[]string{"topic"},
+	)
+
+	OutboxPublishErrors = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "loyalty_outbox_publish_errors_total",
+			Help: "Total number of failed outbox relay attempts",
+		},
+		[]string{"topic"},
+	)
+
 	// Bridge metrics
 	BridgeMessagesTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -50,12 +99,14 @@ var (
 		[]string{"direction", "status"},
 	)
 
-	BridgeConfirmationTime = promauto.NewHistogram(
+	// Deposits confirm in minutes, withdrawals wait out the ~7 day challenge period
+	BridgeConfirmationTime = promauto.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "loyalty_bridge_confirmation_seconds",
 			Help:    "Bridge message confirmation time in seconds",
-			Buckets: []float64{60, 300, 600, 900, 1800, 3600},
+			Buckets: []float64{60, 300, 600, 900, 1800, 3600, 6 * 3600, 86400, 3 * 86400, 6 * 86400, 7 * 86400, 8 * 86400, 14 * 86400},
 		},
+		[]string{"direction"},
 	)
 
 	BridgePendingMessages = promauto.NewGauge(
@@ -73,6 +124,44 @@ var (
 		[]string{"direction"},
 	)
 
+	BridgeRedeemsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "loyalty_bridge_redeems_total",
+			Help: "Total number of retryable ticket redeems submitted by the operator",
+		},
+		[]string{"result"},
+	)
+
+	BridgeRedeemSpendWei = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "loyalty_bridge_redeem_spend_wei",
+			Help: "Operator gas spent or reserved on redeems in the last hour, in wei",
+		},
+	)
+
+	BridgeWithdrawalsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "loyalty_bridge_withdrawals_total",
+			Help: "Total number of L2→L1 withdrawal finalizations by result",
+		},
+		[]string{"result"},
+	)
+
+	BridgePolicyRejections = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "loyalty_bridge_policy_rejections_total",
+			Help: "Total number of bridge requests refused by the bridge policy",
+		},
+		[]string{"reason"},
+	)
+
+	BridgeCircuitOpen = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "loyalty_bridge_circuit_open",
+			Help: "1 while the bridge circuit breaker refuses requests",
+		},
+	)
+
 	// API metrics
 	APIRequestsTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
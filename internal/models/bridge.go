@@ -0,0 +1,112 @@
+package models
+
+// Bridge message directions
+const (
+	BridgeL1ToL2 = "L1_TO_L2"
+	BridgeL2ToL1 = "L2_TO_L1"
+)
+
+// Bridge message statuses
+const (
+	BridgeStatusInitiated = "initiated" // one leg seen on the origin chain
+	BridgeStatusPending   = "pending"   // in flight; the monitor resolves it on-chain
+	BridgeStatusConfirmed = "confirmed" // delivered on the destination chain
+	BridgeStatusFailed    = "failed"    // timed out, expired or reverted
+)
+
+// bridgeStatuses lists every status in lifecycle order
+var bridgeStatuses = []string{BridgeStatusInitiated, BridgeStatusPending, BridgeStatusFailed, BridgeStatusConfirmed}
+
+// bridgeTransitions is the status state machine shared by the listener, the
+// consumer and the bridge monitor. Confirmed is terminal. A failed message is
+// confirmed when the destination leg shows up late, and goes back to pending
+// on a manual retry.
+var bridgeTransitions = map[string][]string{
+	BridgeStatusInitiated: {BridgeStatusPending, BridgeStatusConfirmed, BridgeStatusFailed},
+	BridgeStatusPending:   {BridgeStatusConfirmed, BridgeStatusFailed},
+	BridgeStatusFailed:    {BridgeStatusPending, BridgeStatusConfirmed},
+	BridgeStatusConfirmed: {},
+}
+
+// CanTransitionBridgeStatus reports whether a message in status from may move
+// to status to. Staying in the same status is always allowed.
+func CanTransitionBridgeStatus(from, to string) bool {
+	if from == to {
+		return true
+	}
+	for _, s := range bridgeTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// NextBridgeStatus returns to when the transition is allowed and from otherwise
+func NextBridgeStatus(from, to string) string {
+	if CanTransitionBridgeStatus(from, to) {
+		return to
+	}
+	return from
+}
+
+// BridgeStatusesInto returns the statuses that may move to status to, for
+// guarding SQL updates with status = ANY(...)
+func BridgeStatusesInto(to string) []string {
+	var from []string
+	for _, s := range bridgeStatuses {
+		if CanTransitionBridgeStatus(s, to) {
+			from = append(from, s)
+		}
+	}
+	return from
+}
+
+// MergeBridgeLeg folds a leg observed by the bridge listener into the stored
+// message with the same hash (nil when there is none yet). A leg carries the
+// transaction of the chain it was seen on. The first leg decides the
+// direction: seen on L1 it is a deposit, seen on L2 a withdrawal. A leg on the
+// other chain than the one the message started from means it arrived.
+func MergeBridgeLeg(current, leg *BridgeEvent) *BridgeEvent {
+	if current == nil {
+		merged := *leg
+		if merged.Direction == "" {
+			merged.Direction = BridgeL1ToL2
+			if leg.L1TxHash == "" {
+				merged.Direction = BridgeL2ToL1
+			}
+		}
+		merged.Status = BridgeStatusInitiated
+		return &merged
+	}
+
+	merged := *current
+	if merged.UserAddress == "" {
+		merged.UserAddress = leg.UserAddress
+	}
+	if merged.Amount == "" {
+		merged.Amount = leg.Amount
+	}
+
+	arrived := false
+	if leg.L1TxHash != "" {
+		if merged.L1TxHash == "" {
+			merged.L1TxHash, merged.L1BlockNumber = leg.L1TxHash, leg.L1BlockNumber
+		}
+		arrived = merged.Direction == BridgeL2ToL1
+	}
+	if leg.L2TxHash != "" {
+		if merged.L2TxHash == "" {
+			merged.L2TxHash, merged.L2BlockNumber = leg.L2TxHash, leg.L2BlockNumber
+		}
+		arrived = merged.Direction == BridgeL1ToL2
+	}
+
+	if arrived {
+		merged.Status = NextBridgeStatus(merged.Status, BridgeStatusConfirmed)
+		if merged.Status == BridgeStatusConfirmed && merged.ConfirmedAt == 0 {
+			merged.ConfirmedAt = leg.InitiatedAt
+		}
+	}
+	return &merged
+}
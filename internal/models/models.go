@@ -1,5 +1,20 @@
 package models
 
+// EventSequence orders chain events by block, transaction and log position.
+// Log indexes are block-wide and already follow transaction order, so the
+// sequence packs the block number above a 24-bit log index.
+func EventSequence(block uint64, logIndex uint) uint64 {
+	return block<<24 | uint64(logIndex)&0xFFFFFF
+}
+
+// Finality of a released chain event. Soft and safe events can still be
+// reorged out, which is announced by a "reorg_retraction" event; final ones cannot.
+const (
+	FinalitySoft  = "soft"  // released after a fixed number of confirmations
+	FinalitySafe  = "safe"  // at or below the chain's "safe" block
+	FinalityFinal = "final" // finalized, or (on a rollup) its batch is finalized on the parent chain
+)
+
 // ====== Legacy Event (keep for backward compatibility) ======
 type BalanceEvent struct {
 	UserAddress string `json:"user_address"`
@@ -8,22 +23,34 @@ type BalanceEvent struct {
 	TxHash      string `json:"tx_hash"`
 	Chain       string `json:"chain"`
 	BlockNumber int64  `json:"block_number"`
+	TxIndex     uint   `json:"tx_index"`
+	LogIndex    uint   `json:"log_index"`
+	Sequence    uint64 `json:"sequence"` // EventSequence(block, log index)
 	Confirmed   bool   `json:"confirmed"`
 	Timestamp   int64  `json:"timestamp"`
 }
 
 // ====== NEW: L1 Event ======
 type L1Event struct {
-	UserAddress     string `json:"user_address"`
-	Amount          string `json:"amount"`
-	EventType       string `json:"event_type"` // "collateral_deposit", "collateral_withdraw", "state_update"
-	Token           string `json:"token"`      // "USDC", "USDT", "DAI"
-	TxHash          string `json:"tx_hash"`
-	BlockNumber     int64  `json:"block_number"`
-	Confirmed       bool   `json:"confirmed"`
-	Timestamp       int64  `json:"timestamp"`
-	ContractAddress string `json:"contract_address"`     // CollateralVaultL1 address
-	L2TxHash        string `json:"l2_tx_hash,omitempty"` // For bridge events
+	UserAddress     string                 `json:"user_address"`
+	Amount          string                 `json:"amount"`
+	EventType       string                 `json:"event_type"` // "collateral_deposit", "collateral_withdraw", "state_update"
+	Token           string                 `json:"token"`      // "USDC", "USDT", "DAI"
+	TxHash          string                 `json:"tx_hash"`
+	BlockNumber     int64                  `json:"block_number"`
+	BlockHash       string                 `json:"block_hash,omitempty"`
+	TxIndex         uint                   `json:"tx_index"`
+	LogIndex        uint                   `json:"log_index"`
+	Sequence        uint64                 `json:"sequence"` // EventSequence(block, log index)
+	Confirmed       bool                   `json:"confirmed"`
+	Finality        string                 `json:"finality,omitempty"` // FinalitySoft, FinalitySafe or FinalityFinal once confirmed
+	Timestamp       int64                  `json:"timestamp"`
+	ContractAddress string                 `json:"contract_address"`     // CollateralVaultL1 address
+	L2TxHash        string                 `json:"l2_tx_hash,omitempty"` // For bridge events
+	Metadata        map[string]interface{} `json:"metadata,omitempty"`   // Decoded event fields
+
+	// Set on "reorg_retraction" events: the event type being compensated
+	RetractedEventType string `json:"retracted_event_type,omitempty"`
 }
 
 // ====== NEW: L2 Event ======
@@ -33,10 +60,18 @@ type L2Event struct {
 	EventType       string                 `json:"event_type"` // "vault_deposit", "vault_withdraw", "rwa_trade", etc.
 	TxHash          string                 `json:"tx_hash"`
 	BlockNumber     int64                  `json:"block_number"`
+	BlockHash       string                 `json:"block_hash,omitempty"`
+	TxIndex         uint                   `json:"tx_index"`
+	LogIndex        uint                   `json:"log_index"`
+	Sequence        uint64                 `json:"sequence"` // EventSequence(block, log index)
 	Confirmed       bool                   `json:"confirmed"`
+	Finality        string                 `json:"finality,omitempty"` // FinalitySoft, FinalitySafe or FinalityFinal once confirmed
 	Timestamp       int64                  `json:"timestamp"`
 	ContractAddress string                 `json:"contract_address"`
 	Metadata        map[string]interface{} `json:"metadata,omitempty"` // Protocol, asset_id, etc.
+
+	// Set on "reorg_retraction" events: the event type being compensated
+	RetractedEventType string `json:"retracted_event_type,omitempty"`
 }
 
 // ====== NEW: Bridge Event ======
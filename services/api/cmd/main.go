@@ -5,6 +5,7 @@ import (
   "database/sql"
   "errors"
   "log"
+  "math/big"
   "net/http"
   "net/http/httputil"
   "net/url"
@@ -15,9 +16,13 @@ import (
   "syscall"
   "time"
 
+  "github.com/ethereum/go-ethereum/ethclient"
   "github.com/gin-gonic/gin"
   "github.com/graphql-go/graphql"
   "github.com/prometheus/client_golang/prometheus/promhttp"
+  "loyalty-points-system/internal/blockchain/l1"
+  "loyalty-points-system/internal/bridge"
+  "loyalty-points-system/internal/chain"
   "loyalty-points-system/internal/config"
   "loyalty-points-system/internal/db"
   "loyalty-points-system/internal/airdrop"
@@ -308,12 +313,16 @@ func main() {
   }
 
   // Airdrop routes - Public (no auth required for listing and checking eligibility)
+  airdropClaims := airdrop.NewClaimConfig(cfg.AirdropChainID, cfg.AirdropDistributor,
+    time.Duration(cfg.AirdropNonceTTLSec)*time.Second)
   publicAirdrop := r.Group("/api/airdrop")
   {
     publicAirdrop.GET("/campaigns", airdrop.GetCampaignsHandler(database))
     publicAirdrop.GET("/campaigns/:id", airdrop.GetCampaignHandler(database))
     publicAirdrop.GET("/campaigns/:id/eligibility", airdrop.CheckEligibilityHandler(database))
-    publicAirdrop.POST("/campaigns/:id/claim", airdrop.ClaimAirdropHandler(database))
+    publicAirdrop.GET("/campaigns/:id/proof", airdrop.GetProofHandler(database))
+    publicAirdrop.POST("/campaigns/:id/nonce", airdrop.IssueNonceHandler(database, airdropClaims))
+    publicAirdrop.POST("/campaigns/:id/claim", airdrop.ClaimAirdropHandler(database, airdropClaims))
   }
 
   // L1 routes (Layer 1 collateral management)
@@ -341,14 +350,21 @@ func main() {
   }
 
   // Bridge routes (L1 <-> L2 cross-chain operations)
+  bridgePolicy := newBridgePolicy(cfg, database)
+  bridgeSLA := bridge.SLATargets{
+    L1ToL2: time.Duration(cfg.BridgeSLAL1ToL2Sec) * time.Second,
+    L2ToL1: time.Duration(cfg.BridgeSLAL2ToL1Sec) * time.Second,
+  }
   bridgeAPI := r.Group("/api/v1/bridge")
   {
     bridgeAPI.GET("/status/:messageHash", handlers.GetBridgeStatus(database))
     bridgeAPI.GET("/user/:address/messages", handlers.GetUserBridgeHistory(database))
-    bridgeAPI.POST("/l1-to-l2", handlers.InitiateBridgeL1ToL2(database))
-    bridgeAPI.POST("/l2-to-l1", handlers.InitiateBridgeL2ToL1(database))
+    bridgeAPI.POST("/l1-to-l2", handlers.InitiateBridgeL1ToL2(database, bridgePolicy))
+    bridgeAPI.POST("/l2-to-l1", handlers.InitiateBridgeL2ToL1(database, bridgePolicy))
     bridgeAPI.POST("/retry/:messageHash", handlers.RetryBridgeMessage(database))
     bridgeAPI.GET("/stats", handlers.GetBridgeStats(database))
+    bridgeAPI.GET("/sla", handlers.GetBridgeSLA(database, bridgeSLA))
+    bridgeAPI.GET("/quote", handlers.GetBridgeQuote(newBridgeQuoter(cfg, database, bridgeSLA)))
   }
 
   // Treasury routes (US Treasury tokenization)
@@ -462,6 +478,57 @@ func main() {
   }
 }
 
+// newBridgePolicy builds the checks bridge requests must pass. Without an L1
+// vault (or when its RPC cannot be dialed) only the volume windows and the
+// circuit breaker apply.
+func newBridgePolicy(cfg *config.Config, database *sql.DB) *bridge.Policy {
+  var vault bridge.VaultLimits
+  if cfg.L1CollateralVault != "" {
+    client, err := ethclient.Dial(cfg.L1RPCURL)
+    if err != nil {
+      log.Printf("⚠️  Bridge policy without vault checks: %v", err)
+    } else if svc, err := l1.NewCollateralVaultService(client, cfg.L1CollateralVault); err != nil {
+      log.Printf("⚠️  Bridge policy without vault checks: %v", err)
+    } else {
+      vault = svc
+    }
+  }
+
+  userMax, _ := new(big.Int).SetString(cfg.BridgeUserMaxVolumeWei, 10)
+  globalMax, _ := new(big.Int).SetString(cfg.BridgeGlobalMaxVolumeWei, 10)
+  return bridge.NewPolicy(database, vault, bridge.PolicyConfig{
+    UserMaxVolume:      userMax,
+    UserWindow:         time.Duration(cfg.BridgeUserWindowSec) * time.Second,
+    GlobalMaxVolume:    globalMax,
+    GlobalWindow:       time.Duration(cfg.BridgeGlobalWindowSec) * time.Second,
+    BreakerMaxFailures: cfg.BridgeBreakerMaxFailures,
+    BreakerWindow:      time.Duration(cfg.BridgeBreakerWindowSec) * time.Second,
+    BreakerCooldown:    time.Duration(cfg.BridgeBreakerCooldownSec) * time.Second,
+  })
+}
+
+// newBridgeQuoter connects to both chains for bridge quotes; nil when the
+// gateway is not configured or a chain cannot be reached
+func newBridgeQuoter(cfg *config.Config, database *sql.DB, targets bridge.SLATargets) *bridge.Quoter {
+  if cfg.L1Gateway == "" {
+    return nil
+  }
+  ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+  defer cancel()
+  clients, err := chain.NewDualClientManager(ctx, cfg.L1RPCURL, cfg.L2RPCURL, cfg.L1ChainID, cfg.L2ChainID)
+  if err != nil {
+    log.Printf("⚠️  Bridge quotes disabled: %v", err)
+    return nil
+  }
+  quoter, err := bridge.NewQuoter(database, clients, cfg.L1Gateway, targets, cfg.BridgeAutoFinalize)
+  if err != nil {
+    clients.Close()
+    log.Printf("⚠️  Bridge quotes disabled: %v", err)
+    return nil
+  }
+  return quoter
+}
+
 func timeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
   return func(c *gin.Context) {
     ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
@@ -2,10 +2,16 @@ package handlers
 
 import (
 	"database/sql"
+	"errors"
+	"math/big"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
 	"loyalty-points-system/internal/bridge"
+	"loyalty-points-system/internal/models"
 )
 
 // GetBridgeStatus returns the status of a bridge message
@@ -184,8 +190,42 @@ func GetUserBridgeHistory(db *sql.DB) gin.HandlerFunc {
 	}
 }
 
+// checkBridgePolicy validates the user and amount of a bridge request and runs
+// them through the bridge policy, which reserves the amount in the volume
+// windows when the request is accepted. It writes the error response and
+// returns false when the request is refused.
+func checkBridgePolicy(c *gin.Context, policy *bridge.Policy, direction, userAddress, amount string) bool {
+	if !common.IsHexAddress(userAddress) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_address"})
+		return false
+	}
+	wei, ok := new(big.Int).SetString(amount, 10)
+	if !ok || wei.Sign() <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "amount must be a positive integer in wei"})
+		return false
+	}
+
+	err := policy.Reserve(c.Request.Context(), direction, common.HexToAddress(userAddress), wei)
+	if err == nil {
+		return true
+	}
+
+	status := http.StatusUnprocessableEntity
+	switch {
+	case errors.Is(err, bridge.ErrCircuitOpen), errors.Is(err, bridge.ErrBridgePaused), errors.Is(err, bridge.ErrPolicyUnavailable):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, bridge.ErrUserVolume), errors.Is(err, bridge.ErrGlobalVolume):
+		status = http.StatusTooManyRequests
+	}
+	c.JSON(status, gin.H{
+		"error":  err.Error(),
+		"reason": bridge.RejectionReason(err),
+	})
+	return false
+}
+
 // InitiateBridgeL1ToL2 initiates a bridge from L1 to L2
-func InitiateBridgeL1ToL2(db *sql.DB) gin.HandlerFunc {
+func InitiateBridgeL1ToL2(db *sql.DB, policy *bridge.Policy) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req struct {
 			UserAddress string `json:"user_address" binding:"required"`
@@ -199,6 +239,11 @@ func InitiateBridgeL1ToL2(db *sql.DB) gin.HandlerFunc {
 			return
 		}
 
+		// Refuse requests the vault would revert or the limits do not allow
+		if !checkBridgePolicy(c, policy, models.BridgeL1ToL2, req.UserAddress, req.Amount) {
+			return
+		}
+
 		// TODO: Verify signature
 		// TODO: Submit transaction to L1 Gateway contract
 		// For now, return a placeholder response
@@ -216,7 +261,7 @@ func InitiateBridgeL1ToL2(db *sql.DB) gin.HandlerFunc {
 }
 
 // InitiateBridgeL2ToL1 initiates a bridge from L2 to L1
-func InitiateBridgeL2ToL1(db *sql.DB) gin.HandlerFunc {
+func InitiateBridgeL2ToL1(db *sql.DB, policy *bridge.Policy) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req struct {
 			UserAddress string `json:"user_address" binding:"required"`
@@ -229,6 +274,10 @@ func InitiateBridgeL2ToL1(db *sql.DB) gin.HandlerFunc {
 			return
 		}
 
+		if !checkBridgePolicy(c, policy, models.BridgeL2ToL1, req.UserAddress, req.Amount) {
+			return
+		}
+
 		// TODO: Verify signature
 		// TODO: Submit transaction to L2 IntegratedVault contract
 		// For now, return a placeholder response
@@ -267,6 +316,78 @@ func RetryBridgeMessage(db *sql.DB) gin.HandlerFunc {
 	}
 }
 
+// GetBridgeSLA returns confirmation latency percentiles per direction and per
+// day, time-in-state breakdowns and attainment of the advertised times over
+// the last ?days=N days (default 7, at most 90)
+func GetBridgeSLA(db *sql.DB, targets bridge.SLATargets) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+		if err != nil || days <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be a positive integer"})
+			return
+		}
+		if days > 90 {
+			days = 90
+		}
+
+		report, err := bridge.GetSLA(db, time.Now().AddDate(0, 0, -days), targets)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// GetBridgeQuote estimates gas, retryable and protocol costs and the arrival
+// time of a transfer: ?direction=L1_TO_L2|L2_TO_L1&token=0x..&amount=<wei>,
+// optionally &user=0x.. to estimate the deposit gas for that account
+func GetBridgeQuote(quoter *bridge.Quoter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if quoter == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "bridge quotes need the L1 gateway and both RPC endpoints"})
+			return
+		}
+
+		direction := c.Query("direction")
+		if direction != models.BridgeL1ToL2 && direction != models.BridgeL2ToL1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "direction must be L1_TO_L2 or L2_TO_L1"})
+			return
+		}
+		token := c.Query("token")
+		if !common.IsHexAddress(token) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token"})
+			return
+		}
+		amount, ok := new(big.Int).SetString(c.Query("amount"), 10)
+		if !ok || amount.Sign() <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "amount must be a positive integer in wei"})
+			return
+		}
+		var user common.Address
+		if u := c.Query("user"); u != "" {
+			if !common.IsHexAddress(u) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user"})
+				return
+			}
+			user = common.HexToAddress(u)
+		}
+
+		quote, err := quoter.Quote(c.Request.Context(), direction, common.HexToAddress(token), user, amount)
+		if errors.Is(err, bridge.ErrUnsupportedToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, quote)
+	}
+}
+
 // GetBridgeStats returns bridge statistics
 func GetBridgeStats(db *sql.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
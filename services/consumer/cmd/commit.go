@@ -0,0 +1,92 @@
+package main
+
+import (
+  "context"
+  "log"
+  "sync"
+  "time"
+
+  k "github.com/segmentio/kafka-go"
+)
+
+const (
+  // commitBatch is how many handled messages trigger an offset commit
+  commitBatch = 100
+  // commitInterval bounds how long a handled message stays uncommitted
+  commitInterval = time.Second
+)
+
+// messageReader is the subset of *k.Reader used by consume
+type messageReader interface {
+  FetchMessage(ctx context.Context) (k.Message, error)
+  CommitMessages(ctx context.Context, msgs ...k.Message) error
+}
+
+// offsetCommitter batches offset commits. Only messages whose Postgres
+// transaction has committed (or that were dead-lettered) are added, so a crash
+// at any point redelivers from the last applied message: at-least-once, with
+// duplicates absorbed by the processed-events ledger. It is shared by the
+// workers of a topic; each partition is only ever advanced by its own worker.
+type offsetCommitter struct {
+  mu        sync.Mutex
+  tag       string
+  reader    messageReader
+  pending   map[int]k.Message // highest handled message per partition
+  count     int
+  lastFlush time.Time
+}
+
+func newOffsetCommitter(tag string, reader messageReader) *offsetCommitter {
+  return &offsetCommitter{
+    tag:       tag,
+    reader:    reader,
+    pending:   make(map[int]k.Message),
+    lastFlush: time.Now(),
+  }
+}
+
+// done marks a message as fully handled and commits once the batch is full or old enough
+func (c *offsetCommitter) done(ctx context.Context, m k.Message) {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+  if prev, ok := c.pending[m.Partition]; !ok || m.Offset > prev.Offset {
+    c.pending[m.Partition] = m
+  }
+  c.count++
+  if c.count >= commitBatch || time.Since(c.lastFlush) >= commitInterval {
+    c.flushLocked(ctx)
+  }
+}
+
+// flush commits the highest handled offset of every partition.
+// On failure the offsets stay pending and are retried on the next flush.
+func (c *offsetCommitter) flush(ctx context.Context) {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+  c.flushLocked(ctx)
+}
+
+func (c *offsetCommitter) flushLocked(ctx context.Context) {
+  c.lastFlush = time.Now()
+  if len(c.pending) == 0 {
+    return
+  }
+
+  msgs := make([]k.Message, 0, len(c.pending))
+  for _, m := range c.pending {
+    msgs = append(msgs, m)
+  }
+  if err := c.reader.CommitMessages(ctx, msgs...); err != nil {
+    log.Printf("⚠️  [%s] Offset commit failed: %v", c.tag, err)
+    return
+  }
+  c.pending = make(map[int]k.Message)
+  c.count = 0
+}
+
+// close commits what is pending before the consumer exits
+func (c *offsetCommitter) close() {
+  ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+  defer cancel()
+  c.flush(ctx)
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "sync"
+  "testing"
+  "time"
+
+  k "github.com/segmentio/kafka-go"
+)
+
+var errCrashed = errors.New("consumer process killed")
+
+// memTopic is an in-process stand-in for a Kafka topic read by one consumer group
+type memTopic struct {
+  mu         sync.Mutex
+  name       string
+  partitions [][]k.Message
+  committed  []int64 // next offset to read per partition
+  onCommit   func(m k.Message)
+}
+
+func newMemTopic(name string, partitions int) *memTopic {
+  return &memTopic{name: name, partitions: make([][]k.Message, partitions), committed: make([]int64, partitions)}
+}
+
+func (t *memTopic) produce(partition int, value string) {
+  t.produceKeyed(partition, "", value)
+}
+
+func (t *memTopic) produceKeyed(partition int, key, value string) {
+  t.mu.Lock()
+  defer t.mu.Unlock()
+  t.partitions[partition] = append(t.partitions[partition], k.Message{
+    Topic: t.name, Partition: partition, Offset: int64(len(t.partitions[partition])), Key: []byte(key), Value: []byte(value),
+  })
+}
+
+func (t *memTopic) fullyCommitted() bool {
+  t.mu.Lock()
+  defer t.mu.Unlock()
+  for p, msgs := range t.partitions {
+    if t.committed[p] != int64(len(msgs)) { return false }
+  }
+  return true
+}
+
+// join returns a group member that resumes from the committed offsets
+func (t *memTopic) join() *memReader {
+  t.mu.Lock()
+  defer t.mu.Unlock()
+  return &memReader{topic: t, next: append([]int64(nil), t.committed...)}
+}
+
+// memReader fetches round-robin across partitions. Once killed, commits fail
+// as they would for a process that died before reaching the broker.
+type memReader struct {
+  topic  *memTopic
+  next   []int64
+  rr     int
+  killed bool
+}
+
+func (r *memReader) FetchMessage(ctx context.Context) (k.Message, error) {
+  for {
+    r.topic.mu.Lock()
+    for i := range r.next {
+      p := (r.rr + i) % len(r.next)
+      if r.next[p] < int64(len(r.topic.partitions[p])) {
+        m := r.topic.partitions[p][r.next[p]]
+        r.next[p]++
+        r.rr = p + 1
+        r.topic.mu.Unlock()
+        return m, nil
+      }
+    }
+    r.topic.mu.Unlock()
+
+    select {
+    case <-ctx.Done():
+      return k.Message{}, ctx.Err()
+    case <-time.After(5 * time.Millisecond):
+    }
+  }
+}
+
+func (r *memReader) CommitMessages(ctx context.Context, msgs ...k.Message) error {
+  r.topic.mu.Lock()
+  defer r.topic.mu.Unlock()
+  if r.killed { return errCrashed }
+  for _, m := range msgs {
+    if r.topic.onCommit != nil { r.topic.onCommit(m) }
+    if m.Offset+1 > r.topic.committed[m.Partition] { r.topic.committed[m.Partition] = m.Offset + 1 }
+  }
+  return nil
+}
+
+// memWriter collects dead letters
+type memWriter struct {
+  mu   sync.Mutex
+  msgs []k.Message
+}
+
+func (w *memWriter) WriteMessages(ctx context.Context, msgs ...k.Message) error {
+  w.mu.Lock()
+  defer w.mu.Unlock()
+  w.msgs = append(w.msgs, msgs...)
+  return nil
+}
+
+func (w *memWriter) Close() error { return nil }
+
+// TestConsumeAtLeastOnceAcrossCrash kills the consumer mid-batch and checks
+// that the restarted consumer re-applies everything that was not committed,
+// and that no offset is ever committed before its message was applied.
+func TestConsumeAtLeastOnceAcrossCrash(t *testing.T) {
+  topic := newMemTopic("events.l1", 2)
+  for i := 0; i < 10; i++ {
+    topic.produce(i%2, fmt.Sprintf("evt-%d", i))
+  }
+  topic.produce(1, "poison")
+
+  var mu sync.Mutex
+  applied := make(map[string]int)
+  topic.onCommit = func(m k.Message) {
+    mu.Lock()
+    defer mu.Unlock()
+    if string(m.Value) != "poison" && applied[string(m.Value)] == 0 {
+      t.Errorf("offset %d/%d committed before %s was applied", m.Partition, m.Offset, m.Value)
+    }
+  }
+
+  dlqWriter := &memWriter{}
+  dlq := &deadLetters{writer: dlqWriter}
+
+  // First run: killed after the 6th applied message, before any commit lands
+  reader := topic.join()
+  ctx, cancel := context.WithCancel(context.Background())
+  handled := 0
+  crashing := func(m k.Message) error {
+    if string(m.Value) == "poison" { return decodeError{errors.New("invalid JSON")} }
+    mu.Lock()
+    defer mu.Unlock()
+    applied[string(m.Value)]++
+    if handled++; handled == 6 {
+      topic.mu.Lock()
+      reader.killed = true
+      topic.mu.Unlock()
+      cancel()
+    }
+    return nil
+  }
+  consume(ctx, "L1", topic.name, reader, topicHandler{one: crashing}, dlq, poolOptions{workers: 1})
+
+  if topic.fullyCommitted() {
+    t.Fatal("crashed consumer should not have committed every offset")
+  }
+
+  // Second run: resumes from the committed offsets and drains the topic
+  ctx, cancel = context.WithCancel(context.Background())
+  done := make(chan struct{})
+  go func() {
+    consume(ctx, "L1", topic.name, topic.join(), topicHandler{one: func(m k.Message) error {
+      if string(m.Value) == "poison" { return decodeError{errors.New("invalid JSON")} }
+      mu.Lock()
+      defer mu.Unlock()
+      applied[string(m.Value)]++
+      return nil
+    }}, dlq, poolOptions{workers: 2, batchSize: 4})
+    close(done)
+  }()
+
+  deadline := time.Now().Add(5 * time.Second)
+  for !topic.fullyCommitted() && time.Now().Before(deadline) {
+    time.Sleep(10 * time.Millisecond)
+  }
+  cancel()
+  <-done
+
+  if !topic.fullyCommitted() {
+    t.Fatal("restarted consumer did not commit every offset")
+  }
+  mu.Lock()
+  defer mu.Unlock()
+  for i := 0; i < 10; i++ {
+    if applied[fmt.Sprintf("evt-%d", i)] == 0 {
+      t.Errorf("evt-%d was never applied", i)
+    }
+  }
+  if handled != 6 {
+    t.Errorf("first run applied %d messages, want 6", handled)
+  }
+
+  dlqWriter.mu.Lock()
+  defer dlqWriter.mu.Unlock()
+  if len(dlqWriter.msgs) == 0 {
+    t.Fatal("poison message was not dead-lettered")
+  }
+  for _, m := range dlqWriter.msgs {
+    if m.Topic != "events.l1.dlq" || headerValue(m, headerDLQSourceTopic) != "events.l1" || headerValue(m, headerDLQAttempts) == "" {
+      t.Errorf("unexpected dead letter %s with headers %v", m.Topic, m.Headers)
+    }
+  }
+}
+
+// TestConsumeBatchesPreserveUserOrder runs a pool over several partitions and
+// checks that batches never hold two events of one user and that each user's
+// events are applied in the order they were produced.
+func TestConsumeBatchesPreserveUserOrder(t *testing.T) {
+  topic := newMemTopic("events.l2", 4)
+  const users, perUser = 12, 20
+  for i := 0; i < perUser; i++ {
+    for u := 0; u < users; u++ {
+      topic.produceKeyed(u%4, fmt.Sprintf("user-%d", u), fmt.Sprintf("%d", i))
+    }
+  }
+
+  var mu sync.Mutex
+  applied := make(map[string][]string)
+  largest := 0
+  apply := func(m k.Message) {
+    applied[string(m.Key)] = append(applied[string(m.Key)], string(m.Value))
+  }
+  handler := topicHandler{
+    one: func(m k.Message) error {
+      mu.Lock()
+      defer mu.Unlock()
+      apply(m)
+      return nil
+    },
+    batch: func(msgs []k.Message) error {
+      mu.Lock()
+      defer mu.Unlock()
+      seen := make(map[string]bool)
+      for _, m := range msgs {
+        if seen[string(m.Key)] { t.Errorf("batch holds two events of %s", m.Key) }
+        seen[string(m.Key)] = true
+        apply(m)
+      }
+      if len(msgs) > largest { largest = len(msgs) }
+      return nil
+    },
+  }
+
+  ctx, cancel := context.WithCancel(context.Background())
+  done := make(chan struct{})
+  go func() {
+    consume(ctx, "L2", topic.name, topic.join(), handler, &deadLetters{writer: &memWriter{}}, poolOptions{workers: 3, batchSize: 8})
+    close(done)
+  }()
+
+  deadline := time.Now().Add(5 * time.Second)
+  for !topic.fullyCommitted() && time.Now().Before(deadline) {
+    time.Sleep(10 * time.Millisecond)
+  }
+  cancel()
+  <-done
+
+  mu.Lock()
+  defer mu.Unlock()
+  for u := 0; u < users; u++ {
+    got := applied[fmt.Sprintf("user-%d", u)]
+    if len(got) != perUser {
+      t.Fatalf("user-%d: applied %d events, want %d", u, len(got), perUser)
+    }
+    for i, v := range got {
+      if v != fmt.Sprintf("%d", i) { t.Fatalf("user-%d: event %d applied out of order: %v", u, i, got) }
+    }
+  }
+  if largest < 2 {
+    t.Error("no events were batched")
+  }
+}
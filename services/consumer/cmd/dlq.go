@@ -0,0 +1,140 @@
+package main
+
+import (
+  "context"
+  "errors"
+  "log"
+  "strconv"
+  "time"
+
+  "loyalty-points-system/internal/kafka"
+  "loyalty-points-system/internal/metrics"
+
+  k "github.com/segmentio/kafka-go"
+)
+
+// maxHandleAttempts is how many times a message is applied before it is dead-lettered
+const maxHandleAttempts = 3
+
+// Headers added to dead-lettered messages
+const (
+  headerDLQSourceTopic     = "dlq-source-topic"
+  headerDLQSourcePartition = "dlq-source-partition"
+  headerDLQSourceOffset    = "dlq-source-offset"
+  headerDLQError           = "dlq-error"
+  headerDLQAttempts        = "dlq-attempts"
+  headerDLQFailedAt        = "dlq-failed-at"
+)
+
+// messageHandler decodes and applies one Kafka message
+type messageHandler func(m k.Message) error
+
+// decodeError marks a message that can never be applied; it is not retried
+type decodeError struct{ err error }
+
+func (e decodeError) Error() string { return "decode: " + e.err.Error() }
+func (e decodeError) Unwrap() error { return e.err }
+
+// dlqTopic is the dead-letter topic of a source topic
+func dlqTopic(topic string) string { return topic + ".dlq" }
+
+// headerValue returns the value of header key, or "" when absent
+func headerValue(m k.Message, key string) string {
+  for _, h := range m.Headers {
+    if h.Key == key { return string(h.Value) }
+  }
+  return ""
+}
+
+// sourceTopic is the topic a message was originally consumed from,
+// following dead-letter hops
+func sourceTopic(m k.Message) string {
+  if t := headerValue(m, headerDLQSourceTopic); t != "" { return t }
+  return m.Topic
+}
+
+// deadLetters applies messages with retries and parks the ones that keep
+// failing on the source topic's DLQ, so they can be replayed later
+type deadLetters struct {
+  writer messageWriter
+}
+
+// messageWriter is the subset of *k.Writer used for dead letters
+type messageWriter interface {
+  WriteMessages(ctx context.Context, msgs ...k.Message) error
+  Close() error
+}
+
+func newDeadLetters(brokers []string) *deadLetters {
+  return &deadLetters{writer: kafka.NewWriter(brokers, "")} // topic set per message
+}
+
+func (d *deadLetters) Close() error { return d.writer.Close() }
+
+// process runs handle with retries. A message that still fails is written to
+// the DLQ; an error is returned only if that write fails too.
+func (d *deadLetters) process(ctx context.Context, tag string, m k.Message, handle messageHandler) error {
+  var err error
+  attempts := 0
+  for attempts < maxHandleAttempts {
+    attempts++
+    if err = handle(m); err == nil { return nil }
+
+    var de decodeError
+    if errors.As(err, &de) || attempts == maxHandleAttempts { break }
+
+    log.Printf("⚠️  [%s] Attempt %d/%d failed at offset %d: %v", tag, attempts, maxHandleAttempts, m.Offset, err)
+    select {
+    case <-time.After(time.Duration(attempts) * time.Second):
+    case <-ctx.Done():
+      // Shutting down: leave the message uncommitted so it is redelivered
+      return ctx.Err()
+    }
+  }
+
+  return d.publish(ctx, tag, m, attempts, err)
+}
+
+// publish writes a failed message to its DLQ, carrying the cause and the
+// total number of attempts across replays
+func (d *deadLetters) publish(ctx context.Context, tag string, m k.Message, attempts int, cause error) error {
+  source := sourceTopic(m)
+  if prev, err := strconv.Atoi(headerValue(m, headerDLQAttempts)); err == nil { attempts += prev }
+
+  headers := make([]k.Header, 0, len(m.Headers)+6)
+  for _, h := range m.Headers {
+    switch h.Key {
+    case headerDLQSourceTopic, headerDLQSourcePartition, headerDLQSourceOffset,
+      headerDLQError, headerDLQAttempts, headerDLQFailedAt:
+      continue // replaced below
+    }
+    headers = append(headers, h)
+  }
+  partition, offset := strconv.Itoa(m.Partition), strconv.FormatInt(m.Offset, 10)
+  if source != m.Topic {
+    // Replayed dead letter: keep pointing at the original message
+    partition, offset = headerValue(m, headerDLQSourcePartition), headerValue(m, headerDLQSourceOffset)
+  }
+  headers = append(headers,
+    k.Header{Key: headerDLQSourceTopic, Value: []byte(source)},
+    k.Header{Key: headerDLQSourcePartition, Value: []byte(partition)},
+    k.Header{Key: headerDLQSourceOffset, Value: []byte(offset)},
+    k.Header{Key: headerDLQError, Value: []byte(cause.Error())},
+    k.Header{Key: headerDLQAttempts, Value: []byte(strconv.Itoa(attempts))},
+    k.Header{Key: headerDLQFailedAt, Value: []byte(time.Now().UTC().Format(time.RFC3339))},
+  )
+
+  wctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+  defer cancel()
+  err := d.writer.WriteMessages(wctx, k.Message{
+    Topic: dlqTopic(source), Key: m.Key, Value: m.Value, Headers: headers,
+  })
+  if err != nil {
+    log.Printf("❌ [%s] DLQ write failed for %s offset %s: %v (cause: %v)", tag, source, offset, err, cause)
+    return err
+  }
+
+  metrics.KafkaDeadLetters.WithLabelValues(source).Inc()
+  log.Printf("☠️  [%s] Dead-lettered %s offset %s after %d attempt(s): %v", tag, source, offset, attempts, cause)
+  return nil
+}
@@ -4,6 +4,7 @@ import (
   "context"
   "database/sql"
   "encoding/json"
+  "fmt"
   "log"
   "net/http"
   "os"
@@ -11,11 +12,12 @@ import (
   "strings"
   "sync"
   "syscall"
-  "time"
 
   "github.com/prometheus/client_golang/prometheus/promhttp"
   "loyalty-points-system/internal/config"
   "loyalty-points-system/internal/db"
+  "loyalty-points-system/internal/events"
+  "loyalty-points-system/internal/kafka"
   "loyalty-points-system/internal/models"
 
   k "github.com/segmentio/kafka-go"
@@ -36,16 +38,44 @@ func main() {
   }
   defer database.Close()
 
-  // Create readers for all topics
   brokers := strings.Split(cfg.KafkaBrokers, ",")
+  handlers := newHandlers(cfg, database)
+
+  // Failed messages are parked on <topic>.dlq
+  dlq := newDeadLetters(brokers)
+  defer dlq.Close()
+
+  // `consumer replay ...` re-drives a DLQ or a range of a topic and exits
+  if len(os.Args) > 1 && os.Args[1] == "replay" {
+    if err := runReplay(brokers, handlers, dlq, os.Args[2:]); err != nil {
+      log.Fatalf("❌ Replay failed: %v", err)
+    }
+    return
+  }
+
+  // `consumer rebuild-projections ...` recomputes the projection tables and exits
+  if len(os.Args) > 1 && os.Args[1] == "rebuild-projections" {
+    if err := runRebuild(cfg, database, brokers, os.Args[2:]); err != nil {
+      log.Fatalf("❌ Projection rebuild failed: %v", err)
+    }
+    return
+  }
+
+  // Create readers for all topics
   topicRaw := cfg.KafkaTopicRaw
   topicL1 := cfg.KafkaTopicL1
   topicL2 := cfg.KafkaTopicL2
   topicBridge := cfg.KafkaTopicBridge
 
+  for _, t := range []string{topicRaw, topicL1, topicL2, topicBridge} {
+    if err := kafka.EnsureTopic(context.Background(), brokers[0], dlqTopic(t), 3, 1); err != nil {
+      log.Printf("⚠️  Failed to create DLQ topic %s: %v", dlqTopic(t), err)
+    }
+  }
+
   readerRaw := k.NewReader(k.ReaderConfig{
     Brokers:     brokers,
-    GroupID:     "loyalty-consumer",
+    GroupID:     consumerGroup,
     Topic:       topicRaw,
     StartOffset: k.LastOffset,
   })
@@ -53,7 +83,7 @@ func main() {
 
   readerL1 := k.NewReader(k.ReaderConfig{
     Brokers:     brokers,
-    GroupID:     "loyalty-consumer",
+    GroupID:     consumerGroup,
     Topic:       topicL1,
     StartOffset: k.LastOffset,
   })
@@ -61,7 +91,7 @@ func main() {
 
   readerL2 := k.NewReader(k.ReaderConfig{
     Brokers:     brokers,
-    GroupID:     "loyalty-consumer",
+    GroupID:     consumerGroup,
     Topic:       topicL2,
     StartOffset: k.LastOffset,
   })
@@ -69,7 +99,7 @@ func main() {
 
   readerBridge := k.NewReader(k.ReaderConfig{
     Brokers:     brokers,
-    GroupID:     "loyalty-consumer",
+    GroupID:     consumerGroup,
     Topic:       topicBridge,
     StartOffset: k.LastOffset,
   })
@@ -103,6 +133,9 @@ func main() {
   sigChan := make(chan os.Signal, 1)
   signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
+  // Each topic spreads its partitions over a pool of workers
+  pool := poolOptions{workers: cfg.ConsumerWorkers, batchSize: cfg.ConsumerBatchSize}
+
   var wg sync.WaitGroup
 
   // Start goroutine for each topic
@@ -110,22 +143,22 @@ func main() {
 
   go func() {
     defer wg.Done()
-    consumeRaw(ctx, readerRaw, database)
+    consume(ctx, "RAW", topicRaw, readerRaw, handlers[topicRaw], dlq, pool)
   }()
 
   go func() {
     defer wg.Done()
-    consumeL1(ctx, readerL1, database)
+    consume(ctx, "L1", topicL1, readerL1, handlers[topicL1], dlq, pool)
   }()
 
   go func() {
     defer wg.Done()
-    consumeL2(ctx, readerL2, database)
+    consume(ctx, "L2", topicL2, readerL2, handlers[topicL2], dlq, pool)
   }()
 
   go func() {
     defer wg.Done()
-    consumeBridge(ctx, readerBridge, database)
+    consume(ctx, "BRIDGE", topicBridge, readerBridge, handlers[topicBridge], dlq, pool)
   }()
 
   // Wait for interrupt signal
@@ -136,246 +169,132 @@ func main() {
   log.Println("✅ Consumer stopped gracefully")
 }
 
-func applyEvent(dbx *sql.DB, evt *models.BalanceEvent) (err error) {
-  tx, txErr := dbx.Begin()
-  if txErr != nil {
-    return txErr
+// applyEvent applies a legacy balance event inside tx
+func applyEvent(tx *sql.Tx, evt *models.BalanceEvent) error {
+  // Skip events already applied (Kafka redelivery, replays)
+  fresh, err := db.MarkEventProcessed(tx, evt.Chain, evt.TxHash, evt.LogIndex, evt.EventType)
+  if err != nil { return err }
+  if !fresh {
+    log.Printf("⏭️  [RAW] Skipping already applied %s tx=%s log=%d", evt.EventType, evt.TxHash, evt.LogIndex)
+    return nil
   }
 
-  // Proper transaction rollback/commit handling
-  defer func() {
-    if err != nil {
-      if rbErr := tx.Rollback(); rbErr != nil {
-        log.Printf("Transaction rollback error: %v (original error: %v)", rbErr, err)
-      }
-    } else {
-      if cmErr := tx.Commit(); cmErr != nil {
-        log.Printf("Transaction commit error: %v", cmErr)
-        err = cmErr
-      }
-    }
-  }()
-
   _, err = tx.Exec(`INSERT INTO users(address) VALUES($1) ON CONFLICT (address) DO NOTHING`, evt.UserAddress)
   if err != nil { return err }
 
-  _, err = tx.Exec(`INSERT INTO balances(user_address, balance) VALUES($1, 0) ON CONFLICT (user_address) DO NOTHING`, evt.UserAddress)
-  if err != nil { return err }
-
-  _, err = tx.Exec(`INSERT INTO balance_events (user_address, amount, event_type, tx_hash, chain, block_number, confirmed)
-                    VALUES ($1, $2, $3, $4, $5, $6, TRUE)`,
-    evt.UserAddress, evt.Amount, evt.EventType, evt.TxHash, evt.Chain, evt.BlockNumber)
+  _, err = tx.Exec(`INSERT INTO balance_events (user_address, amount, event_type, tx_hash, chain, block_number, confirmed, log_index)
+                    VALUES ($1, $2, $3, $4, $5, $6, TRUE, $7)`,
+    evt.UserAddress, evt.Amount, evt.EventType, evt.TxHash, evt.Chain, evt.BlockNumber, int64(evt.LogIndex))
   if err != nil { return err }
 
-  op := "+"
-  if evt.EventType == "burn" || evt.EventType == "transfer_out" { op = "-" }
-
-  _, err = tx.Exec(`
-    UPDATE balances
-    SET balance = CASE WHEN $3 = '-' THEN balance - CAST($2 AS NUMERIC)
-                       ELSE balance + CAST($2 AS NUMERIC) END, updated_at = NOW()
-    WHERE user_address = $1
-  `, evt.UserAddress, evt.Amount, op)
-
-  return err
+  return db.ApplyBalanceEvent(tx, evt)
 }
 
-// consumeRaw handles legacy balance events from events.raw topic
-func consumeRaw(ctx context.Context, reader *k.Reader, database *sql.DB) {
-  log.Println("📥 [RAW] Started consumer for legacy balance events")
-  for {
-    select {
-    case <-ctx.Done():
-      log.Println("🛑 [RAW] Consumer stopped")
-      return
-    default:
-      m, err := reader.ReadMessage(ctx)
-      if err != nil {
-        if ctx.Err() != nil {
-          return
-        }
-        log.Printf("❌ [RAW] Read error: %v", err)
-        time.Sleep(time.Second)
-        continue
-      }
-
-      var evt models.BalanceEvent
-      if err := json.Unmarshal(m.Value, &evt); err != nil {
-        log.Printf("❌ [RAW] JSON unmarshal error: %v", err)
-        continue
-      }
-
-      if !evt.Confirmed {
-        continue
-      }
+// txApplier decodes one message and applies it inside a transaction owned by the caller
+type txApplier func(tx *sql.Tx, m k.Message) error
 
-      if err := applyEvent(database, &evt); err != nil {
-        log.Printf("❌ [RAW] Apply event error: %v", err)
-      } else {
-        log.Printf("✅ [RAW] Processed %s tx=%s user=%s", evt.EventType, evt.TxHash, evt.UserAddress)
-      }
-    }
+// newHandlers returns the handler of every consumed topic
+func newHandlers(cfg *config.Config, database *sql.DB) map[string]topicHandler {
+  return map[string]topicHandler{
+    cfg.KafkaTopicRaw:    newTopicHandler(database, applyRaw),
+    cfg.KafkaTopicL1:     newTopicHandler(database, applyL1),
+    cfg.KafkaTopicL2:     newTopicHandler(database, applyL2),
+    cfg.KafkaTopicBridge: newTopicHandler(database, applyBridge),
   }
 }
 
-// consumeL1 handles L1 events from events.l1 topic
-func consumeL1(ctx context.Context, reader *k.Reader, database *sql.DB) {
-  log.Println("📥 [L1] Started consumer for L1 events")
-  for {
-    select {
-    case <-ctx.Done():
-      log.Println("🛑 [L1] Consumer stopped")
-      return
-    default:
-      m, err := reader.ReadMessage(ctx)
-      if err != nil {
-        if ctx.Err() != nil {
-          return
+// newTopicHandler runs apply in one transaction per message, or per batch
+func newTopicHandler(database *sql.DB, apply txApplier) topicHandler {
+  return topicHandler{
+    one: func(m k.Message) error {
+      return inTx(database, func(tx *sql.Tx) error { return apply(tx, m) })
+    },
+    batch: func(msgs []k.Message) error {
+      return inTx(database, func(tx *sql.Tx) error {
+        for _, m := range msgs {
+          if err := apply(tx, m); err != nil {
+            return fmt.Errorf("offset %d/%d: %w", m.Partition, m.Offset, err)
+          }
         }
-        log.Printf("❌ [L1] Read error: %v", err)
-        time.Sleep(time.Second)
-        continue
-      }
-
-      var evt models.L1Event
-      if err := json.Unmarshal(m.Value, &evt); err != nil {
-        log.Printf("❌ [L1] JSON unmarshal error: %v", err)
-        continue
-      }
-
-      if !evt.Confirmed {
-        continue
-      }
-
-      if err := handleL1Event(database, &evt); err != nil {
-        log.Printf("❌ [L1] Handle event error: %v", err)
-      } else {
-        log.Printf("✅ [L1] Processed %s tx=%s user=%s block=%d", evt.EventType, evt.TxHash, evt.UserAddress, evt.BlockNumber)
-      }
-    }
+        return nil
+      })
+    },
   }
 }
 
-// consumeL2 handles L2 events from events.l2 topic
-func consumeL2(ctx context.Context, reader *k.Reader, database *sql.DB) {
-  log.Println("📥 [L2] Started consumer for L2 events")
-  for {
-    select {
-    case <-ctx.Done():
-      log.Println("🛑 [L2] Consumer stopped")
-      return
-    default:
-      m, err := reader.ReadMessage(ctx)
-      if err != nil {
-        if ctx.Err() != nil {
-          return
-        }
-        log.Printf("❌ [L2] Read error: %v", err)
-        time.Sleep(time.Second)
-        continue
-      }
-
-      var evt models.L2Event
-      if err := json.Unmarshal(m.Value, &evt); err != nil {
-        log.Printf("❌ [L2] JSON unmarshal error: %v", err)
-        continue
-      }
+// applyRaw applies legacy balance events from events.raw topic
+func applyRaw(tx *sql.Tx, m k.Message) error {
+  var evt models.BalanceEvent
+  if err := json.Unmarshal(m.Value, &evt); err != nil {
+    return decodeError{err}
+  }
 
-      if !evt.Confirmed {
-        continue
-      }
+  if !evt.Confirmed {
+    return nil
+  }
 
-      if err := handleL2Event(database, &evt); err != nil {
-        log.Printf("❌ [L2] Handle event error: %v", err)
-      } else {
-        log.Printf("✅ [L2] Processed %s tx=%s user=%s block=%d", evt.EventType, evt.TxHash, evt.UserAddress, evt.BlockNumber)
-      }
-    }
+  if err := applyEvent(tx, &evt); err != nil {
+    return fmt.Errorf("apply %s tx=%s: %w", evt.EventType, evt.TxHash, err)
   }
+  log.Printf("✅ [RAW] Processed %s tx=%s user=%s", evt.EventType, evt.TxHash, evt.UserAddress)
+  return nil
 }
 
-// consumeBridge handles bridge events from events.bridge topic
-func consumeBridge(ctx context.Context, reader *k.Reader, database *sql.DB) {
-  log.Println("📥 [BRIDGE] Started consumer for bridge events")
-  for {
-    select {
-    case <-ctx.Done():
-      log.Println("🛑 [BRIDGE] Consumer stopped")
-      return
-    default:
-      m, err := reader.ReadMessage(ctx)
-      if err != nil {
-        if ctx.Err() != nil {
-          return
-        }
-        log.Printf("❌ [BRIDGE] Read error: %v", err)
-        time.Sleep(time.Second)
-        continue
-      }
+// applyL1 applies L1 event envelopes from events.l1 topic
+func applyL1(tx *sql.Tx, m k.Message) error {
+  env, err := events.Decode(m, events.KindL1, nil)
+  if err != nil {
+    return decodeError{err}
+  }
+  evt := env.L1
 
-      var evt models.BridgeEvent
-      if err := json.Unmarshal(m.Value, &evt); err != nil {
-        log.Printf("❌ [BRIDGE] JSON unmarshal error: %v", err)
-        continue
-      }
+  if !evt.Confirmed {
+    return nil
+  }
 
-      if err := handleBridgeEvent(database, &evt); err != nil {
-        log.Printf("❌ [BRIDGE] Handle event error: %v", err)
-      } else {
-        log.Printf("✅ [BRIDGE] Processed %s msg=%s user=%s status=%s", evt.Direction, evt.MessageHash, evt.UserAddress, evt.Status)
-      }
-    }
+  if err := db.ProcessL1Event(tx, evt); err != nil {
+    return fmt.Errorf("handle %s tx=%s: %w", evt.EventType, evt.TxHash, err)
   }
+  log.Printf("✅ [L1] Processed %s tx=%s user=%s block=%d", evt.EventType, evt.TxHash, evt.UserAddress, evt.BlockNumber)
+  return nil
 }
 
-// handleL1Event processes L1 events using the database operations
-func handleL1Event(dbx *sql.DB, evt *models.L1Event) (err error) {
-  tx, txErr := dbx.Begin()
-  if txErr != nil {
-    return txErr
+// applyL2 applies L2 event envelopes from events.l2 topic
+func applyL2(tx *sql.Tx, m k.Message) error {
+  env, err := events.Decode(m, events.KindL2, nil)
+  if err != nil {
+    return decodeError{err}
   }
+  evt := env.L2
 
-  defer func() {
-    if err != nil {
-      if rbErr := tx.Rollback(); rbErr != nil {
-        log.Printf("Transaction rollback error: %v (original error: %v)", rbErr, err)
-      }
-    } else {
-      if cmErr := tx.Commit(); cmErr != nil {
-        log.Printf("Transaction commit error: %v", cmErr)
-        err = cmErr
-      }
-    }
-  }()
+  if !evt.Confirmed {
+    return nil
+  }
 
-  return db.ProcessL1Event(tx, evt)
+  if err := db.ProcessL2Event(tx, evt); err != nil {
+    return fmt.Errorf("handle %s tx=%s: %w", evt.EventType, evt.TxHash, err)
+  }
+  log.Printf("✅ [L2] Processed %s tx=%s user=%s block=%d", evt.EventType, evt.TxHash, evt.UserAddress, evt.BlockNumber)
+  return nil
 }
 
-// handleL2Event processes L2 events using the database operations
-func handleL2Event(dbx *sql.DB, evt *models.L2Event) (err error) {
-  tx, txErr := dbx.Begin()
-  if txErr != nil {
-    return txErr
+// applyBridge applies bridge event envelopes from events.bridge topic
+func applyBridge(tx *sql.Tx, m k.Message) error {
+  env, err := events.Decode(m, events.KindBridge, nil)
+  if err != nil {
+    return decodeError{err}
   }
+  evt := env.Bridge
 
-  defer func() {
-    if err != nil {
-      if rbErr := tx.Rollback(); rbErr != nil {
-        log.Printf("Transaction rollback error: %v (original error: %v)", rbErr, err)
-      }
-    } else {
-      if cmErr := tx.Commit(); cmErr != nil {
-        log.Printf("Transaction commit error: %v", cmErr)
-        err = cmErr
-      }
-    }
-  }()
-
-  return db.ProcessL2Event(tx, evt)
+  if err := db.ProcessBridgeEvent(tx, evt); err != nil {
+    return fmt.Errorf("handle %s msg=%s: %w", evt.Direction, evt.MessageHash, err)
+  }
+  log.Printf("✅ [BRIDGE] Processed %s msg=%s user=%s status=%s", evt.Direction, evt.MessageHash, evt.UserAddress, evt.Status)
+  return nil
 }
 
-// handleBridgeEvent processes bridge events using the database operations
-func handleBridgeEvent(dbx *sql.DB, evt *models.BridgeEvent) (err error) {
+// inTx runs fn in a transaction, committing on success and rolling back on error
+func inTx(dbx *sql.DB, fn func(tx *sql.Tx) error) (err error) {
   tx, txErr := dbx.Begin()
   if txErr != nil {
     return txErr
@@ -394,5 +313,5 @@ func handleBridgeEvent(dbx *sql.DB, evt *models.BridgeEvent) (err error) {
     }
   }()
 
-  return db.ProcessBridgeEvent(tx, evt)
+  return fn(tx)
 }
@@ -0,0 +1,216 @@
+package main
+
+import (
+  "context"
+  "errors"
+  "log"
+  "strconv"
+  "sync"
+  "time"
+
+  "loyalty-points-system/internal/metrics"
+
+  k "github.com/segmentio/kafka-go"
+)
+
+// lagInterval is how often reader stats are exported to KafkaLag
+const lagInterval = 5 * time.Second
+
+// poolOptions sizes the worker pool of one topic
+type poolOptions struct {
+  workers   int // partitions are spread over this many workers
+  batchSize int // max messages applied in one transaction
+}
+
+// batchHandler decodes and applies several messages in one transaction
+type batchHandler func(msgs []k.Message) error
+
+// topicHandler applies the messages of one topic
+type topicHandler struct {
+  one   messageHandler // own transaction; used for retries, the DLQ and replay
+  batch batchHandler   // nil applies messages one at a time
+}
+
+// statsReader is implemented by *k.Reader
+type statsReader interface {
+  Stats() k.ReaderStats
+}
+
+// consume reads a topic and fans its messages out to a pool of workers. Every
+// partition is owned by exactly one worker, and producers key messages by
+// user, so events of one user are always applied in order. Messages that keep
+// failing are moved to the topic's DLQ instead of being dropped. Offsets are
+// committed only after a message was applied or dead-lettered.
+func consume(ctx context.Context, tag, topic string, reader messageReader, handler topicHandler, dlq *deadLetters, opts poolOptions) {
+  if opts.workers < 1 { opts.workers = 1 }
+  if opts.batchSize < 1 { opts.batchSize = 1 }
+  log.Printf("📥 [%s] Started consumer for %s (%d workers, batch %d)", tag, topic, opts.workers, opts.batchSize)
+
+  commits := newOffsetCommitter(tag, reader)
+  workers := make([]*partitionWorker, opts.workers)
+  var wg sync.WaitGroup
+  for i := range workers {
+    w := &partitionWorker{
+      tag: tag, topic: topic, handler: handler, batchSize: opts.batchSize,
+      dlq: dlq, commits: commits, msgs: make(chan k.Message, opts.batchSize),
+    }
+    workers[i] = w
+    wg.Add(1)
+    go func() {
+      defer wg.Done()
+      w.run(ctx)
+    }()
+  }
+  defer func() {
+    for _, w := range workers { close(w.msgs) }
+    wg.Wait()
+    commits.close()
+    log.Printf("🛑 [%s] Consumer stopped", tag)
+  }()
+
+  if sr, ok := reader.(statsReader); ok {
+    go reportLag(ctx, sr)
+  }
+
+  for ctx.Err() == nil {
+    // Wake up at least every commitInterval so an idle topic still commits
+    fctx, cancel := context.WithTimeout(ctx, commitInterval)
+    m, err := reader.FetchMessage(fctx)
+    cancel()
+    if err != nil {
+      if ctx.Err() != nil {
+        return
+      }
+      if errors.Is(err, context.DeadlineExceeded) {
+        commits.flush(ctx)
+        continue
+      }
+      log.Printf("❌ [%s] Read error: %v", tag, err)
+      time.Sleep(time.Second)
+      continue
+    }
+    metrics.KafkaMessagesConsumed.WithLabelValues(m.Topic).Inc()
+
+    w := workers[m.Partition%len(workers)]
+    select {
+    case w.msgs <- m:
+    case <-ctx.Done():
+      return
+    }
+  }
+}
+
+// partitionWorker applies the messages of the partitions assigned to it, in
+// offset order
+type partitionWorker struct {
+  tag       string
+  topic     string
+  handler   topicHandler
+  batchSize int
+  dlq       *deadLetters
+  commits   *offsetCommitter
+  msgs      chan k.Message
+}
+
+func (w *partitionWorker) run(ctx context.Context) {
+  var held *k.Message // starts the next batch; its user is already in the current one
+  for {
+    var first k.Message
+    if held != nil {
+      first, held = *held, nil
+    } else {
+      select {
+      case m, ok := <-w.msgs:
+        if !ok { return }
+        first = m
+      case <-ctx.Done():
+        return
+      }
+    }
+
+    // Add what is already queued, as long as every message is for a different user
+    batch := []k.Message{first}
+    users := map[string]bool{string(first.Key): true}
+  fill:
+    for len(batch) < w.batchSize {
+      select {
+      case m, ok := <-w.msgs:
+        if !ok { break fill }
+        if users[string(m.Key)] {
+          held = &m
+          break fill
+        }
+        users[string(m.Key)] = true
+        batch = append(batch, m)
+      default:
+        break fill
+      }
+    }
+
+    if ctx.Err() != nil || !w.apply(ctx, batch) {
+      return
+    }
+  }
+}
+
+// apply runs a batch in one transaction. When that fails the messages are
+// applied one by one, so a bad message is retried or dead-lettered on its own.
+// It returns false when the worker is shutting down.
+func (w *partitionWorker) apply(ctx context.Context, batch []k.Message) bool {
+  if len(batch) > 1 && w.handler.batch != nil {
+    err := w.handler.batch(batch)
+    if err == nil {
+      for _, m := range batch { w.done(ctx, m) }
+      return true
+    }
+    log.Printf("⚠️  [%s] Batch of %d failed, applying one by one: %v", w.tag, len(batch), err)
+  }
+
+  for _, m := range batch {
+    if ctx.Err() != nil {
+      return false
+    }
+    // A message is never committed past unless it was applied or parked in the DLQ
+    for {
+      err := w.dlq.process(ctx, w.tag, m, w.handler.one)
+      if err == nil {
+        break
+      }
+      if ctx.Err() != nil {
+        return false
+      }
+      log.Printf("❌ [%s] Offset %d not handled, retrying: %v", w.tag, m.Offset, err)
+      select {
+      case <-time.After(5 * time.Second):
+      case <-ctx.Done():
+        return false
+      }
+    }
+    w.done(ctx, m)
+  }
+  return true
+}
+
+// done hands a handled message to the committer and records the partition lag
+func (w *partitionWorker) done(ctx context.Context, m k.Message) {
+  w.commits.done(ctx, m)
+  if m.HighWaterMark > 0 {
+    metrics.KafkaLag.WithLabelValues(w.topic, strconv.Itoa(m.Partition)).Set(float64(m.HighWaterMark - m.Offset - 1))
+  }
+}
+
+// reportLag exports the reader's own lag statistic. Group readers report it
+// under partition "-1", next to the per-partition values set by the workers.
+func reportLag(ctx context.Context, r statsReader) {
+  ticker := time.NewTicker(lagInterval)
+  defer ticker.Stop()
+  for {
+    select {
+    case <-ticker.C:
+      s := r.Stats()
+      metrics.KafkaLag.WithLabelValues(s.Topic, s.Partition).Set(float64(s.Lag))
+    case <-ctx.Done():
+      return
+    }
+  }
+}
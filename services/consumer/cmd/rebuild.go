@@ -0,0 +1,208 @@
+package main
+
+import (
+  "context"
+  "database/sql"
+  "encoding/json"
+  "errors"
+  "flag"
+  "fmt"
+  "log"
+  "os"
+  "os/signal"
+  "strings"
+  "syscall"
+
+  "loyalty-points-system/internal/config"
+  "loyalty-points-system/internal/db"
+  "loyalty-points-system/internal/events"
+  "loyalty-points-system/internal/models"
+
+  k "github.com/segmentio/kafka-go"
+)
+
+// consumerGroup is the Kafka consumer group of the live consumer
+const consumerGroup = "loyalty-consumer"
+
+// runRebuild recomputes projection tables from the event log and swaps them in.
+// The source is either the stored balance_events or the Kafka topics read from
+// their first retained offset.
+//
+// With -source db the live consumer keeps running: its writes wait on the
+// rebuild's locks and land in the new tables once they are swapped in. With
+// -source kafka the consumer group has to be stopped first. A message the
+// consumer already fetched may be waiting on the locks with its
+// processed_events row written; the replay would apply it from the topic and
+// the consumer again after the swap. The rebuild refuses to start while the
+// group has members.
+//
+//   consumer rebuild-projections
+//   consumer rebuild-projections -tables balances,l2_vault_positions -dry-run
+//   consumer rebuild-projections -source kafka
+func runRebuild(cfg *config.Config, database *sql.DB, brokers []string, args []string) error {
+  fs := flag.NewFlagSet("rebuild-projections", flag.ExitOnError)
+  source := fs.String("source", "db", "event source: db (balance_events) or kafka (topics from offset 0)")
+  tables := fs.String("tables", "", "comma-separated tables to rebuild (default: all the source supports)")
+  dryRun := fs.Bool("dry-run", false, "rebuild and report row counts, then roll back")
+  fs.Parse(args)
+
+  selected := db.ProjectionTables
+  if *source == "db" {
+    // balance_events does not keep the metadata treasury holdings are derived from
+    selected = []string{db.ProjectionBalances, db.ProjectionL1Collateral, db.ProjectionL2VaultPositions}
+  }
+  if *tables != "" {
+    selected = strings.Split(*tables, ",")
+  }
+
+  ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+  defer cancel()
+
+  if *source == "kafka" {
+    if err := ensureGroupStopped(ctx, brokers, consumerGroup); err != nil { return err }
+  }
+
+  rebuild, err := db.BeginProjectionRebuild(ctx, database, selected)
+  if err != nil { return err }
+  defer rebuild.Rollback() // no-op after Swap
+
+  log.Printf("🏗️  Rebuilding %s from %s", strings.Join(selected, ", "), *source)
+  var n int
+  switch *source {
+  case "db":
+    n, err = rebuild.ReplayBalanceEvents(ctx)
+  case "kafka":
+    n, err = replayTopics(ctx, brokers, cfg, rebuild)
+  default:
+    err = fmt.Errorf("unknown -source %q", *source)
+  }
+  if err != nil { return err }
+
+  counts, err := rebuild.Counts(ctx)
+  if err != nil { return err }
+  for _, c := range counts {
+    log.Printf("   %-24s live=%d rebuilt=%d", c.Table, c.Live, c.Rebuilt)
+  }
+
+  if *dryRun {
+    log.Printf("🧪 Dry run: replayed %d event(s), nothing swapped", n)
+    return nil
+  }
+  if err := rebuild.Swap(ctx); err != nil { return fmt.Errorf("swap: %w", err) }
+  log.Printf("✅ Replayed %d event(s); previous rows kept in schema projections_previous", n)
+  return nil
+}
+
+// replayLedger mirrors processed_events for a Kafka replay: redelivered
+// duplicates are skipped, and retractions only revert events that were applied
+type replayLedger map[string]bool
+
+func ledgerKey(chain, txHash string, logIndex uint, eventType string) string {
+  return fmt.Sprintf("%s:%s:%d:%s", chain, txHash, logIndex, eventType)
+}
+
+// admit reports whether an event (or the retraction of one) takes effect
+func (l replayLedger) admit(chain, txHash string, logIndex uint, eventType, retracted string) bool {
+  if eventType == "reorg_retraction" {
+    key := ledgerKey(chain, txHash, logIndex, retracted)
+    if !l[key] { return false }
+    delete(l, key)
+    return true
+  }
+  key := ledgerKey(chain, txHash, logIndex, eventType)
+  if l[key] { return false }
+  l[key] = true
+  return true
+}
+
+// ensureGroupStopped fails while a consumer group has active members
+func ensureGroupStopped(ctx context.Context, brokers []string, group string) error {
+  client := &k.Client{Addr: k.TCP(brokers...)}
+  res, err := client.DescribeGroups(ctx, &k.DescribeGroupsRequest{GroupIDs: []string{group}})
+  if err != nil { return fmt.Errorf("describe consumer group %s: %w", group, err) }
+  for _, g := range res.Groups {
+    if g.Error != nil { return fmt.Errorf("describe consumer group %s: %w", group, g.Error) }
+    if len(g.Members) > 0 {
+      return fmt.Errorf("consumer group %s has %d active member(s); stop the consumer before a Kafka-sourced rebuild", group, len(g.Members))
+    }
+  }
+  return nil
+}
+
+// replayTopics replays the raw, L1 and L2 topics up to their current end.
+// The consumer group is stopped, so the end is what the live tables were
+// built from.
+func replayTopics(ctx context.Context, brokers []string, cfg *config.Config, rebuild *db.ProjectionRebuild) (int, error) {
+  ledger := make(replayLedger)
+  apply := map[string]func(m k.Message) error{
+    cfg.KafkaTopicRaw: func(m k.Message) error {
+      var evt models.BalanceEvent
+      if err := json.Unmarshal(m.Value, &evt); err != nil { return decodeError{err} }
+      if !evt.Confirmed || !ledger.admit(evt.Chain, evt.TxHash, evt.LogIndex, evt.EventType, "") { return nil }
+      return rebuild.ApplyBalanceEvent(&evt)
+    },
+    cfg.KafkaTopicL1: func(m k.Message) error {
+      env, err := events.Decode(m, events.KindL1, nil)
+      if err != nil { return decodeError{err} }
+      evt := env.L1
+      if !evt.Confirmed || !ledger.admit("L1", evt.TxHash, evt.LogIndex, evt.EventType, evt.RetractedEventType) { return nil }
+      return rebuild.ApplyL1Event(evt)
+    },
+    cfg.KafkaTopicL2: func(m k.Message) error {
+      env, err := events.Decode(m, events.KindL2, nil)
+      if err != nil { return decodeError{err} }
+      evt := env.L2
+      if !evt.Confirmed || !ledger.admit("L2", evt.TxHash, evt.LogIndex, evt.EventType, evt.RetractedEventType) { return nil }
+      return rebuild.ApplyL2Event(evt)
+    },
+  }
+
+  total := 0
+  for _, topic := range []string{cfg.KafkaTopicRaw, cfg.KafkaTopicL1, cfg.KafkaTopicL2} {
+    conn, err := k.DialContext(ctx, "tcp", brokers[0])
+    if err != nil { return total, err }
+    partitions, err := conn.ReadPartitions(topic)
+    conn.Close()
+    if err != nil { return total, err }
+
+    for _, p := range partitions {
+      n, err := replayTopicPartition(ctx, brokers, topic, p.ID, apply[topic])
+      total += n
+      if err != nil { return total, fmt.Errorf("%s/%d: %w", topic, p.ID, err) }
+    }
+  }
+  return total, nil
+}
+
+// replayTopicPartition applies one partition from its first retained offset
+// to its current end. Undecodable messages, which the live consumer
+// dead-lettered, are skipped.
+func replayTopicPartition(ctx context.Context, brokers []string, topic string, partition int, apply func(m k.Message) error) (int, error) {
+  leader, err := k.DialLeader(ctx, "tcp", brokers[0], topic, partition)
+  if err != nil { return 0, err }
+  first, last, err := leader.ReadOffsets()
+  leader.Close()
+  if err != nil { return 0, err }
+  if first >= last { return 0, nil }
+
+  reader := k.NewReader(k.ReaderConfig{
+    Brokers: brokers, Topic: topic, Partition: partition, MaxBytes: 10e6,
+  })
+  defer reader.Close()
+  if err := reader.SetOffset(first); err != nil { return 0, err }
+
+  total := 0
+  for {
+    m, err := reader.ReadMessage(ctx)
+    if err != nil { return total, err }
+
+    if err := apply(m); err != nil {
+      var de decodeError
+      if !errors.As(err, &de) { return total, fmt.Errorf("offset %d: %w", m.Offset, err) }
+      log.Printf("⚠️  [REBUILD] Skipping undecodable %s/%d offset %d: %v", topic, partition, m.Offset, err)
+    } else {
+      total++
+    }
+    if m.Offset >= last-1 { return total, nil }
+  }
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"loyalty-points-system/internal/config"
+	"loyalty-points-system/internal/events"
+	"loyalty-points-system/internal/listener"
+)
+
+// listenerDefaults are the settings shared by every chain's listeners
+type listenerDefaults struct {
+	checkpoints   listener.CheckpointStore
+	bridgeStore   listener.BridgeStore
+	backfillBatch uint64
+	pollInterval  time.Duration
+	codec         events.Codec
+}
+
+func (d listenerDefaults) poll(c config.ChainConfig) time.Duration {
+	if c.PollIntervalSec > 0 {
+		return time.Duration(c.PollIntervalSec) * time.Second
+	}
+	return d.pollInterval
+}
+
+// l1Config builds the L1 listener of a chain
+func (d listenerDefaults) l1Config(c config.ChainConfig) listener.L1ListenerConfig {
+	return listener.L1ListenerConfig{
+		Name:            c.Name,
+		RPCURL:          c.RPCURL,
+		WSSURL:          c.WSSURL,
+		ChainID:         c.ChainID,
+		Confirmations:   c.Confirmations,
+		Finality:        listener.FinalityMode(c.Finality),
+		CollateralVault: c.Contracts.CollateralVault,
+		StateRegistry:   c.Contracts.StateRegistry,
+		LoyaltyUSD:      c.Contracts.LoyaltyUSD,
+		Gateway:         c.Contracts.Gateway,
+		StartBlock:      uint64(c.StartBlock),
+		BackfillBatch:   d.backfillBatch,
+		Checkpoints:     d.checkpoints,
+		PollInterval:    d.poll(c),
+		Codec:           d.codec,
+	}
+}
+
+// l2Config builds the L2 listener of a chain; parentRPC is the RPC URL of its parent L1, if any
+func (d listenerDefaults) l2Config(c config.ChainConfig, parentRPC string) listener.L2ListenerConfig {
+	return listener.L2ListenerConfig{
+		Name:            c.Name,
+		RPCURL:          c.RPCURL,
+		WSSURL:          c.WSSURL,
+		ChainID:         c.ChainID,
+		Confirmations:   c.Confirmations,
+		Finality:        listener.FinalityMode(c.Finality),
+		ParentRPCURL:    parentRPC,
+		IntegratedVault: c.Contracts.IntegratedVault,
+		StateAggregator: c.Contracts.StateAggregator,
+		AaveAdapter:     c.Contracts.AaveAdapter,
+		CompoundAdapter: c.Contracts.CompoundAdapter,
+		UniswapAdapter:  c.Contracts.UniswapAdapter,
+		RWAFactory:      c.Contracts.RWAFactory,
+		RWAMarketplace:  c.Contracts.RWAMarketplace,
+		RWAYield:        c.Contracts.RWAYieldDistributor,
+		RWACompliance:   c.Contracts.RWACompliance,
+		RWAValuation:    c.Contracts.RWAValuation,
+		RWAGovernance:   c.Contracts.RWAGovernance,
+		StartBlock:      uint64(c.StartBlock),
+		BackfillBatch:   d.backfillBatch,
+		Checkpoints:     d.checkpoints,
+		PollInterval:    d.poll(c),
+		Codec:           d.codec,
+	}
+}
+
+// bridgeConfig builds the bridge listener between an L2 and its parent L1
+func (d listenerDefaults) bridgeConfig(name string, l1, l2 config.ChainConfig) listener.BridgeListenerConfig {
+	l2Gateway := l2.Contracts.Gateway
+	if l2Gateway == "" {
+		l2Gateway = l2.Contracts.IntegratedVault // TODO: Use actual L2 Gateway address
+	}
+	return listener.BridgeListenerConfig{
+		Name:            name,
+		L1RPCURL:        l1.RPCURL,
+		L1WSSURL:        l1.WSSURL,
+		L1ChainID:       l1.ChainID,
+		L1Gateway:       l1.Contracts.Gateway,
+		L1Confirmations: l1.Confirmations,
+		L2RPCURL:        l2.RPCURL,
+		L2WSSURL:        l2.WSSURL,
+		L2ChainID:       l2.ChainID,
+		L2Gateway:       l2Gateway,
+		L2Confirmations: l2.Confirmations,
+		L1StartBlock:    uint64(l1.StartBlock),
+		L2StartBlock:    uint64(l2.StartBlock),
+		BackfillBatch:   d.backfillBatch,
+		Checkpoints:     d.checkpoints,
+		Messages:        d.bridgeStore,
+		PollInterval:    d.poll(l2),
+		Codec:           d.codec,
+	}
+}
+
+// bridgeName names the bridge listener of an L2. Deployments configured through
+// L1_*/L2_* keep "bridge", the name their checkpoints were stored under.
+func bridgeName(cfg *config.Config, l2 config.ChainConfig) string {
+	if cfg.ChainsFile == "" {
+		return "bridge"
+	}
+	return "bridge-" + l2.Name
+}
+
+// finalityName describes the release policy of a chain for the startup log
+func finalityName(c config.ChainConfig) string {
+	if c.Finality == "" || c.Finality == string(listener.FinalityDepth) {
+		return fmt.Sprintf("depth %d", c.Confirmations)
+	}
+	return c.Finality
+}
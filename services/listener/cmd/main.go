@@ -2,14 +2,22 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"log"
+	"math/big"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"loyalty-points-system/internal/blockchain"
+	"loyalty-points-system/internal/bridge"
+	"loyalty-points-system/internal/chain"
 	"loyalty-points-system/internal/config"
+	"loyalty-points-system/internal/db"
+	"loyalty-points-system/internal/events"
 	"loyalty-points-system/internal/kafka"
 	"loyalty-points-system/internal/listener"
 )
@@ -23,6 +31,14 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// Connect to database for block checkpoints and bridge messages
+	database, err := db.Open(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+	checkpoints := db.NewCheckpointStore(database)
+
 	// Ensure Kafka topics exist
 	ctx := context.Background()
 	broker := cfg.KafkaBrokers
@@ -50,68 +66,116 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Create and start L1 listener
-	l1Config := listener.L1ListenerConfig{
-		RPCURL:          cfg.L1RPCURL,
-		WSSURL:          cfg.L1WSSURL,
-		ChainID:         cfg.L1ChainID,
-		Confirmations:   cfg.L1Confirmations,
-		CollateralVault: cfg.L1CollateralVault,
-		StateRegistry:   cfg.L1StateRegistry,
-		LoyaltyUSD:      cfg.L1LoyaltyUSD,
-		Gateway:         cfg.L1Gateway,
-	}
+	// Listeners write confirmed events to the outbox; the relay delivers them to Kafka
+	outbox := db.NewOutbox(database)
+	relay := listener.NewOutboxRelay(outbox, map[string]listener.Writer{
+		cfg.KafkaTopicL1:     l1Writer,
+		cfg.KafkaTopicL2:     l2Writer,
+		cfg.KafkaTopicBridge: bridgeWriter,
+	})
+	go relay.Run(ctx)
 
-	l1Listener := listener.NewL1Listener(l1Config, l1Writer)
-	if err := l1Listener.Start(ctx); err != nil {
-		log.Fatalf("❌ Failed to start L1 listener: %v", err)
-	}
-	log.Println("✅ L1 Listener started")
-
-	// Create and start L2 listener
-	l2Config := listener.L2ListenerConfig{
-		RPCURL:          cfg.L2RPCURL,
-		WSSURL:          cfg.L2WSSURL,
-		ChainID:         cfg.L2ChainID,
-		Confirmations:   cfg.L2Confirmations,
-		IntegratedVault: cfg.L2IntegratedVault,
-		StateAggregator: cfg.L2StateAggregator,
-		AaveAdapter:     cfg.L2AaveAdapter,
-		CompoundAdapter: cfg.L2CompoundAdapter,
-		UniswapAdapter:  cfg.L2UniswapAdapter,
-		RWAFactory:      cfg.L2RWAFactory,
-		RWAMarketplace:  cfg.L2RWAMarketplace,
-		RWAYield:        cfg.L2RWAYieldDistributor,
-		RWACompliance:   cfg.L2RWACompliance,
-		RWAValuation:    cfg.L2RWAValuation,
-		RWAGovernance:   cfg.L2RWAGovernance,
+	codec, err := events.CodecByName(cfg.EventCodec)
+	if err != nil {
+		log.Fatalf("❌ Invalid EVENT_CODEC: %v", err)
 	}
 
-	l2Listener := listener.NewL2Listener(l2Config, l2Writer)
-	if err := l2Listener.Start(ctx); err != nil {
-		log.Fatalf("❌ Failed to start L2 listener: %v", err)
+	defaults := listenerDefaults{
+		checkpoints:   checkpoints,
+		bridgeStore:   db.NewBridgeStore(database),
+		backfillBatch: uint64(cfg.BackfillBatchSize),
+		pollInterval:  time.Duration(cfg.ListenerPollIntervalSec) * time.Second, // for listeners that fall back to HTTP
+		codec:         codec,
 	}
-	log.Println("✅ L2 Listener started")
-
-	// Create and start Bridge listener
-	bridgeConfig := listener.BridgeListenerConfig{
-		L1RPCURL:        cfg.L1RPCURL,
-		L1WSSURL:        cfg.L1WSSURL,
-		L1ChainID:       cfg.L1ChainID,
-		L1Gateway:       cfg.L1Gateway,
-		L1Confirmations: cfg.L1Confirmations,
-		L2RPCURL:        cfg.L2RPCURL,
-		L2WSSURL:        cfg.L2WSSURL,
-		L2ChainID:       cfg.L2ChainID,
-		L2Gateway:       cfg.L2IntegratedVault, // TODO: Use actual L2 Gateway address
-		L2Confirmations: cfg.L2Confirmations,
+
+	// One listener per chain, plus a bridge listener for every L2 with a parent L1.
+	// Every event carries the chain ID of the chain it was observed on.
+	var statuses []func() []listener.Status
+	var closers []func() error
+	chainIDs := make(map[string]int64, len(cfg.Chains))
+	for _, c := range cfg.Chains {
+		chainIDs[c.Name] = c.ChainID
+		switch c.Kind {
+		case config.ChainKindL1:
+			l := listener.NewL1Listener(defaults.l1Config(c), outbox.Writer(cfg.KafkaTopicL1))
+			if err := l.Start(ctx); err != nil {
+				log.Fatalf("❌ Failed to start L1 listener %s: %v", c.Name, err)
+			}
+			statuses = append(statuses, func() []listener.Status { return []listener.Status{l.Status()} })
+			closers = append(closers, l.Close)
+			log.Printf("✅ L1 Listener %s started (chain ID: %d, finality: %s)", c.Name, c.ChainID, finalityName(c))
+
+		case config.ChainKindL2:
+			parent, hasParent := cfg.ChainByName(c.Parent)
+			l := listener.NewL2Listener(defaults.l2Config(c, parent.RPCURL), outbox.Writer(cfg.KafkaTopicL2))
+			if err := l.Start(ctx); err != nil {
+				log.Fatalf("❌ Failed to start L2 listener %s: %v", c.Name, err)
+			}
+			statuses = append(statuses, func() []listener.Status { return []listener.Status{l.Status()} })
+			closers = append(closers, l.Close)
+			log.Printf("✅ L2 Listener %s started (chain ID: %d, finality: %s)", c.Name, c.ChainID, finalityName(c))
+
+			if !hasParent {
+				continue
+			}
+			b := listener.NewBridgeListener(defaults.bridgeConfig(bridgeName(cfg, c), parent, c), outbox.Writer(cfg.KafkaTopicBridge))
+			if err := b.Start(ctx); err != nil {
+				log.Fatalf("❌ Failed to start Bridge listener %s ↔ %s: %v", parent.Name, c.Name, err)
+			}
+			statuses = append(statuses, b.Status)
+			closers = append(closers, b.Close)
+			log.Printf("✅ Bridge Listener %s ↔ %s started", parent.Name, c.Name)
+		}
 	}
 
-	bridgeListener := listener.NewBridgeListener(bridgeConfig, bridgeWriter)
-	if err := bridgeListener.Start(ctx); err != nil {
-		log.Fatalf("❌ Failed to start Bridge listener: %v", err)
+	// Resolve pending bridge messages (retryable tickets, outbox messages) on-chain
+	if parent, rollup, ok := cfg.ArbitrumChain(); ok {
+		clients, err := chain.NewDualClientManager(ctx, parent.RPCURL, rollup.RPCURL, parent.ChainID, rollup.ChainID)
+		if err != nil {
+			log.Printf("⚠️  Bridge Monitor disabled: %v", err)
+		} else {
+			defer clients.Close()
+			tracker := bridge.NewArbitrumTracker(clients.L1Client, clients.L2Client, rollup.ChainID, rollup.Contracts.Inbox, rollup.Contracts.Outbox)
+			go bridge.NewMonitor(database).WithArbitrum(tracker).Start(ctx)
+			log.Printf("✅ Bridge Monitor tracking %s ↔ %s", parent.Name, rollup.Name)
+
+			// Redeem tickets whose auto-redeem failed and finalize withdrawals, from the operator key
+			if cfg.BridgeAutoRedeem || cfg.BridgeAutoFinalize {
+				operator, err := blockchain.NewClientWithKey(clients.L1Client, clients.L2Client, cfg.BridgeOperatorKey)
+				if err != nil {
+					log.Fatalf("❌ Invalid bridge operator key: %v", err)
+				}
+				if cfg.BridgeAutoRedeem {
+					maxSpend, _ := new(big.Int).SetString(cfg.BridgeRedeemMaxSpendWei, 10)
+					redeemer, err := bridge.NewRedeemer(database, operator, bridge.RedeemerConfig{
+						MaxSpendPerHour: maxSpend,
+						Interval:        time.Duration(cfg.BridgeRedeemIntervalSec) * time.Second,
+						MaxAttempts:     cfg.BridgeRedeemMaxAttempts,
+					})
+					if err != nil {
+						log.Fatalf("❌ Invalid bridge redeemer config: %v", err)
+					}
+					go redeemer.Start(ctx)
+					log.Printf("✅ Bridge Redeemer sending from %s", operator.FromAddress.Hex())
+				}
+				if cfg.BridgeAutoFinalize && parent.Contracts.Gateway == "" {
+					log.Printf("⚠️  Bridge Executor disabled: %s has no gateway address", parent.Name)
+				} else if cfg.BridgeAutoFinalize {
+					go bridge.NewExecutor(database, operator, tracker, outbox.Writer(cfg.KafkaTopicBridge), bridge.ExecutorConfig{
+						L1ChainID:  parent.ChainID,
+						L2ChainID:  rollup.ChainID,
+						L1Gateway:  parent.Contracts.Gateway,
+						L2Gateway:  rollup.Contracts.Gateway,
+						StartBlock: uint64(rollup.StartBlock),
+						Interval:   time.Duration(cfg.BridgeFinalizeIntervalSec) * time.Second,
+						ScanBatch:  uint64(cfg.BackfillBatchSize),
+						Codec:      codec,
+					}).Start(ctx)
+					log.Printf("✅ Bridge Executor finalizing withdrawals to %s from %s", parent.Contracts.Gateway, operator.FromAddress.Hex())
+				}
+			}
+		}
 	}
-	log.Println("✅ Bridge Listener started")
 
 	// Prometheus metrics endpoint
 	http.Handle("/metrics", promhttp.Handler())
@@ -122,23 +186,31 @@ func main() {
 		w.Write([]byte("ok"))
 	})
 
-	// Status endpoint with listener info
+	// Status endpoint with per-listener connection state
 	http.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		overall := "running"
+		listeners := make(map[string]listener.Status)
+		for _, status := range statuses {
+			for _, st := range status() {
+				if st.State != listener.StateConnected {
+					overall = "degraded"
+				}
+				listeners[st.Name] = st
+			}
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(200)
-		w.Write([]byte(`{
-			"status": "running",
-			"listeners": {
-				"l1": "active",
-				"l2": "active",
-				"bridge": "active"
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":    overall,
+			"listeners": listeners,
+			"chains":    chainIDs,
+			"topics": map[string]string{
+				"l1":     cfg.KafkaTopicL1,
+				"l2":     cfg.KafkaTopicL2,
+				"bridge": cfg.KafkaTopicBridge,
 			},
-			"topics": {
-				"l1": "` + cfg.KafkaTopicL1 + `",
-				"l2": "` + cfg.KafkaTopicL2 + `",
-				"bridge": "` + cfg.KafkaTopicBridge + `"
-			}
-		}`))
+		})
 	})
 
 	// Start HTTP server in goroutine
@@ -162,9 +234,9 @@ func main() {
 	cancel()
 
 	// Close listeners
-	l1Listener.Close()
-	l2Listener.Close()
-	bridgeListener.Close()
+	for _, closeListener := range closers {
+		closeListener()
+	}
 
 	log.Println("✅ All listeners stopped gracefully")
 }
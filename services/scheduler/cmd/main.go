@@ -78,6 +78,9 @@ func runOnce(db *sql.DB, rate string) error {
 		log.Printf("⚠️ Airdrop status update error: %v", err)
 		// Don't fail the whole scheduler if airdrop update fails
 	}
+	if err := airdrop.CleanupExpiredNonces(ctx, db); err != nil {
+		log.Printf("⚠️ Airdrop nonce cleanup error: %v", err)
+	}
 
 	return nil
 }